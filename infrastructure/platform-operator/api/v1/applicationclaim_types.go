@@ -12,6 +12,12 @@ type ApplicationClaimSpec struct {
 	// GiteaURL Gitea server URL (e.g., http://gitea-http.gitea.svc.cluster.local:3000)
 	GiteaURL string `json:"giteaURL"`
 
+	// GitProvider Git hosting service serving giteaURL: gitea, github, gitlab, or git for a plain
+	// Git remote without an API whose repositories must exist (operator default if empty)
+	// +kubebuilder:validation:Enum=gitea;github;gitlab;git
+	// +optional
+	GitProvider string `json:"gitProvider,omitempty"`
+
 	// Organization Gitea organization name
 	Organization string `json:"organization"`
 
@@ -27,11 +33,30 @@ type ApplicationClaimSpec struct {
 	// Components platform components like databases
 	Components []ComponentSpec `json:"components,omitempty"`
 
-	// Namespace target namespace (auto-generated if empty)
+	// Namespace target namespace (rendered from the operator namespace template if empty)
 	Namespace string `json:"namespace,omitempty"`
 
 	// Owner team ownership information
 	Owner OwnerSpec `json:"owner"`
+
+	// GitOpsMode how generated files reach the voltran branch: direct push or pullRequest
+	// (operator setting for the cluster type if empty)
+	// +kubebuilder:validation:Enum=direct;pullRequest
+	// +optional
+	GitOpsMode string `json:"gitOpsMode,omitempty"`
+
+	// DriftPolicy what happens when the voltran files of the claim were changed outside the
+	// operator: alert only sets the Drifted condition, selfHeal also restores the generated files
+	// (default selfHeal; pullRequest mode only alerts)
+	// +kubebuilder:validation:Enum=alert;selfHeal
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// CredentialsSecretRef Secret in the claim's namespace with the Git credentials of the claim:
+	// username and token, or ssh-privatekey with sshURL and optionally known_hosts; API calls use
+	// the operator credentials when the Secret has no token (operator credentials if empty)
+	// +optional
+	CredentialsSecretRef *GitCredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
 }
 
 // ApplicationSpec single application configuration
@@ -75,6 +100,15 @@ type ApplicationSpec struct {
 
 	// Ingress ingress configuration
 	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// DependsOn applications of this claim, components or platform services that must be
+	// deployed before this application (e.g. "postgres", "user-service")
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Values Helm values deep-merged last, over the operator's environment values and the
+	// values generated from the typed fields (e.g. podAnnotations, volumes, securityContext)
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values runtime.RawExtension `json:"values,omitempty"`
 }
 
 // ChartSpec defines Helm chart source
@@ -82,13 +116,13 @@ type ChartSpec struct {
 	// Name chart name
 	Name string `json:"name"`
 
-	// Source chart source (embedded, github, helm-repo)
+	// Source chart source (embedded, git, github, helm-repo, oci)
 	Source string `json:"source,omitempty"`
 
-	// Repository Helm repository URL (if external)
+	// Repository Git, Helm repository or oci:// registry URL (if external)
 	Repository string `json:"repository,omitempty"`
 
-	// Version chart version
+	// Version chart version; the Git revision for git sources
 	Version string `json:"version,omitempty"`
 }
 
@@ -123,6 +157,9 @@ type IngressSpec struct {
 
 	// Annotations ingress annotations
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Port name of the port the ingress routes to (defaults to the first port)
+	Port string `json:"port,omitempty"`
 }
 
 // ComponentSpec platform component specification
@@ -173,12 +210,23 @@ type ResourceList struct {
 
 // PortSpec port configuration
 type PortSpec struct {
-	Name     string `json:"name"`
-	Port     int32  `json:"port"`
+	// Name port name, referenced by the ingress and health checks
+	Name string `json:"name"`
+
+	// Port container port
+	Port int32 `json:"port"`
+
+	// ServicePort port exposed by the Service (defaults to 80 for the "http" port, Port otherwise)
+	ServicePort int32 `json:"servicePort,omitempty"`
+
+	// Protocol port protocol (TCP, UDP, SCTP)
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
 	Protocol string `json:"protocol,omitempty"`
 }
 
 // HealthCheckSpec health check configuration
+// Path, Port, InitialDelaySeconds and PeriodSeconds are shared defaults for the
+// liveness and readiness probes; each probe can override them
 type HealthCheckSpec struct {
 	// Path HTTP path for health check
 	Path string `json:"path,omitempty"`
@@ -186,11 +234,60 @@ type HealthCheckSpec struct {
 	// Port port for health check
 	Port int32 `json:"port,omitempty"`
 
+	// PortName named port for health check, takes precedence over Port
+	PortName string `json:"portName,omitempty"`
+
 	// InitialDelaySeconds delay before first check
 	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
 
 	// PeriodSeconds check interval
 	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// Liveness liveness probe, restarts the container when failing
+	Liveness *ProbeSpec `json:"liveness,omitempty"`
+
+	// Readiness readiness probe, removes the pod from service endpoints when failing
+	Readiness *ProbeSpec `json:"readiness,omitempty"`
+
+	// Startup startup probe, holds liveness and readiness until the container started
+	Startup *ProbeSpec `json:"startup,omitempty"`
+}
+
+// ProbeSpec container probe configuration
+type ProbeSpec struct {
+	// Enabled enable the probe (default true when the probe is set)
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Type probe type (http, tcp, exec)
+	// +kubebuilder:validation:Enum=http;tcp;exec
+	Type string `json:"type,omitempty"`
+
+	// Path HTTP path (http probes)
+	Path string `json:"path,omitempty"`
+
+	// Port container port to probe (http and tcp probes, defaults to the http port)
+	Port int32 `json:"port,omitempty"`
+
+	// PortName named port to probe, takes precedence over Port
+	PortName string `json:"portName,omitempty"`
+
+	// Command command executed in the container (exec probes)
+	Command []string `json:"command,omitempty"`
+
+	// InitialDelaySeconds delay before first check
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
+	// PeriodSeconds check interval
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// TimeoutSeconds check timeout
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// FailureThreshold consecutive failures before the probe fails
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// SuccessThreshold consecutive successes before the probe succeeds
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
 }
 
 // EnvVar environment variable
@@ -205,7 +302,9 @@ type EnvVar struct {
 	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
 }
 
-// EnvVarSource environment variable source
+// EnvVarSource environment variable source, exactly one of the references must be set
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
 type EnvVarSource struct {
 	// SecretKeyRef secret reference
 	SecretKeyRef *SecretKeySelector `json:"secretKeyRef,omitempty"`
@@ -223,6 +322,12 @@ type SecretKeySelector struct {
 	Key string `json:"key"`
 }
 
+// GitCredentialsSecretRef reference to a Git credentials Secret in the namespace of the claim
+type GitCredentialsSecretRef struct {
+	// Name secret name
+	Name string `json:"name"`
+}
+
 // ConfigMapKeySelector configmap key reference
 type ConfigMapKeySelector struct {
 	// Name configmap name
@@ -262,9 +367,31 @@ type OwnerSpec struct {
 	Slack string `json:"slack,omitempty"`
 }
 
+// PullRequestStatus voltran pull request carrying a claim generation in pullRequest mode
+type PullRequestStatus struct {
+	// Number pull request number in the voltran repository
+	Number int64 `json:"number"`
+
+	// URL web URL of the pull request
+	URL string `json:"url"`
+
+	// Branch branch holding the generated files
+	Branch string `json:"branch"`
+
+	// State pull request state (Open, Merged, Closed)
+	State string `json:"state"`
+
+	// Generation claim generation the pull request was opened for
+	Generation int64 `json:"generation"`
+
+	// RenderedHash hash of the generated files of the pull request; files rendered differently
+	// at the same generation, e.g. once held services are released, open a follow-up pull request
+	RenderedHash string `json:"renderedHash,omitempty"`
+}
+
 // ApplicationClaimStatus defines the observed state of ApplicationClaim
 type ApplicationClaimStatus struct {
-	// Phase current phase (Pending, Provisioning, Ready, Failed)
+	// Phase current phase (Pending, AwaitingReview, Provisioning, Ready, Failed)
 	Phase string `json:"phase,omitempty"`
 
 	// Ready overall readiness status
@@ -287,6 +414,25 @@ type ApplicationClaimStatus struct {
 
 	// LastUpdated last update timestamp
 	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+
+	// Message provides additional status information
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration generation of the spec last pushed to Git
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastCommit SHA of the voltran commit holding the current generated files
+	LastCommit string `json:"lastCommit,omitempty"`
+
+	// RenderedHash hash of the generated files last pushed directly; a different hash at the
+	// same generation is a rendering change of the operator, pushed rather than taken for drift
+	RenderedHash string `json:"renderedHash,omitempty"`
+
+	// CommitURL web URL of LastCommit on the Gitea server
+	CommitURL string `json:"commitURL,omitempty"`
+
+	// PullRequest pull request of the latest generation in pullRequest mode
+	PullRequest *PullRequestStatus `json:"pullRequest,omitempty"`
 }
 
 // ApplicationStatus application deployment status
@@ -297,6 +443,23 @@ type ApplicationStatus struct {
 	Replicas          int32    `json:"replicas"`
 	AvailableReplicas int32    `json:"availableReplicas"`
 	Endpoints         []string `json:"endpoints,omitempty"`
+
+	// Phase WaitingForDependencies while the application is held back until the components and
+	// platform services it depends on are ready, Provisioning until its ArgoCD Application is
+	// Synced and Healthy, then Ready
+	Phase string `json:"phase,omitempty"`
+
+	// SyncStatus ArgoCD sync status (Synced, OutOfSync, Unknown)
+	SyncStatus string `json:"syncStatus,omitempty"`
+
+	// HealthStatus ArgoCD health status (Healthy, Progressing, Degraded, Suspended, Missing, Unknown)
+	HealthStatus string `json:"healthStatus,omitempty"`
+
+	// Revision Git revision ArgoCD last synced
+	Revision string `json:"revision,omitempty"`
+
+	// Message additional status message
+	Message string `json:"message,omitempty"`
 }
 
 // ComponentStatus component provision status
@@ -306,6 +469,12 @@ type ComponentStatus struct {
 	Ready            bool   `json:"ready"`
 	ConnectionString string `json:"connectionString,omitempty"`
 	SecretName       string `json:"secretName,omitempty"`
+
+	// SyncStatus ArgoCD sync status (Synced, OutOfSync, Unknown)
+	SyncStatus string `json:"syncStatus,omitempty"`
+
+	// HealthStatus ArgoCD health status (Healthy, Progressing, Degraded, Suspended, Missing, Unknown)
+	HealthStatus string `json:"healthStatus,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -11,6 +11,12 @@ type BootstrapClaimSpec struct {
 	// GiteaURL is the URL of the Gitea server
 	GiteaURL string `json:"giteaURL"`
 
+	// GitProvider Git hosting service serving giteaURL: gitea, github, gitlab, or git for a plain
+	// Git remote without an API whose repositories must exist (operator default if empty)
+	// +kubebuilder:validation:Enum=gitea;github;gitlab;git
+	// +optional
+	GitProvider string `json:"gitProvider,omitempty"`
+
 	// Organization is the Gitea organization name
 	Organization string `json:"organization"`
 
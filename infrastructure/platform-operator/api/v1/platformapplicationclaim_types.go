@@ -12,6 +12,12 @@ type PlatformApplicationClaimSpec struct {
 	// GiteaURL Gitea server URL (e.g., http://gitea-http.gitea.svc.cluster.local:3000)
 	GiteaURL string `json:"giteaURL"`
 
+	// GitProvider Git hosting service serving giteaURL: gitea, github, gitlab, or git for a plain
+	// Git remote without an API whose repositories must exist (operator default if empty)
+	// +kubebuilder:validation:Enum=gitea;github;gitlab;git
+	// +optional
+	GitProvider string `json:"gitProvider,omitempty"`
+
 	// Organization Gitea organization name
 	Organization string `json:"organization"`
 
@@ -24,7 +30,7 @@ type PlatformApplicationClaimSpec struct {
 	// Services platform services to deploy
 	Services []PlatformServiceSpec `json:"services"`
 
-	// Namespace target namespace (auto-generated if empty)
+	// Namespace target namespace (rendered from the operator namespace template if empty)
 	Namespace string `json:"namespace,omitempty"`
 
 	// Owner team ownership information
@@ -34,6 +40,25 @@ type PlatformApplicationClaimSpec struct {
 	// +kubebuilder:default="standard"
 	// +optional
 	StorageClass string `json:"storageClass,omitempty"`
+
+	// GitOpsMode how generated files reach the voltran branch: direct push or pullRequest
+	// (operator setting for the cluster type if empty)
+	// +kubebuilder:validation:Enum=direct;pullRequest
+	// +optional
+	GitOpsMode string `json:"gitOpsMode,omitempty"`
+
+	// DriftPolicy what happens when the voltran files of the claim were changed outside the
+	// operator: alert only sets the Drifted condition, selfHeal also restores the generated files
+	// (default selfHeal; pullRequest mode only alerts)
+	// +kubebuilder:validation:Enum=alert;selfHeal
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// CredentialsSecretRef Secret in the claim's namespace with the Git credentials of the claim:
+	// username and token, or ssh-privatekey with sshURL and optionally known_hosts; API calls use
+	// the operator credentials when the Secret has no token (operator credentials if empty)
+	// +optional
+	CredentialsSecretRef *GitCredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
 }
 
 // PlatformServiceSpec defines a platform service configuration
@@ -88,7 +113,7 @@ type BackupSpec struct {
 
 // PlatformApplicationClaimStatus defines the observed state of PlatformApplicationClaim
 type PlatformApplicationClaimStatus struct {
-	// Phase current phase (Pending, Provisioning, Ready, Failed)
+	// Phase current phase (Pending, AwaitingReview, WaitingForOperator, Provisioning, Ready, Failed)
 	Phase string `json:"phase,omitempty"`
 
 	// Ready overall readiness status
@@ -108,6 +133,22 @@ type PlatformApplicationClaimStatus struct {
 
 	// Message provides additional status information
 	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration generation of the spec last pushed to Git
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastCommit SHA of the voltran commit holding the current generated files
+	LastCommit string `json:"lastCommit,omitempty"`
+
+	// RenderedHash hash of the generated files last pushed directly; a different hash at the
+	// same generation is a rendering change of the operator, pushed rather than taken for drift
+	RenderedHash string `json:"renderedHash,omitempty"`
+
+	// CommitURL web URL of LastCommit on the Gitea server
+	CommitURL string `json:"commitURL,omitempty"`
+
+	// PullRequest pull request of the latest generation in pullRequest mode
+	PullRequest *PullRequestStatus `json:"pullRequest,omitempty"`
 }
 
 // PlatformServiceStatus defines the status of a platform service
@@ -121,6 +162,10 @@ type PlatformServiceStatus struct {
 	// Ready service ready status
 	Ready bool `json:"ready"`
 
+	// Phase WaitingForOperator while the service is held back until the operator of its type is
+	// ready, Provisioning until its ArgoCD Application is Synced and Healthy, then Ready
+	Phase string `json:"phase,omitempty"`
+
 	// Version deployed version
 	Version string `json:"version,omitempty"`
 
@@ -130,6 +175,12 @@ type PlatformServiceStatus struct {
 	// SecretName secret containing credentials
 	SecretName string `json:"secretName,omitempty"`
 
+	// SyncStatus ArgoCD sync status (Synced, OutOfSync, Unknown)
+	SyncStatus string `json:"syncStatus,omitempty"`
+
+	// HealthStatus ArgoCD health status (Healthy, Progressing, Degraded, Suspended, Missing, Unknown)
+	HealthStatus string `json:"healthStatus,omitempty"`
+
 	// Message additional status message
 	Message string `json:"message,omitempty"`
 }
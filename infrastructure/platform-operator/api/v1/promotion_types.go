@@ -0,0 +1,143 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PromotionApprovedAnnotation lists the target environments a reviewer approved (e.g. "staging,prod")
+const PromotionApprovedAnnotation = "platform.infraforge.io/approved-environments"
+
+// PromotionSpec defines the desired state of Promotion
+type PromotionSpec struct {
+	// Source ApplicationClaim the image tags and chart versions are copied from
+	Source PromotionSource `json:"source"`
+
+	// Targets environments promoted to, in order; a target waiting for approval holds back the ones after it
+	// +kubebuilder:validation:MinItems=1
+	Targets []PromotionTarget `json:"targets"`
+
+	// Applications names of the applications to promote (every enabled application of the source if empty)
+	Applications []string `json:"applications,omitempty"`
+}
+
+// PromotionSource defines the ApplicationClaim promoted versions are read from
+type PromotionSource struct {
+	// ClaimName ApplicationClaim in the namespace of the Promotion
+	ClaimName string `json:"claimName,omitempty"`
+
+	// Environment environment whose ApplicationClaim in the namespace of the Promotion is used if ClaimName is empty
+	Environment string `json:"environment,omitempty"`
+}
+
+// PromotionTarget defines an environment versions are promoted to
+type PromotionTarget struct {
+	// Environment target environment (qa, sandbox, staging, prod)
+	Environment string `json:"environment"`
+
+	// ClaimName ApplicationClaim updated in the target environment (the environment's claim declaring the applications if empty)
+	ClaimName string `json:"claimName,omitempty"`
+
+	// RequireApproval waits until the environment is listed in the approved-environments annotation
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+// PromotionStatus defines the observed state of Promotion
+type PromotionStatus struct {
+	// Phase current phase (Pending, AwaitingApproval, Succeeded, Failed)
+	Phase string `json:"phase,omitempty"`
+
+	// Message provides additional status information
+	Message string `json:"message,omitempty"`
+
+	// Targets per-target promotion state of the current generation
+	Targets []PromotionTargetStatus `json:"targets,omitempty"`
+
+	// History versions copied into target claims, oldest first
+	History []PromotionRecord `json:"history,omitempty"`
+
+	// Conditions detailed conditions
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration generation of the spec the targets were promoted for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastUpdated last update timestamp
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// PromotionTargetStatus promotion state of a single target environment
+type PromotionTargetStatus struct {
+	// Environment target environment
+	Environment string `json:"environment"`
+
+	// ClaimName ApplicationClaim the versions were copied into
+	ClaimName string `json:"claimName,omitempty"`
+
+	// Phase target phase (Pending, AwaitingApproval, Promoted, Failed)
+	Phase string `json:"phase"`
+
+	// Message additional status message
+	Message string `json:"message,omitempty"`
+
+	// PromotedAt time the target claim was updated
+	PromotedAt *metav1.Time `json:"promotedAt,omitempty"`
+}
+
+// PromotionRecord a version change made to a target claim
+type PromotionRecord struct {
+	// Time time of the change
+	Time metav1.Time `json:"time"`
+
+	// Generation Promotion generation that made the change
+	Generation int64 `json:"generation"`
+
+	// SourceClaim ApplicationClaim the versions were read from
+	SourceClaim string `json:"sourceClaim"`
+
+	// TargetClaim ApplicationClaim the versions were written to
+	TargetClaim string `json:"targetClaim"`
+
+	// Environment target environment
+	Environment string `json:"environment"`
+
+	// Application promoted application
+	Application string `json:"application"`
+
+	// ImageTag image tag after the promotion
+	ImageTag string `json:"imageTag,omitempty"`
+
+	// PreviousImageTag image tag before the promotion
+	PreviousImageTag string `json:"previousImageTag,omitempty"`
+
+	// ChartVersion chart version after the promotion
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// PreviousChartVersion chart version before the promotion
+	PreviousChartVersion string `json:"previousChartVersion,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.environment`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Promotion is the Schema for the promotions API
+// It copies image tags and chart versions from a source ApplicationClaim into the claims of target environments
+type Promotion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PromotionSpec   `json:"spec,omitempty"`
+	Status PromotionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PromotionList contains a list of Promotion
+type PromotionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Promotion `json:"items"`
+}
@@ -5,5 +5,6 @@ func init() {
 		&ApplicationClaim{}, &ApplicationClaimList{},
 		&BootstrapClaim{}, &BootstrapClaimList{},
 		&PlatformApplicationClaim{}, &PlatformApplicationClaimList{},
+		&Promotion{}, &PromotionList{},
 	)
 }
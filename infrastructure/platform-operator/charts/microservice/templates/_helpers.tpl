@@ -31,3 +31,37 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Container probe: http (default), tcp or exec, with optional timing overrides
+*/}}
+{{- define "microservice.probe" -}}
+{{- $type := default "http" .type }}
+{{- if eq $type "tcp" }}
+tcpSocket:
+  port: {{ .port | default "http" }}
+{{- else if eq $type "exec" }}
+exec:
+  command:
+    {{- toYaml .command | nindent 4 }}
+{{- else }}
+httpGet:
+  path: {{ .path | default "/" }}
+  port: {{ .port | default "http" }}
+{{- end }}
+{{- with .initialDelaySeconds }}
+initialDelaySeconds: {{ . }}
+{{- end }}
+{{- with .periodSeconds }}
+periodSeconds: {{ . }}
+{{- end }}
+{{- with .timeoutSeconds }}
+timeoutSeconds: {{ . }}
+{{- end }}
+{{- with .failureThreshold }}
+failureThreshold: {{ . }}
+{{- end }}
+{{- with .successThreshold }}
+successThreshold: {{ . }}
+{{- end }}
+{{- end }}
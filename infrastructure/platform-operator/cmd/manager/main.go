@@ -3,7 +3,10 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -15,6 +18,11 @@ import (
 
 	platformv1 "github.com/infraforge/platform-operator/api/v1"
 	"github.com/infraforge/platform-operator/internal/controller"
+	"github.com/infraforge/platform-operator/internal/defaults"
+	"github.com/infraforge/platform-operator/internal/metrics"
+	webhookv1 "github.com/infraforge/platform-operator/internal/webhook/v1"
+	"github.com/infraforge/platform-operator/pkg/gitea"
+	"github.com/infraforge/platform-operator/pkg/sops"
 )
 
 var (
@@ -36,6 +44,22 @@ func main() {
 	var voltranRepo string
 	var gitBranch string
 	var chartsPath string
+	var defaultPullSecrets string
+	var rejectMutableProdTags bool
+	var appNamespaceTemplate string
+	var platformNamespaceTemplate string
+	var enableWebhooks bool
+	var defaultsConfig string
+	var pullRequestClusterTypes string
+	var driftCheckInterval time.Duration
+	var sopsAgeKeyFile string
+	var sopsAgeRecipients string
+	var sopsEncryptedRegex string
+	var gitPushAttempts int
+	var gitBatchWrites bool
+	var gitCacheDir string
+	var gitCacheMaxSize string
+	var gitCacheMaxAge time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -45,10 +69,77 @@ func main() {
 	flag.StringVar(&voltranRepo, "voltran-repo", "voltran", "GitOps voltran repository name")
 	flag.StringVar(&gitBranch, "git-branch", "main", "Git branch to use")
 	flag.StringVar(&chartsPath, "charts-path", "", "Path to charts directory for bootstrap")
+	flag.StringVar(&defaultPullSecrets, "default-pull-secrets", "ghcr-pull-secret", "Comma-separated image pull secrets added to every application")
+	flag.BoolVar(&rejectMutableProdTags, "reject-mutable-prod-tags", false, "Reject empty and latest image tags in prod ApplicationClaims")
+	flag.StringVar(&appNamespaceTemplate, "app-namespace-template", controller.DefaultApplicationNamespaceTemplate,
+		"Namespace of ApplicationClaims without spec.namespace; placeholders {team}, {env}, {cluster}, {claim}, {namespace}")
+	flag.StringVar(&platformNamespaceTemplate, "platform-namespace-template", controller.DefaultPlatformNamespaceTemplate,
+		"Namespace of PlatformApplicationClaims without spec.namespace; placeholders {team}, {env}, {cluster}, {claim}, {namespace}")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the validating admission webhooks (requires serving certificates)")
+	flag.StringVar(&defaultsConfig, "defaults-config", "", "YAML file overriding the default values of unset claim fields")
+	flag.StringVar(&pullRequestClusterTypes, "pull-request-cluster-types", "", "Comma-separated cluster types whose claims are applied through voltran pull requests")
+	flag.DurationVar(&driftCheckInterval, "drift-check-interval", controller.DefaultDriftCheckInterval,
+		"How often reconciled claims are compared with the voltran branch")
+	flag.StringVar(&sopsAgeKeyFile, "sops-age-key-file", "", "age key file; when set, sensitive values of generated files are encrypted with sops")
+	flag.StringVar(&sopsAgeRecipients, "sops-age-recipients", "", "Comma-separated additional age recipients of encrypted files")
+	flag.StringVar(&sopsEncryptedRegex, "sops-encrypted-regex", sops.DefaultEncryptedRegex, "Keys whose values are encrypted")
+	flag.IntVar(&gitPushAttempts, "git-push-attempts", gitea.DefaultPushAttempts, "Pushes tried per write to a voltran branch that keeps moving")
+	flag.BoolVar(&gitBatchWrites, "git-batch-writes", false, "Merge writes queued for the same voltran branch into one commit")
+	flag.StringVar(&gitCacheDir, "git-cache-dir", "", "Directory of the local mirrors of voltran and chart repositories; empty clones every time")
+	flag.StringVar(&gitCacheMaxSize, "git-cache-max-size", "2Gi", "Size of the Git cache above which the least recently used mirrors are removed")
+	flag.DurationVar(&gitCacheMaxAge, "git-cache-max-age", 24*time.Hour, "How long an unused mirror is kept in the Git cache")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	// Defaults shared by the reconcilers and the defaulting webhooks
+	claimDefaults, err := defaults.Load(defaultsConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to load defaults", "path", defaultsConfig)
+		os.Exit(1)
+	}
+
+	// Encryption of generated files, disabled without an age key
+	var encryptor *sops.Encryptor
+	if sopsAgeKeyFile != "" {
+		identities, err := sops.LoadIdentities(sopsAgeKeyFile)
+		if err == nil {
+			encryptor, err = sops.NewEncryptor(identities, splitList(sopsAgeRecipients), sopsEncryptedRegex)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to set up sops encryption", "path", sopsAgeKeyFile)
+			os.Exit(1)
+		}
+		setupLog.Info("Encrypting generated values with sops", "recipients", encryptor.Recipients())
+	}
+
+	// Writes to the same voltran branch are serialized across all controllers
+	gitea.DefaultWriteCoordinator = gitea.NewWriteCoordinator(gitea.WriteOptions{
+		MaxAttempts: gitPushAttempts,
+		Batch:       gitBatchWrites,
+	})
+
+	// Clones and fetches are exported as metrics; with a cache directory repositories are
+	// mirrored locally and fetched incrementally
+	gitea.GitOperationObserver = func(operation string, duration time.Duration, err error) {
+		metrics.RecordGitOperation(operation, duration.Seconds(), err)
+	}
+	if gitCacheDir != "" {
+		maxSize, err := resource.ParseQuantity(gitCacheMaxSize)
+		if err != nil {
+			setupLog.Error(err, "invalid Git cache size", "size", gitCacheMaxSize)
+			os.Exit(1)
+		}
+		gitea.DefaultRepoCache, err = gitea.NewRepoCache(gitCacheDir, gitea.CacheOptions{
+			MaxSize: maxSize.Value(),
+			MaxAge:  gitCacheMaxAge,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to set up Git cache", "path", gitCacheDir)
+			os.Exit(1)
+		}
+	}
+
 	// Gitea credentials for controllers to use
 	if giteaToken == "" {
 		setupLog.Info("Gitea token not provided, GitOps features will be disabled")
@@ -80,6 +171,9 @@ func main() {
 			GiteaUsername: giteaUsername,
 			GiteaToken:    giteaToken,
 			ChartsPath:    chartsPath,
+			Defaults:      claimDefaults,
+			Encryptor:     encryptor,
+			Recorder:      mgr.GetEventRecorderFor("bootstrap-controller"),
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "Bootstrap")
 			os.Exit(1)
@@ -93,6 +187,16 @@ func main() {
 			GiteaToken:    giteaToken,
 			VoltranRepo:   voltranRepo,
 			Branch:        gitBranch,
+
+			DefaultPullSecrets:      splitList(defaultPullSecrets),
+			RejectMutableProdTags:   rejectMutableProdTags,
+			NamespaceTemplate:       appNamespaceTemplate,
+			PullRequestClusterTypes: splitList(pullRequestClusterTypes),
+			DriftCheckInterval:      driftCheckInterval,
+			Defaults:                claimDefaults,
+			Encryptor:               encryptor,
+			Recorder:                mgr.GetEventRecorderFor("applicationclaim-controller"),
+			APIReader:               mgr.GetAPIReader(),
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "ApplicationClaimGitOps")
 			os.Exit(1)
@@ -106,14 +210,65 @@ func main() {
 			GiteaToken:    giteaToken,
 			VoltranRepo:   voltranRepo,
 			Branch:        gitBranch,
+
+			NamespaceTemplate:       platformNamespaceTemplate,
+			PullRequestClusterTypes: splitList(pullRequestClusterTypes),
+			DriftCheckInterval:      driftCheckInterval,
+			Defaults:                claimDefaults,
+			Encryptor:               encryptor,
+			Recorder:                mgr.GetEventRecorderFor("platformapplicationclaim-controller"),
+			APIReader:               mgr.GetAPIReader(),
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "PlatformApplicationClaim")
 			os.Exit(1)
 		}
 
+		// Promotion controller - copies versions between environment claims
+		if err = (&controller.PromotionReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Defaults: claimDefaults,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Promotion")
+			os.Exit(1)
+		}
+
 		setupLog.Info("All controllers registered successfully with GitOps enabled")
 	}
 
+	// Defaulting webhooks store the effective defaults, validating webhooks reject bad claims
+	// before anything reaches Git
+	if enableWebhooks {
+		if err = (&webhookv1.ApplicationClaimDefaulter{Defaults: claimDefaults}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ApplicationClaimDefaulter")
+			os.Exit(1)
+		}
+		if err = (&webhookv1.PlatformApplicationClaimDefaulter{Defaults: claimDefaults}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PlatformApplicationClaimDefaulter")
+			os.Exit(1)
+		}
+		if err = (&webhookv1.BootstrapClaimDefaulter{Defaults: claimDefaults}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BootstrapClaimDefaulter")
+			os.Exit(1)
+		}
+		if err = (&webhookv1.ApplicationClaimValidator{
+			Defaults:              claimDefaults,
+			RejectMutableProdTags: rejectMutableProdTags,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ApplicationClaimValidator")
+			os.Exit(1)
+		}
+		if err = (&webhookv1.PlatformApplicationClaimValidator{Defaults: claimDefaults}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PlatformApplicationClaimValidator")
+			os.Exit(1)
+		}
+		if err = (&webhookv1.BootstrapClaimValidator{Defaults: claimDefaults}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BootstrapClaimValidator")
+			os.Exit(1)
+		}
+		setupLog.Info("Admission webhooks registered")
+	}
+
 	// Add health and readiness checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -130,3 +285,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
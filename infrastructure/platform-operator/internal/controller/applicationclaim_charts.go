@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+const (
+	// ChartSourceEmbedded charts in the charts repository of the claim's Gitea organization
+	ChartSourceEmbedded = "embedded"
+
+	// ChartSourceGit charts in a directory of an external Git repository
+	ChartSourceGit = "git"
+
+	// ChartSourceGitHub is an alias of ChartSourceGit
+	ChartSourceGitHub = "github"
+
+	// ChartSourceHelmRepo charts in a classic Helm repository serving index.yaml
+	ChartSourceHelmRepo = "helm-repo"
+
+	// ChartSourceOCI charts in an OCI registry
+	ChartSourceOCI = "oci"
+)
+
+// chartSource ArgoCD source of an application chart, written to config.json for the
+// ApplicationSet template; a Git source sets Path, a Helm source sets Chart
+type chartSource struct {
+	RepoURL        string
+	Path           string
+	Chart          string
+	TargetRevision string
+}
+
+// resolveChartSource returns the ArgoCD source of the application's chart
+// Embedded charts follow the charts repository branch, Git charts pin Version as Git revision and
+// Helm and OCI charts pin Version as chart version
+func (r *ApplicationClaimGitOpsReconciler) resolveChartSource(claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec) (chartSource, error) {
+	chart := app.Chart
+	source := chart.Source
+	// An oci:// repository is an OCI registry whatever the declared source
+	if strings.HasPrefix(chart.Repository, "oci://") && (source == "" || source == ChartSourceHelmRepo) {
+		source = ChartSourceOCI
+	}
+
+	switch source {
+	case "", ChartSourceEmbedded:
+		if chart.Repository != "" {
+			return chartSource{}, fmt.Errorf("application %s: embedded charts come from the organization's charts repository, not %s", app.Name, chart.Repository)
+		}
+		return chartSource{
+			RepoURL:        fmt.Sprintf("%s/%s/charts", claim.Spec.GiteaURL, claim.Spec.Organization),
+			Path:           chart.Name,
+			TargetRevision: "main",
+		}, nil
+
+	case ChartSourceGit, ChartSourceGitHub:
+		if chart.Repository == "" {
+			return chartSource{}, fmt.Errorf("application %s: chart source %s requires a repository", app.Name, source)
+		}
+		return chartSource{
+			RepoURL:        chart.Repository,
+			Path:           chart.Name,
+			TargetRevision: orDefault(chart.Version, "HEAD"),
+		}, nil
+
+	case ChartSourceHelmRepo:
+		if chart.Repository == "" {
+			return chartSource{}, fmt.Errorf("application %s: chart source %s requires a repository", app.Name, source)
+		}
+		return chartSource{
+			RepoURL:        chart.Repository,
+			Chart:          chart.Name,
+			TargetRevision: orDefault(chart.Version, "*"),
+		}, nil
+
+	case ChartSourceOCI:
+		if !strings.HasPrefix(chart.Repository, "oci://") {
+			return chartSource{}, fmt.Errorf("application %s: chart source oci requires an oci:// repository", app.Name)
+		}
+		if chart.Version == "" {
+			return chartSource{}, fmt.Errorf("application %s: OCI charts require a version", app.Name)
+		}
+		return chartSource{
+			// ArgoCD addresses OCI Helm repositories without the scheme
+			RepoURL:        strings.TrimPrefix(chart.Repository, "oci://"),
+			Chart:          chart.Name,
+			TargetRevision: chart.Version,
+		}, nil
+	}
+	return chartSource{}, fmt.Errorf("application %s: unsupported chart source %s", app.Name, source)
+}
+
+// orDefault returns value, or def when value is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+)
+
+func TestResolveChartSource(t *testing.T) {
+	r := &ApplicationClaimGitOpsReconciler{}
+	claim := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{GiteaURL: "http://gitea:3000", Organization: "acme"}}
+
+	tests := map[string]struct {
+		chart    platformv1.ChartSpec
+		expected chartSource
+		wantErr  bool
+	}{
+		"embedded": {
+			chart:    platformv1.ChartSpec{Name: "microservice", Version: "1.0.0"},
+			expected: chartSource{RepoURL: "http://gitea:3000/acme/charts", Path: "microservice", TargetRevision: "main"},
+		},
+		"git pinned to a tag": {
+			chart:    platformv1.ChartSpec{Name: "charts/api", Source: "github", Repository: "https://github.com/acme/charts.git", Version: "v1.4.0"},
+			expected: chartSource{RepoURL: "https://github.com/acme/charts.git", Path: "charts/api", TargetRevision: "v1.4.0"},
+		},
+		"helm repository": {
+			chart:    platformv1.ChartSpec{Name: "nginx", Source: "helm-repo", Repository: "https://charts.bitnami.com/bitnami", Version: "15.1.0"},
+			expected: chartSource{RepoURL: "https://charts.bitnami.com/bitnami", Chart: "nginx", TargetRevision: "15.1.0"},
+		},
+		"oci registry": {
+			chart:    platformv1.ChartSpec{Name: "microservice", Repository: "oci://ghcr.io/acme/charts", Version: "2.0.1"},
+			expected: chartSource{RepoURL: "ghcr.io/acme/charts", Chart: "microservice", TargetRevision: "2.0.1"},
+		},
+		"unpinned oci chart": {
+			chart:   platformv1.ChartSpec{Name: "microservice", Source: "oci", Repository: "oci://ghcr.io/acme/charts"},
+			wantErr: true,
+		},
+		"embedded chart with a repository": {
+			chart:   platformv1.ChartSpec{Name: "microservice", Source: "embedded", Repository: "oci://ghcr.io/acme/charts", Version: "2.0.1"},
+			wantErr: true,
+		},
+		"helm repository without URL": {
+			chart:   platformv1.ChartSpec{Name: "nginx", Source: "helm-repo"},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		app := platformv1.ApplicationSpec{Name: "api", Chart: tt.chart}
+		source, err := r.resolveChartSource(claim, app)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil || source != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v, %v", name, tt.expected, source, err)
+		}
+	}
+
+	// Defaulting does not pin Git and Helm repository charts to the embedded chart version
+	defaulted := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{Applications: []platformv1.ApplicationSpec{
+		{Name: "api", Chart: platformv1.ChartSpec{Name: "charts/api", Source: "git", Repository: "https://github.com/acme/charts.git"}},
+		{Name: "web", Chart: platformv1.ChartSpec{Name: "nginx", Source: "helm-repo", Repository: "https://charts.bitnami.com/bitnami"}},
+	}}}
+	defaults.New().ApplyApplicationClaim(defaulted)
+	if source, err := r.resolveChartSource(claim, defaulted.Spec.Applications[0]); err != nil || source.TargetRevision != "HEAD" {
+		t.Errorf("expected a defaulted git chart to follow HEAD, got %+v, %v", source, err)
+	}
+	if source, err := r.resolveChartSource(claim, defaulted.Spec.Applications[1]); err != nil || source.TargetRevision != "*" {
+		t.Errorf("expected a defaulted helm repository chart to follow the latest version, got %+v, %v", source, err)
+	}
+
+	// The ApplicationSet template reads the source from config.json
+	source, _ := r.resolveChartSource(claim, platformv1.ApplicationSpec{Name: "api", Chart: tests["oci registry"].chart})
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(r.generateConfigJSON(claim, platformv1.ApplicationSpec{Name: "api"}, source, 0)), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["chartRepoURL"] != "ghcr.io/acme/charts" || config["chartName"] != "microservice" || config["chartPath"] != "" || config["chartRevision"] != "2.0.1" {
+		t.Errorf("unexpected chart source in config.json: %v", config)
+	}
+}
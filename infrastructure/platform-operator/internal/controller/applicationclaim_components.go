@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+)
+
+// componentLabel is set on every Application generated from a claim's components ApplicationSet
+const componentLabel = "platform.infraforge.io/component"
+
+// componentReplicaKeys maps a component type to the replica field of its chart
+var componentReplicaKeys = map[string]string{
+	"postgresql":    "instances",
+	"redis":         "clusterSize",
+	"rabbitmq":      "replicas",
+	"elasticsearch": "replicas",
+}
+
+// componentSizePresets resources applied when a component sets Size but no explicit resources
+var componentSizePresets = map[string]platformv1.ResourceRequirements{
+	"small": {
+		Requests: platformv1.ResourceList{CPU: "100m", Memory: "256Mi"},
+		Limits:   platformv1.ResourceList{CPU: "200m", Memory: "512Mi"},
+	},
+	"medium": {
+		Requests: platformv1.ResourceList{CPU: "500m", Memory: "1Gi"},
+		Limits:   platformv1.ResourceList{CPU: "1", Memory: "2Gi"},
+	},
+	"large": {
+		Requests: platformv1.ResourceList{CPU: "1", Memory: "2Gi"},
+		Limits:   platformv1.ResourceList{CPU: "2", Memory: "4Gi"},
+	},
+}
+
+// validateComponentNames checks that no component shares its name with an application; both are
+// deployed as ArgoCD Applications named <name>-<namespace>, which two ApplicationSets would fight over
+func validateComponentNames(claim *platformv1.ApplicationClaim) error {
+	apps := map[string]bool{}
+	for _, app := range claim.Spec.Applications {
+		apps[app.Name] = true
+	}
+	for _, comp := range claim.Spec.Components {
+		if apps[comp.Name] {
+			return fmt.Errorf("component %s has the same name as an application", comp.Name)
+		}
+	}
+	return nil
+}
+
+// componentsAppSetPath returns the voltran path of the claim's components ApplicationSet
+// Components live next to the platform ApplicationSets so the platform root app picks them up
+func (r *ApplicationClaimGitOpsReconciler) componentsAppSetPath(claim *platformv1.ApplicationClaim) string {
+	return fmt.Sprintf("appsets/%s/platform/%s-components-appset.yaml", claim.Spec.ClusterType, r.applicationNamespace(claim))
+}
+
+// componentsRoot returns the voltran directory owned by the claim for its components
+func (r *ApplicationClaimGitOpsReconciler) componentsRoot(claim *platformv1.ApplicationClaim) string {
+	return r.environmentDir(claim) + "/components"
+}
+
+// componentDir returns the voltran directory holding the generated files of a component
+func (r *ApplicationClaimGitOpsReconciler) componentDir(claim *platformv1.ApplicationClaim, componentName string) string {
+	return r.componentsRoot(claim) + "/" + componentName
+}
+
+// componentReleaseName returns the Helm release (and ArgoCD Application) name of a component
+func (r *ApplicationClaimGitOpsReconciler) componentReleaseName(claim *platformv1.ApplicationClaim, componentName string) string {
+	return fmt.Sprintf("%s-%s", componentName, r.applicationNamespace(claim))
+}
+
+// generateComponentsApplicationSet generates the ArgoCD ApplicationSet deploying the claim's
+// components into the application namespace, using the platform service charts
+func (r *ApplicationClaimGitOpsReconciler) generateComponentsApplicationSet(claim *platformv1.ApplicationClaim) string {
+	namespace := r.applicationNamespace(claim)
+
+	var elements []map[string]interface{}
+	for _, comp := range claim.Spec.Components {
+		elements = append(elements, map[string]interface{}{
+			"name":  comp.Name,
+			"chart": comp.Type,
+		})
+	}
+
+	appSet := map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "ApplicationSet",
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("%s-components", namespace),
+			"namespace": "argocd",
+			"labels": map[string]string{
+				"platform.infraforge.io/environment": claim.Spec.Environment,
+				"platform.infraforge.io/cluster":     claim.Spec.ClusterType,
+				"platform.infraforge.io/type":        "component",
+			},
+		},
+		"spec": map[string]interface{}{
+			"generators": []map[string]interface{}{
+				{
+					"list": map[string]interface{}{
+						"elements": elements,
+					},
+				},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name": fmt.Sprintf("{{name}}-%s", namespace),
+					"labels": map[string]string{
+						componentLabel:                "{{name}}",
+						envLabel:                      claim.Spec.Environment,
+						namespaceLabel:                namespace,
+						"platform.infraforge.io/type": "component",
+					},
+				},
+				"spec": map[string]interface{}{
+					"project": "default",
+					"sources": []map[string]interface{}{
+						{
+							// Source 1: Helm chart from ChartMuseum
+							"repoURL":        "http://chartmuseum.chartmuseum.svc.cluster.local:8080",
+							"chart":          "{{chart}}",
+							"targetRevision": "*",
+							"helm": map[string]interface{}{
+								"valueFiles": []string{
+									valueFile(r.Encryptor, fmt.Sprintf("$values/%s/{{name}}/values.yaml", r.componentsRoot(claim))),
+								},
+							},
+						},
+						{
+							// Source 2: Values from voltran repository
+							"repoURL":        fmt.Sprintf("%s/%s/%s", claim.Spec.GiteaURL, claim.Spec.Organization, r.VoltranRepo),
+							"targetRevision": r.Branch,
+							"ref":            "values",
+						},
+					},
+					"destination": map[string]interface{}{
+						"server":    "https://kubernetes.default.svc",
+						"namespace": namespace,
+					},
+					"syncPolicy": map[string]interface{}{
+						"automated": map[string]interface{}{
+							"prune":    true,
+							"selfHeal": true,
+						},
+						"syncOptions": []string{"CreateNamespace=true"},
+					},
+				},
+			},
+		},
+	}
+
+	data, _ := yaml.Marshal(appSet)
+	return string(data)
+}
+
+// generateComponentValuesYAML generates Helm values.yaml for a component
+// Typed fields (storage, replicas, resources, size) are applied first, Config overrides them
+func (r *ApplicationClaimGitOpsReconciler) generateComponentValuesYAML(claim *platformv1.ApplicationClaim, comp platformv1.ComponentSpec) (string, error) {
+	section := map[string]interface{}{}
+
+	if comp.Storage != "" {
+		section["storage"] = map[string]interface{}{
+			"size": comp.Storage,
+		}
+	}
+
+	if comp.Replicas > 0 {
+		if key, ok := componentReplicaKeys[comp.Type]; ok {
+			section[key] = comp.Replicas
+		}
+	}
+
+	resources := comp.Resources
+	if resources == (platformv1.ResourceRequirements{}) {
+		resources = componentSizePresets[comp.Size]
+	}
+	if resourceValues := buildResourceValues(resources); resourceValues != nil {
+		section["resources"] = resourceValues
+	}
+
+	customValues := map[string]interface{}{}
+	if len(section) > 0 {
+		customValues[comp.Type] = section
+	}
+
+	if comp.Config.Raw != nil {
+		var config map[string]interface{}
+		if err := yaml.Unmarshal(comp.Config.Raw, &config); err != nil {
+			return "", fmt.Errorf("invalid config for component %s: %w", comp.Name, err)
+		}
+		mergeDeep(customValues, config)
+	}
+
+	d := defaults.OrBuiltin(r.Defaults)
+	values := buildPlatformServiceValues(comp.Name, comp.Type, d.ServiceVersion(comp.Type, comp.Version), d.StorageClass, customValues)
+
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal values for component %s: %w", comp.Name, err)
+	}
+	return string(data), nil
+}
+
+// buildResourceValues converts ResourceRequirements to Helm values, nil if nothing is set
+func buildResourceValues(res platformv1.ResourceRequirements) map[string]interface{} {
+	resources := map[string]interface{}{}
+	for key, list := range map[string]platformv1.ResourceList{"requests": res.Requests, "limits": res.Limits} {
+		entry := map[string]interface{}{}
+		if list.CPU != "" {
+			entry["cpu"] = list.CPU
+		}
+		if list.Memory != "" {
+			entry["memory"] = list.Memory
+		}
+		if len(entry) > 0 {
+			resources[key] = entry
+		}
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	return resources
+}
+
+// componentConnection returns the in-cluster connection string and credentials secret
+// exposed by the operator backing a component type
+func (r *ApplicationClaimGitOpsReconciler) componentConnection(claim *platformv1.ApplicationClaim, comp platformv1.ComponentSpec) (string, string) {
+	release := r.componentReleaseName(claim, comp.Name)
+	namespace := r.applicationNamespace(claim)
+
+	switch comp.Type {
+	case "postgresql":
+		// CloudNativePG: read-write service and application user secret
+		return fmt.Sprintf("postgresql://%s-rw.%s.svc.cluster.local:5432/app", release, namespace), release + "-app"
+	case "redis":
+		// Redis operator: leader service, no authentication by default
+		return fmt.Sprintf("redis://%s-leader.%s.svc.cluster.local:6379", release, namespace), ""
+	case "rabbitmq":
+		// RabbitMQ cluster operator: client service and default user secret
+		return fmt.Sprintf("amqp://%s.%s.svc.cluster.local:5672", release, namespace), release + "-default-user"
+	case "elasticsearch":
+		// ECK: HTTP service and elastic user secret
+		return fmt.Sprintf("https://%s-es-http.%s.svc.cluster.local:9200", release, namespace), release + "-es-elastic-user"
+	}
+	return "", ""
+}
+
+// collectComponentStatuses builds per-component status entries from the live ArgoCD Applications
+// and reports whether every component is Synced and Healthy
+func (r *ApplicationClaimGitOpsReconciler) collectComponentStatuses(ctx context.Context, claim *platformv1.ApplicationClaim) ([]platformv1.ComponentStatus, bool, error) {
+	if len(claim.Spec.Components) == 0 {
+		return nil, true, nil
+	}
+
+	argoApps := &unstructured.UnstructuredList{}
+	argoApps.SetGroupVersionKind(argoApplicationGVK.GroupVersion().WithKind("ApplicationList"))
+	if err := r.List(ctx, argoApps,
+		client.InNamespace(argoCDNamespace),
+		client.MatchingLabels{envLabel: claim.Spec.Environment},
+		client.HasLabels{componentLabel}); err != nil {
+		return nil, false, fmt.Errorf("failed to list ArgoCD Applications: %w", err)
+	}
+
+	byName := make(map[string]*unstructured.Unstructured, len(argoApps.Items))
+	for i := range argoApps.Items {
+		if labels := argoApps.Items[i].GetLabels(); r.generatedFor(claim, labels) {
+			byName[labels[componentLabel]] = &argoApps.Items[i]
+		}
+	}
+
+	statuses := []platformv1.ComponentStatus{}
+	allReady := true
+	for _, comp := range claim.Spec.Components {
+		connection, secretName := r.componentConnection(claim, comp)
+		status := platformv1.ComponentStatus{
+			Name:             comp.Name,
+			Type:             comp.Type,
+			ConnectionString: connection,
+			SecretName:       secretName,
+			SyncStatus:       "Unknown",
+			HealthStatus:     "Missing",
+		}
+
+		if argoApp, ok := byName[comp.Name]; ok {
+			status.SyncStatus, status.HealthStatus = argoSyncHealth(argoApp)
+			status.Ready = status.SyncStatus == "Synced" && status.HealthStatus == "Healthy"
+		} else {
+			log.FromContext(ctx).V(1).Info("ArgoCD Application for component not found yet", "component", comp.Name)
+		}
+
+		if !status.Ready {
+			allReady = false
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, allReady, nil
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+func TestGenerateComponentValuesYAML(t *testing.T) {
+	r := &ApplicationClaimGitOpsReconciler{}
+	claim := &platformv1.ApplicationClaim{
+		Spec: platformv1.ApplicationClaimSpec{Environment: "dev", ClusterType: "nonprod"},
+	}
+	comp := platformv1.ComponentSpec{
+		Name:     "orders-db",
+		Type:     "postgresql",
+		Version:  "16",
+		Size:     "medium",
+		Storage:  "20Gi",
+		Replicas: 3,
+		Config:   runtime.RawExtension{Raw: []byte(`{"postgresql":{"storage":{"size":"50Gi"}}}`)},
+	}
+
+	out, err := r.generateComponentValuesYAML(claim, comp)
+	if err != nil {
+		t.Fatalf("generateComponentValuesYAML failed: %v", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(out), &values); err != nil {
+		t.Fatalf("generated values are not valid YAML: %v", err)
+	}
+	if values["version"] != "16" {
+		t.Errorf("expected version 16, got %v", values["version"])
+	}
+
+	pg := values["postgresql"].(map[string]interface{})
+	storage := pg["storage"].(map[string]interface{})
+	if storage["size"] != "50Gi" {
+		t.Errorf("expected config to override storage size, got %v", storage["size"])
+	}
+	if storage["storageClass"] != "standard" {
+		t.Errorf("expected default storage class to be kept, got %v", storage["storageClass"])
+	}
+	if pg["instances"] != 3 {
+		t.Errorf("expected 3 instances, got %v", pg["instances"])
+	}
+	requests := pg["resources"].(map[string]interface{})["requests"].(map[string]interface{})
+	if requests["memory"] != "1Gi" {
+		t.Errorf("expected medium size preset, got %v", requests["memory"])
+	}
+
+	if conn, secret := r.componentConnection(claim, comp); conn != "postgresql://orders-db-dev-rw.dev.svc.cluster.local:5432/app" || secret != "orders-db-dev-app" {
+		t.Errorf("unexpected connection %q / %q", conn, secret)
+	}
+}
+
+func TestValidateComponentNames(t *testing.T) {
+	claim := &platformv1.ApplicationClaim{
+		Spec: platformv1.ApplicationClaimSpec{
+			Applications: []platformv1.ApplicationSpec{{Name: "orders"}},
+			Components:   []platformv1.ComponentSpec{{Name: "orders-db", Type: "postgresql"}},
+		},
+	}
+	if err := validateComponentNames(claim); err != nil {
+		t.Errorf("expected distinct names to be valid, got %v", err)
+	}
+	claim.Spec.Components = append(claim.Spec.Components, platformv1.ComponentSpec{Name: "orders", Type: "redis"})
+	if err := validateComponentNames(claim); err == nil {
+		t.Error("expected a component named like an application to be rejected")
+	}
+}
@@ -4,21 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	platformv1 "github.com/infraforge/platform-operator/api/v1"
-	"github.com/infraforge/platform-operator/pkg/gitea"
+	"github.com/infraforge/platform-operator/internal/defaults"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+	"github.com/infraforge/platform-operator/pkg/sops"
 )
 
+// applicationClaimFinalizer guards removal of the GitOps files generated for an ApplicationClaim
+const applicationClaimFinalizer = "platform.infraforge.io/gitops-cleanup"
+
 // ApplicationClaimGitOpsReconciler reconciles ApplicationClaim with GitOps
 type ApplicationClaimGitOpsReconciler struct {
 	client.Client
@@ -29,11 +40,46 @@ type ApplicationClaimGitOpsReconciler struct {
 	GiteaToken    string
 	VoltranRepo   string
 	Branch        string
+
+	// DefaultPullSecrets registry credentials added to every application's imagePullSecrets
+	DefaultPullSecrets []string
+
+	// RejectMutableProdTags refuses empty and "latest" image tags in prod claims
+	RejectMutableProdTags bool
+
+	// NamespaceTemplate destination namespace of claims without Spec.Namespace (e.g. "{team}-{env}")
+	NamespaceTemplate string
+
+	// PullRequestClusterTypes cluster types whose claims are applied through voltran pull requests
+	// unless Spec.GitOpsMode says otherwise
+	PullRequestClusterTypes []string
+
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+	// DriftCheckInterval how often reconciled claims are compared with the voltran branch,
+	// DefaultDriftCheckInterval when zero
+	DriftCheckInterval time.Duration
+
+	// Encryptor encrypts the sensitive values of generated files with sops, nil to commit them in plain text
+	Encryptor *sops.Encryptor
+
+	// Recorder emits Events on claims, none when nil
+	Recorder record.EventRecorder
+
+	// APIReader reads credentials Secrets from the API server so that not every Secret of the
+	// cluster is cached; the client when nil
+	APIReader client.Reader
 }
 
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=applicationclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=applicationclaims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=applicationclaims/finalizers,verbs=update
+//+kubebuilder:rbac:groups=platform.infraforge.io,resources=platformapplicationclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
 
 // Reconcile handles ApplicationClaim reconciliation with GitOps
 func (r *ApplicationClaimGitOpsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -50,6 +96,20 @@ func (r *ApplicationClaimGitOpsReconciler) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{}, err
 	}
 
+	// Handle deletion - remove generated files from Git before releasing the claim
+	if !claim.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, claim)
+	}
+
+	// Ensure finalizer is present so deletion cleans up the voltran repository
+	if !controllerutil.ContainsFinalizer(claim, applicationClaimFinalizer) {
+		controllerutil.AddFinalizer(claim, applicationClaimFinalizer)
+		if err := r.Update(ctx, claim); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	// Initialize status if needed
 	if claim.Status.Phase == "" {
 		claim.Status.Phase = "Pending"
@@ -60,23 +120,67 @@ func (r *ApplicationClaimGitOpsReconciler) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Refuse to overwrite the namespace or voltran files of an older claim
+	conflict, err := r.findConflictingClaim(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conflict != nil {
+		message := fmt.Sprintf("namespace %s or its GitOps paths are already used by ApplicationClaim %s/%s",
+			r.applicationNamespace(claim), conflict.Namespace, conflict.Name)
+		logger.Info("Refusing conflicting ApplicationClaim", "conflictsWith", conflict.Namespace+"/"+conflict.Name)
+		if _, err := r.updateStatusFailed(ctx, claim, ReasonConflict, message); err != nil {
+			return ctrl.Result{}, err
+		}
+		// Retry in case the other claim goes away
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
 	// Always reconcile to handle spec changes
 	// This ensures updates to the ApplicationClaim are always processed
 
-	// Create GiteaClient dynamically from claim
-	giteaClient := gitea.NewClient(claim.Spec.GiteaURL, r.GiteaUsername, r.GiteaToken)
+	// Fill unset fields the same way the defaulting webhook does
+	defaults.OrBuiltin(r.Defaults).ApplyApplicationClaim(claim)
+
+	// Git provider of the claim, with the credentials of its Secret
+	provider, err := r.gitProvider(ctx, claim)
+	if err != nil {
+		logger.Error(err, "failed to set up Git provider")
+		return r.updateStatusPushFailed(ctx, claim, ReasonInvalidCredentials, err)
+	}
 
 	// Generate ApplicationSet and values.yaml
 	logger.Info("Generating ApplicationSet and values", "environment", claim.Spec.Environment)
 
 	files := make(map[string]string)
 
+	// Order applications after the applications, components and platform services they depend on
+	err = validateComponentNames(claim)
+	var waves map[string]int
+	if err == nil {
+		waves, err = applicationWaves(claim)
+	}
+	var dependenciesReady map[string]bool
+	if err == nil {
+		dependenciesReady, err = r.resolveExternalDependencies(ctx, claim)
+	}
+	if err != nil {
+		logger.Error(err, "invalid application dependencies")
+		return r.updateStatusFailed(ctx, claim, ReasonInvalidSpec, err.Error())
+	}
+	// Applications are only created once the components and platform services they depend on
+	// are Healthy; other ApplicationSets cannot be ordered by sync waves
+	held := heldApplications(claim, waves, dependenciesReady)
+
 	// Generate ApplicationSet
-	appSetPath := fmt.Sprintf("appsets/%s/apps/%s-appset.yaml", claim.Spec.ClusterType, claim.Spec.Environment)
-	appSetContent := r.generateApplicationSet(claim)
+	appSetPath := r.appSetPath(claim)
+	appSetContent := r.generateApplicationSet(claim, waves)
 	files[appSetPath] = appSetContent
 	logger.Info("Generated ApplicationSet content", "path", appSetPath, "length", len(appSetContent))
 
+	// Keep the applications directory itself; everything else under it is owned by the claim
+	files[r.applicationsRoot(claim)+"/.gitkeep"] = ""
+
 	// Generate directory structure for each application
 	enabledCount := 0
 	for _, app := range claim.Spec.Applications {
@@ -86,36 +190,119 @@ func (r *ApplicationClaimGitOpsReconciler) Reconcile(ctx context.Context, req ct
 			continue
 		}
 		enabledCount++
+		if reason, ok := held[app.Name]; ok {
+			logger.Info("Holding back application", "name", app.Name, "reason", reason)
+			continue
+		}
+
+		err := validateApplication(app)
+		if err == nil {
+			err = r.validateImagePolicy(claim, app)
+		}
+		var source chartSource
+		if err == nil {
+			source, err = r.resolveChartSource(claim, app)
+		}
+		if err != nil {
+			logger.Error(err, "invalid application spec", "app", app.Name)
+			return r.updateStatusFailed(ctx, claim, ReasonInvalidSpec, err.Error())
+		}
 
 		// values.yaml
-		valuesPath := fmt.Sprintf("environments/%s/%s/applications/%s/values.yaml", claim.Spec.ClusterType, claim.Spec.Environment, app.Name)
+		valuesPath := r.applicationDir(claim, app.Name) + "/values.yaml"
 		valuesContent := r.generateValuesYAML(claim, app)
 		files[valuesPath] = valuesContent
 
 		// config.json (metadata for ApplicationSet)
-		configPath := fmt.Sprintf("environments/%s/%s/applications/%s/config.json", claim.Spec.ClusterType, claim.Spec.Environment, app.Name)
-		configContent := r.generateConfigJSON(claim, app)
+		configPath := r.applicationDir(claim, app.Name) + "/config.json"
+		configContent := r.generateConfigJSON(claim, app, source, waves[app.Name])
 		files[configPath] = configContent
 
 		logger.Info("Generated application files", "app", app.Name, "valuesPath", valuesPath, "configPath", configPath)
 	}
 
-	logger.Info("Total files to push", "fileCount", len(files), "enabledApps", enabledCount)
+	// Components (databases, caches, brokers) deployed next to the applications
+	if len(claim.Spec.Components) > 0 {
+		files[r.componentsAppSetPath(claim)] = r.generateComponentsApplicationSet(claim)
+		for _, comp := range claim.Spec.Components {
+			valuesContent, err := r.generateComponentValuesYAML(claim, comp)
+			if err != nil {
+				logger.Error(err, "failed to generate component values", "component", comp.Name)
+				return r.updateStatusFailed(ctx, claim, ReasonInvalidSpec, err.Error())
+			}
+			files[r.componentDir(claim, comp.Name)+"/values.yaml"] = valuesContent
+			logger.Info("Generated component files", "component", comp.Name, "type", comp.Type)
+		}
+	}
+
+	logger.Info("Total files to push", "fileCount", len(files), "enabledApps", enabledCount, "components", len(claim.Spec.Components))
+	conditions := r.conditions(claim)
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered,
+		fmt.Sprintf("rendered %d files for %d applications and %d components", len(files), enabledCount, len(claim.Spec.Components)))
 
 	// Push to Gitea - use internal clone URL
-	voltranURL := giteaClient.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo)
+	voltranURL := provider.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo)
 	commitMsg := fmt.Sprintf("Update %s environment applications by operator", claim.Spec.Environment)
+	owned := r.ownedPaths(claim)
+
+	change := gitOpsChange{
+		Organization: claim.Spec.Organization,
+		Repo:         r.VoltranRepo,
+		Branch:       r.Branch,
+		Owned:        owned,
+		Files:        files,
+		CommitMsg:    commitMsg,
+		Title: fmt.Sprintf("Update %s applications of ApplicationClaim %s/%s",
+			claim.Spec.Environment, claim.Namespace, claim.Name),
+	}
 
-	logger.Info("Pushing files to Gitea", "url", voltranURL, "branch", r.Branch, "commitMsg", commitMsg)
-
-	if err := giteaClient.PushFiles(ctx, voltranURL, r.Branch, files, commitMsg,
-		"Platform Operator", "operator@platform.local"); err != nil {
-		logger.Error(err, "failed to push to Git", "url", voltranURL)
-		// Don't update status on git errors, just retry
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	var sha string
+	if usePullRequest(claim.Spec.GitOpsMode, claim.Spec.ClusterType, r.PullRequestClusterTypes) {
+		// Changes reach the branch through a reviewed pull request
+		pr, mergedSHA, err := syncPullRequest(ctx, provider, claim, claim.Status.PullRequest, change)
+		if err != nil {
+			logger.Error(err, "failed to sync pull request", "url", voltranURL)
+			return r.updateStatusPushFailed(ctx, claim, ReasonPushFailed, err)
+		}
+		claim.Status.PullRequest = pr
+		setPullRequestCondition(&claim.Status.Conditions, pr, claim.Generation)
+		if pr != nil && pr.State != pullRequestMerged {
+			return r.updateStatusPullRequest(ctx, claim)
+		}
+		sha = mergedSHA
+		if sha == "" {
+			sha = claim.Status.LastCommit
+		}
+		if err := reportDrift(ctx, provider, claim, claim.Status.ObservedGeneration, change, conditions); err != nil {
+			logger.Error(err, "failed to check drift", "url", voltranURL)
+			return r.updateStatusPushFailed(ctx, claim, ReasonPushFailed, err)
+		}
+	} else {
+		logger.Info("Pushing files to Gitea", "url", voltranURL, "branch", r.Branch, "commitMsg", commitMsg)
+
+		// Sync prunes applications that were removed from the claim or disabled
+		pushed, err := syncDirect(ctx, provider, claim, claim.Status.ObservedGeneration, claim.Status.LastCommit,
+			claim.Status.RenderedHash, claim.Spec.DriftPolicy, change, conditions)
+		if err != nil {
+			logger.Error(err, "failed to push to Git", "url", voltranURL)
+			return r.updateStatusPushFailed(ctx, claim, ReasonPushFailed, err)
+		}
+		sha = pushed
+		claim.Status.RenderedHash = renderedHash(change)
+		claim.Status.PullRequest = nil
+		setPullRequestCondition(&claim.Status.Conditions, nil, claim.Generation)
 	}
 
-	logger.Info("Successfully pushed files to Git")
+	logger.Info("Generated files are on the voltran branch", "commit", sha)
+	conditions.set(ConditionGitPushed, metav1.ConditionTrue, ReasonPushed, fmt.Sprintf("generated files are in voltran commit %s", sha))
+	// The generation is only recorded once no application is held back, so that releasing held
+	// applications is pushed rather than taken for drift; in pullRequest mode the released
+	// applications change the rendered files, which opens a follow-up pull request
+	if len(held) == 0 {
+		claim.Status.ObservedGeneration = claim.Generation
+	}
+	claim.Status.LastCommit = sha
+	claim.Status.CommitURL = provider.CommitURL(claim.Spec.Organization, r.VoltranRepo, sha)
 
 	// DISABLED: Direct Application creation - Root Apps will watch ApplicationSets and create them
 	// // Create individual Applications in ArgoCD namespace
@@ -133,28 +320,275 @@ func (r *ApplicationClaimGitOpsReconciler) Reconcile(ctx context.Context, req ct
 	// 	logger.Info("Created Application", "name", app.Name)
 	// }
 
-	// Update status to Ready only if not already ready
-	if claim.Status.Phase != "Ready" || !claim.Status.Ready {
+	// Pushed is not deployed - report readiness from the live ArgoCD Applications
+	statuses, allReady, err := r.collectApplicationStatuses(ctx, claim, held)
+	if err != nil {
+		logger.Error(err, "failed to collect application statuses")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	componentStatuses, componentsReady, err := r.collectComponentStatuses(ctx, claim)
+	if err != nil {
+		logger.Error(err, "failed to collect component statuses")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	claim.Status.Applications = statuses
+	claim.Status.ApplicationsReady = allReady
+	claim.Status.Components = componentStatuses
+	claim.Status.ComponentsReady = componentsReady
+	conditions.setArgoConditions(claimArgoStates(statuses, componentStatuses))
+	conditions.setReady(ConditionRendered, ConditionGitPushed, ConditionSynced, ConditionHealthy)
+	allReady = allReady && componentsReady
+	claim.Status.Message = ""
+	if len(held) > 0 {
+		claim.Status.Message = fmt.Sprintf("%d applications wait for their dependencies", len(held))
+	}
+	claim.Status.Ready = allReady
+	if allReady {
 		claim.Status.Phase = "Ready"
-		claim.Status.Ready = true
-		claim.Status.ApplicationsReady = true
-		claim.Status.LastUpdated = metav1.Now()
-		if err := r.Status().Update(ctx, claim); err != nil {
-			logger.Error(err, "failed to update status")
-			return ctrl.Result{Requeue: true}, nil
-		}
+	} else {
+		claim.Status.Phase = "Provisioning"
+	}
+	claim.Status.LastUpdated = metav1.Now()
+	if err := r.Status().Update(ctx, claim); err != nil {
+		logger.Error(err, "failed to update status")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if !allReady {
+		// Application watch triggers on changes; poll as a safety net while rolling out
+		logger.Info("Waiting for applications and components to become Synced and Healthy")
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
 	logger.Info("ApplicationClaim reconciliation completed successfully")
+	// Compare the voltran files with the claim again later
+	return ctrl.Result{RequeueAfter: driftCheckInterval(r.DriftCheckInterval)}, nil
+}
+
+// reconcileDelete removes everything the claim generated in the voltran repository and
+// releases the finalizer only after the removal has been pushed
+func (r *ApplicationClaimGitOpsReconciler) reconcileDelete(ctx context.Context, claim *platformv1.ApplicationClaim) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(claim, applicationClaimFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// A claim refused for a conflict never wrote anything; the files belong to the other claim
+	conflict, err := r.findConflictingClaim(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conflict != nil {
+		logger.Info("Skipping GitOps cleanup of conflicting ApplicationClaim", "conflictsWith", conflict.Namespace+"/"+conflict.Name)
+		controllerutil.RemoveFinalizer(claim, applicationClaimFinalizer)
+		return ctrl.Result{}, r.Update(ctx, claim)
+	}
+
+	// Remove the ApplicationSet and every application directory, keeping the empty environment layout
+	owned := r.ownedPaths(claim)
+	files := map[string]string{r.applicationsRoot(claim) + "/.gitkeep": ""}
+
+	provider, err := r.gitProvider(ctx, claim)
+	if errors.IsNotFound(err) {
+		// The Secret may be deleted together with the namespace; the files belong to the operator
+		logger.Info("Git credentials Secret is gone, removing GitOps files with the operator credentials")
+		provider, err = gitprovider.New(r.gitConfig(claim))
+	}
+	if err != nil {
+		logger.Error(err, "failed to set up Git provider")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	closeOpenPullRequest(ctx, provider, claim.Status.PullRequest, claim.Spec.Organization, r.VoltranRepo)
+	voltranURL := provider.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo)
+	commitMsg := fmt.Sprintf("Remove %s environment applications of %s/%s by operator",
+		claim.Spec.Environment, claim.Namespace, claim.Name)
+
+	logger.Info("Removing GitOps files for deleted ApplicationClaim", "url", voltranURL, "paths", owned)
+
+	if _, err := provider.SyncFiles(ctx, voltranURL, r.Branch, owned, files, commitMsg,
+		"Platform Operator", "operator@platform.local"); err != nil {
+		logger.Error(err, "failed to remove GitOps files", "url", voltranURL)
+		// Keep the finalizer until the removal reaches Git
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	controllerutil.RemoveFinalizer(claim, applicationClaimFinalizer)
+	if err := r.Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("ApplicationClaim GitOps files removed, finalizer released")
+	return ctrl.Result{}, nil
+}
+
+// updateStatusPullRequest reports a pull request that is not merged yet; open pull requests are
+// polled, a pull request closed without merging waits for the next claim change
+func (r *ApplicationClaimGitOpsReconciler) updateStatusPullRequest(ctx context.Context, claim *platformv1.ApplicationClaim) (ctrl.Result, error) {
+	pr := claim.Status.PullRequest
+	claim.Status.Ready = false
+	claim.Status.Message = meta.FindStatusCondition(claim.Status.Conditions, pullRequestCondition).Message
+	claim.Status.LastUpdated = metav1.Now()
+	reason := ReasonAwaitingReview
+	if pr.State == pullRequestClosed {
+		claim.Status.Phase = "Failed"
+		reason = ReasonPullRequestClosed
+	} else {
+		claim.Status.Phase = "AwaitingReview"
+	}
+	conditions := r.conditions(claim)
+	conditions.set(ConditionGitPushed, metav1.ConditionFalse, reason, claim.Status.Message)
+	conditions.setReady(ConditionRendered, ConditionGitPushed, ConditionSynced, ConditionHealthy)
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+	if pr.State == pullRequestClosed {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+}
+
+// updateStatusFailed marks the claim Failed because its files could not be rendered; the claim is
+// retried on its next change
+func (r *ApplicationClaimGitOpsReconciler) updateStatusFailed(ctx context.Context, claim *platformv1.ApplicationClaim, reason, message string) (ctrl.Result, error) {
+	claim.Status.Phase = "Failed"
+	claim.Status.Ready = false
+	claim.Status.Message = message
+	claim.Status.LastUpdated = metav1.Now()
+	conditions := r.conditions(claim)
+	conditions.set(ConditionRendered, metav1.ConditionFalse, reason, message)
+	conditions.setReady(ConditionRendered, ConditionGitPushed, ConditionSynced, ConditionHealthy)
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
 	return ctrl.Result{}, nil
 }
 
+// updateStatusPushFailed reports that the generated files could not reach the voltran branch
+// and retries shortly
+func (r *ApplicationClaimGitOpsReconciler) updateStatusPushFailed(ctx context.Context, claim *platformv1.ApplicationClaim, reason string, pushErr error) (ctrl.Result, error) {
+	claim.Status.Ready = false
+	claim.Status.Message = "Failed to push to Git: " + pushErr.Error()
+	claim.Status.LastUpdated = metav1.Now()
+	conditions := r.conditions(claim)
+	conditions.set(ConditionGitPushed, metav1.ConditionFalse, reason, pushErr.Error())
+	conditions.setReady(ConditionRendered, ConditionGitPushed, ConditionSynced, ConditionHealthy)
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+}
+
+// gitProvider returns the Git provider of the claim, with the credentials of its Secret if it has one
+func (r *ApplicationClaimGitOpsReconciler) gitProvider(ctx context.Context, claim *platformv1.ApplicationClaim) (gitprovider.GitProvider, error) {
+	reader := client.Reader(r.Client)
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	return claimGitProvider(ctx, reader, claim.Namespace, claim.Spec.CredentialsSecretRef, r.gitConfig(claim))
+}
+
+// gitConfig returns the Git provider configuration of the claim with the operator credentials
+func (r *ApplicationClaimGitOpsReconciler) gitConfig(claim *platformv1.ApplicationClaim) gitprovider.Config {
+	return gitprovider.Config{
+		Provider: gitProviderOf(claim.Spec.GitProvider, r.Defaults),
+		URL:      claim.Spec.GiteaURL,
+		Username: r.GiteaUsername,
+		Token:    r.GiteaToken,
+		Encoder:  r.Encryptor,
+	}
+}
+
+// conditions returns the condition setter of the claim
+func (r *ApplicationClaimGitOpsReconciler) conditions(claim *platformv1.ApplicationClaim) *claimConditions {
+	return newClaimConditions(claim, &claim.Status.Conditions, r.Recorder)
+}
+
+// applicationNamespace returns the namespace applications and components of the claim are deployed to:
+// Spec.Namespace when set, the operator's namespace template otherwise
+func (r *ApplicationClaimGitOpsReconciler) applicationNamespace(claim *platformv1.ApplicationClaim) string {
+	if claim.Spec.Namespace != "" {
+		return claim.Spec.Namespace
+	}
+	template := r.NamespaceTemplate
+	if template == "" {
+		template = DefaultApplicationNamespaceTemplate
+	}
+	return renderNamespace(template, namespaceVars{
+		Team:           claim.Spec.Owner.Team,
+		Env:            claim.Spec.Environment,
+		Cluster:        claim.Spec.ClusterType,
+		ClaimName:      claim.Name,
+		ClaimNamespace: claim.Namespace,
+	})
+}
+
+// environmentDir returns the voltran directory holding the claim's generated files
+// Claims deploying to the environment namespace keep the bootstrap layout, others get a directory per namespace
+func (r *ApplicationClaimGitOpsReconciler) environmentDir(claim *platformv1.ApplicationClaim) string {
+	namespace := r.applicationNamespace(claim)
+	if namespace == claim.Spec.Environment {
+		return fmt.Sprintf("environments/%s/%s", claim.Spec.ClusterType, claim.Spec.Environment)
+	}
+	return fmt.Sprintf("environments/%s/%s/namespaces/%s", claim.Spec.ClusterType, claim.Spec.Environment, namespace)
+}
+
+// appSetPath returns the voltran path of the claim's application ApplicationSet
+func (r *ApplicationClaimGitOpsReconciler) appSetPath(claim *platformv1.ApplicationClaim) string {
+	return fmt.Sprintf("appsets/%s/apps/%s-appset.yaml", claim.Spec.ClusterType, r.applicationNamespace(claim))
+}
+
+// applicationsRoot returns the voltran directory owned by the claim for its applications
+func (r *ApplicationClaimGitOpsReconciler) applicationsRoot(claim *platformv1.ApplicationClaim) string {
+	return r.environmentDir(claim) + "/applications"
+}
+
+// applicationDir returns the voltran directory holding the generated files of an application
+func (r *ApplicationClaimGitOpsReconciler) applicationDir(claim *platformv1.ApplicationClaim, appName string) string {
+	return r.applicationsRoot(claim) + "/" + appName
+}
+
+// ownedPaths returns every voltran path the claim generates and prunes
+func (r *ApplicationClaimGitOpsReconciler) ownedPaths(claim *platformv1.ApplicationClaim) []string {
+	return []string{r.appSetPath(claim), r.applicationsRoot(claim), r.componentsAppSetPath(claim), r.componentsRoot(claim)}
+}
+
+// findConflictingClaim returns an older ApplicationClaim that already deploys to the same
+// namespace or writes the same voltran paths as claim, nil if there is none
+func (r *ApplicationClaimGitOpsReconciler) findConflictingClaim(ctx context.Context, claim *platformv1.ApplicationClaim) (*platformv1.ApplicationClaim, error) {
+	claims := &platformv1.ApplicationClaimList{}
+	if err := r.List(ctx, claims); err != nil {
+		return nil, fmt.Errorf("failed to list ApplicationClaims: %w", err)
+	}
+
+	namespace := r.applicationNamespace(claim)
+	owned := map[string]bool{}
+	for _, path := range r.ownedPaths(claim) {
+		owned[path] = true
+	}
+
+	for i := range claims.Items {
+		other := &claims.Items[i]
+		if other.UID == claim.UID || !claimPrecedes(other, claim) {
+			continue
+		}
+		if r.applicationNamespace(other) == namespace {
+			return other, nil
+		}
+		for _, path := range r.ownedPaths(other) {
+			if owned[path] {
+				return other, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 // generateApplication generates a simple ArgoCD Application manifest
 func (r *ApplicationClaimGitOpsReconciler) generateApplication(claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec) string {
 	chartName := app.Chart.Name
-	if chartName == "" {
-		chartName = "microservice"
-	}
 
 	// Get values as YAML string
 	valuesMap := r.buildCRDOverrides(app)
@@ -164,11 +598,12 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplication(claim *platformv1
 		"apiVersion": "argoproj.io/v1alpha1",
 		"kind":       "Application",
 		"metadata": map[string]interface{}{
-			"name":      fmt.Sprintf("%s-%s", app.Name, claim.Spec.Environment),
+			"name":      fmt.Sprintf("%s-%s", app.Name, r.applicationNamespace(claim)),
 			"namespace": "argocd",
 			"labels": map[string]string{
-				"platform.infraforge.io/app": app.Name,
-				"platform.infraforge.io/env": claim.Spec.Environment,
+				appLabel:       app.Name,
+				envLabel:       claim.Spec.Environment,
+				namespaceLabel: r.applicationNamespace(claim),
 			},
 		},
 		"spec": map[string]interface{}{
@@ -183,7 +618,7 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplication(claim *platformv1
 			},
 			"destination": map[string]interface{}{
 				"server":    "https://kubernetes.default.svc",
-				"namespace": claim.Spec.Environment,
+				"namespace": r.applicationNamespace(claim),
 			},
 			"syncPolicy": map[string]interface{}{
 				"automated": map[string]interface{}{
@@ -200,13 +635,16 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplication(claim *platformv1
 }
 
 // generateApplicationSet generates ArgoCD ApplicationSet manifest - one per application
-func (r *ApplicationClaimGitOpsReconciler) generateApplicationSet(claim *platformv1.ApplicationClaim) string {
+// Applications in different sync waves are rolled out one wave after the other
+func (r *ApplicationClaimGitOpsReconciler) generateApplicationSet(claim *platformv1.ApplicationClaim, waves map[string]int) string {
+	namespace := r.applicationNamespace(claim)
+
 	// Use Git Files Generator to read config.json from each application directory
 	appSet := map[string]interface{}{
 		"apiVersion": "argoproj.io/v1alpha1",
 		"kind":       "ApplicationSet",
 		"metadata": map[string]interface{}{
-			"name":      fmt.Sprintf("%s-apps", claim.Spec.Environment),
+			"name":      fmt.Sprintf("%s-apps", namespace),
 			"namespace": "argocd",
 			"labels": map[string]string{
 				"platform.infraforge.io/environment": claim.Spec.Environment,
@@ -221,8 +659,7 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplicationSet(claim *platfor
 						"revision": r.Branch,
 						"files": []map[string]interface{}{
 							{
-								"path": fmt.Sprintf("environments/%s/%s/applications/*/config.json",
-									claim.Spec.ClusterType, claim.Spec.Environment),
+								"path": r.applicationsRoot(claim) + "/*/config.json",
 							},
 						},
 					},
@@ -230,24 +667,30 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplicationSet(claim *platfor
 			},
 			"template": map[string]interface{}{
 				"metadata": map[string]interface{}{
-					"name": "{{name}}-" + claim.Spec.Environment,
+					"name": "{{name}}-" + namespace,
 					"labels": map[string]string{
-						"platform.infraforge.io/app": "{{name}}",
-						"platform.infraforge.io/env": claim.Spec.Environment,
+						appLabel:       "{{name}}",
+						envLabel:       claim.Spec.Environment,
+						namespaceLabel: namespace,
+						syncWaveLabel:  "{{syncWave}}",
+					},
+					"annotations": map[string]string{
+						syncWaveAnnotation: "{{syncWave}}",
 					},
 				},
 				"spec": map[string]interface{}{
 					"project": "default",
 					"sources": []map[string]interface{}{
 						{
-							// Source 1: Helm chart from charts repository
-							"repoURL":        fmt.Sprintf("%s/%s/charts", claim.Spec.GiteaURL, claim.Spec.Organization),
-							"path":           "{{chart}}",
-							"targetRevision": "main",
+							// Source 1: Helm chart from a Git path, Helm repository or OCI registry;
+							// config.json leaves path or chart empty depending on the source
+							"repoURL":        "{{chartRepoURL}}",
+							"path":           "{{chartPath}}",
+							"chart":          "{{chartName}}",
+							"targetRevision": "{{chartRevision}}",
 							"helm": map[string]interface{}{
 								"valueFiles": []string{
-									fmt.Sprintf("$values/environments/%s/%s/applications/{{name}}/values.yaml",
-										claim.Spec.ClusterType, claim.Spec.Environment),
+									valueFile(r.Encryptor, "$values/"+r.applicationsRoot(claim)+"/{{name}}/values.yaml"),
 								},
 							},
 						},
@@ -260,7 +703,7 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplicationSet(claim *platfor
 					},
 					"destination": map[string]interface{}{
 						"server":    "https://kubernetes.default.svc",
-						"namespace": claim.Spec.Environment,
+						"namespace": namespace,
 					},
 					"syncPolicy": map[string]interface{}{
 						"automated": map[string]interface{}{
@@ -274,6 +717,16 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplicationSet(claim *platfor
 		},
 	}
 
+	// Each step waits for the applications of the previous waves to become Healthy
+	if steps := rollingSyncSteps(waves); steps != nil {
+		appSet["spec"].(map[string]interface{})["strategy"] = map[string]interface{}{
+			"type": "RollingSync",
+			"rollingSync": map[string]interface{}{
+				"steps": steps,
+			},
+		}
+	}
+
 	data, _ := yaml.Marshal(appSet)
 	return string(data)
 }
@@ -288,24 +741,14 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplicationElements(claim *pl
 			continue
 		}
 
-		chartName := app.Chart.Name
-		if chartName == "" {
-			chartName = "microservice" // default chart name
-		}
-
-		version := app.Chart.Version
-		if version == "" {
-			version = "1.0.0" // default version
-		}
-
 		// Get values as YAML string for ArgoCD
 		valuesMap := r.buildCRDOverrides(app)
 		valuesYAML, _ := yaml.Marshal(valuesMap)
 
 		elements = append(elements, map[string]interface{}{
 			"name":    app.Name,
-			"chart":   chartName,
-			"version": version,
+			"chart":   app.Chart.Name,
+			"version": app.Chart.Version,
 			"values":  string(valuesYAML), // Send as YAML string
 		})
 	}
@@ -313,17 +756,17 @@ func (r *ApplicationClaimGitOpsReconciler) generateApplicationElements(claim *pl
 	return elements
 }
 
-// generateConfigJSON generates config.json with chart metadata and service name
-func (r *ApplicationClaimGitOpsReconciler) generateConfigJSON(claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec) string {
+// generateConfigJSON generates config.json with chart metadata and source, service name and sync wave
+func (r *ApplicationClaimGitOpsReconciler) generateConfigJSON(claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec, source chartSource, wave int) string {
 	config := map[string]interface{}{
-		"name":    app.Name,
-		"chart":   app.Chart.Name,  // Just chart name, no prefix
-		"version": app.Chart.Version,
-		"values":  r.generateValuesYAML(claim, app),
-	}
-
-	if app.Chart.Version == "" {
-		config["version"] = "1.0.0"
+		"name":          app.Name,
+		"chart":         app.Chart.Name, // Just chart name, no prefix
+		"version":       app.Chart.Version,
+		"syncWave":      strconv.Itoa(wave), // Label values must be strings
+		"chartRepoURL":  source.RepoURL,
+		"chartPath":     source.Path,
+		"chartName":     source.Chart,
+		"chartRevision": source.TargetRevision,
 	}
 
 	jsonBytes, _ := json.Marshal(config)
@@ -338,41 +781,61 @@ func (r *ApplicationClaimGitOpsReconciler) generateValuesYAML(claim *platformv1.
 }
 
 // generateValuesFromCRD generates values.yaml from CRD spec only (fallback)
+// Later layers win: the operator's environment values, the typed fields, the application's values.
+// Maps are merged key by key, lists and scalars are replaced.
 func (r *ApplicationClaimGitOpsReconciler) generateValuesFromCRD(claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec) string {
-	values := r.buildCRDOverrides(app)
+	values := normalizeValues(defaults.OrBuiltin(r.Defaults).EnvironmentValues[claim.Spec.Environment])
+	mergeDeep(values, normalizeValues(r.buildCRDOverrides(app)))
+	if app.Values.Raw != nil {
+		// Checked by validateApplication before any values are generated
+		custom, _ := applicationValues(app)
+		mergeDeep(values, custom)
+	}
 	data, _ := yaml.Marshal(values)
 	return string(data)
 }
 
+// applicationValues decodes the application's free-form Helm values
+func applicationValues(app platformv1.ApplicationSpec) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if app.Values.Raw == nil {
+		return values, nil
+	}
+	if err := yaml.Unmarshal(app.Values.Raw, &values); err != nil {
+		return nil, fmt.Errorf("application %s: values must be an object: %w", app.Name, err)
+	}
+	return values, nil
+}
+
+// normalizeValues returns a deep copy of values with every nested map as map[string]interface{}
+// so mergeDeep merges typed maps (e.g. ingress annotations) instead of replacing them
+func normalizeValues(values interface{}) map[string]interface{} {
+	normalized := map[string]interface{}{}
+	data, err := yaml.Marshal(values)
+	if err == nil {
+		_ = yaml.Unmarshal(data, &normalized)
+	}
+	return normalized
+}
+
 // buildCRDOverrides builds override values from CRD spec
 func (r *ApplicationClaimGitOpsReconciler) buildCRDOverrides(app platformv1.ApplicationSpec) map[string]interface{} {
 	overrides := make(map[string]interface{})
 
 	// Image configuration
 	if app.Image.Repository != "" {
-		imageTag := app.Image.Tag
-		if imageTag == "" {
-			imageTag = "latest"
-		}
 		overrides["image"] = map[string]interface{}{
 			"repository": app.Image.Repository,
-			"tag":        imageTag,
+			"tag":        app.Image.Tag,
 		}
 		if app.Image.PullPolicy != "" {
 			overrides["image"].(map[string]interface{})["pullPolicy"] = app.Image.PullPolicy
 		}
-
-		// Add imagePullSecrets for GHCR
-		overrides["imagePullSecrets"] = []map[string]interface{}{
-			{"name": "ghcr-pull-secret"},
-		}
 	}
 
-	// Always add imagePullSecrets for GHCR
-	overrides["imagePullSecrets"] = []map[string]interface{}{
-		{
-			"name": "ghcr-pull-secret",
-		},
+	// Operator default registry credentials plus the application's own
+	if pullSecrets := r.imagePullSecrets(app); len(pullSecrets) > 0 {
+		overrides["imagePullSecrets"] = pullSecrets
 	}
 
 	// Replica count
@@ -422,6 +885,9 @@ func (r *ApplicationClaimGitOpsReconciler) buildCRDOverrides(app platformv1.Appl
 		if len(app.Ingress.Annotations) > 0 {
 			ingress["annotations"] = app.Ingress.Annotations
 		}
+		if port := ingressPort(app); port != "" {
+			ingress["port"] = port
+		}
 		overrides["ingress"] = ingress
 	}
 
@@ -435,6 +901,9 @@ func (r *ApplicationClaimGitOpsReconciler) buildCRDOverrides(app platformv1.Appl
 			if env.Value != "" {
 				envVar["value"] = env.Value
 			}
+			if env.ValueFrom != nil {
+				envVar["valueFrom"] = buildEnvVarSource(env.ValueFrom)
+			}
 			envVars = append(envVars, envVar)
 		}
 		overrides["env"] = envVars
@@ -460,32 +929,319 @@ func (r *ApplicationClaimGitOpsReconciler) buildCRDOverrides(app platformv1.Appl
 		overrides["autoscaling"] = autoscaling
 	}
 
-	// Service configuration - fix targetPort
+	// Service and container ports
 	if len(app.Ports) > 0 {
-		servicePort := 80
-		targetPort := app.Ports[0].Port
-
-		// Special handling for user-service
-		if app.Name == "user-service" {
-			targetPort = 8081
-			// Disable health checks temporarily
-			overrides["healthCheck"] = map[string]interface{}{
-				"liveness": map[string]interface{}{
-					"enabled": false,
-				},
-				"readiness": map[string]interface{}{
-					"enabled": false,
-				},
+		overrides["service"] = map[string]interface{}{
+			"ports": buildServicePorts(app.Ports),
+		}
+	}
+
+	// Health checks
+	if healthCheck := buildHealthCheckValues(app.HealthCheck, defaultProbePort(app)); len(healthCheck) > 0 {
+		overrides["healthCheck"] = healthCheck
+	}
+
+	return overrides
+}
+
+// imagePullSecrets returns the operator default pull secrets followed by the application's, without duplicates
+func (r *ApplicationClaimGitOpsReconciler) imagePullSecrets(app platformv1.ApplicationSpec) []map[string]interface{} {
+	var pullSecrets []map[string]interface{}
+	seen := map[string]bool{}
+	for _, name := range append(append([]string{}, r.DefaultPullSecrets...), app.Image.PullSecrets...) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		pullSecrets = append(pullSecrets, map[string]interface{}{"name": name})
+	}
+	return pullSecrets
+}
+
+// validateImagePolicy enforces immutable image tags for prod claims when the policy is enabled
+func (r *ApplicationClaimGitOpsReconciler) validateImagePolicy(claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec) error {
+	if !r.RejectMutableProdTags || claim.Spec.ClusterType != "prod" {
+		return nil
+	}
+	if app.Image.Tag == "" || app.Image.Tag == "latest" {
+		return fmt.Errorf("application %s: prod claims require a pinned image tag, got %q", app.Name, app.Image.Tag)
+	}
+	return nil
+}
+
+// buildServicePorts converts the declared ports to the chart's service.ports values
+func buildServicePorts(ports []platformv1.PortSpec) []map[string]interface{} {
+	values := make([]map[string]interface{}, 0, len(ports))
+	for _, port := range ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		values = append(values, map[string]interface{}{
+			"name":       port.Name,
+			"port":       servicePort(port),
+			"targetPort": port.Port,
+			"protocol":   protocol,
+		})
+	}
+	return values
+}
+
+// servicePort returns the Service port of a declared port
+// The http port keeps listening on 80 so in-cluster URLs stay stable
+func servicePort(port platformv1.PortSpec) int32 {
+	if port.ServicePort != 0 {
+		return port.ServicePort
+	}
+	if port.Name == "http" {
+		return 80
+	}
+	return port.Port
+}
+
+// ingressPort returns the named port the ingress routes to, empty to use the chart default
+func ingressPort(app platformv1.ApplicationSpec) string {
+	if app.Ingress != nil && app.Ingress.Port != "" {
+		return app.Ingress.Port
+	}
+	if len(app.Ports) > 0 {
+		return app.Ports[0].Name
+	}
+	return ""
+}
+
+// defaultProbePort returns the named port probes target when the claim does not choose one
+// Empty keeps the chart default "http" port
+func defaultProbePort(app platformv1.ApplicationSpec) string {
+	for _, port := range app.Ports {
+		if port.Name == "http" {
+			return ""
+		}
+	}
+	if len(app.Ports) > 0 {
+		return app.Ports[0].Name
+	}
+	return ""
+}
+
+// buildHealthCheckValues converts HealthCheckSpec to the chart's healthCheck values
+// Only fields set in the claim are rendered, the chart defaults fill in the rest;
+// defaultPort, when set, is the port of every enabled probe that does not choose one
+func buildHealthCheckValues(hc platformv1.HealthCheckSpec, defaultPort string) map[string]interface{} {
+	// Shared settings apply to liveness and readiness, not to the startup probe
+	shared := platformv1.ProbeSpec{
+		Path:                hc.Path,
+		Port:                hc.Port,
+		PortName:            hc.PortName,
+		InitialDelaySeconds: hc.InitialDelaySeconds,
+		PeriodSeconds:       hc.PeriodSeconds,
+	}
+
+	healthCheck := map[string]interface{}{}
+	for key, probe := range map[string]*platformv1.ProbeSpec{
+		"liveness":  hc.Liveness,
+		"readiness": hc.Readiness,
+		"startup":   hc.Startup,
+	} {
+		var values map[string]interface{}
+		if key == "startup" {
+			values = buildProbeValues(probe, platformv1.ProbeSpec{})
+		} else {
+			values = buildProbeValues(probe, shared)
+		}
+		if defaultPort != "" && values["enabled"] != false && values["port"] == nil {
+			// Only the port is set so a chart-disabled probe stays disabled
+			if values == nil {
+				values = map[string]interface{}{}
 			}
+			values["port"] = defaultPort
+		}
+		if len(values) > 0 {
+			healthCheck[key] = values
 		}
+	}
+	return healthCheck
+}
 
-		overrides["service"] = map[string]interface{}{
-			"port":       servicePort,
-			"targetPort": targetPort,
+// buildProbeValues renders a probe, falling back to the shared settings for unset fields
+func buildProbeValues(probe *platformv1.ProbeSpec, shared platformv1.ProbeSpec) map[string]interface{} {
+	p := shared
+	if probe != nil {
+		if probe.Enabled != nil && !*probe.Enabled {
+			return map[string]interface{}{"enabled": false}
+		}
+		p.Type = probe.Type
+		p.Command = probe.Command
+		if probe.Path != "" {
+			p.Path = probe.Path
+		}
+		if probe.Port != 0 || probe.PortName != "" {
+			p.Port = probe.Port
+			p.PortName = probe.PortName
+		}
+		if probe.InitialDelaySeconds != 0 {
+			p.InitialDelaySeconds = probe.InitialDelaySeconds
+		}
+		if probe.PeriodSeconds != 0 {
+			p.PeriodSeconds = probe.PeriodSeconds
 		}
+		p.TimeoutSeconds = probe.TimeoutSeconds
+		p.FailureThreshold = probe.FailureThreshold
+		p.SuccessThreshold = probe.SuccessThreshold
 	}
 
-	return overrides
+	values := map[string]interface{}{}
+	if p.PortName != "" {
+		values["port"] = p.PortName
+	} else if p.Port != 0 {
+		values["port"] = p.Port
+	}
+	for key, value := range map[string]int32{
+		"initialDelaySeconds": p.InitialDelaySeconds,
+		"periodSeconds":       p.PeriodSeconds,
+		"timeoutSeconds":      p.TimeoutSeconds,
+		"failureThreshold":    p.FailureThreshold,
+		"successThreshold":    p.SuccessThreshold,
+	} {
+		if value != 0 {
+			values[key] = value
+		}
+	}
+	if p.Type != "" {
+		values["type"] = p.Type
+	}
+	if p.Path != "" && p.Type != "tcp" && p.Type != "exec" {
+		values["path"] = p.Path
+	}
+	if len(p.Command) > 0 {
+		values["command"] = p.Command
+	}
+	if len(values) == 0 && probe == nil {
+		return nil
+	}
+	values["enabled"] = true
+	return values
+}
+
+// buildEnvVarSource converts an EnvVarSource to the valueFrom block of a container env entry
+func buildEnvVarSource(source *platformv1.EnvVarSource) map[string]interface{} {
+	valueFrom := map[string]interface{}{}
+	if source.SecretKeyRef != nil {
+		valueFrom["secretKeyRef"] = map[string]interface{}{
+			"name": source.SecretKeyRef.Name,
+			"key":  source.SecretKeyRef.Key,
+		}
+	}
+	if source.ConfigMapKeyRef != nil {
+		valueFrom["configMapKeyRef"] = map[string]interface{}{
+			"name": source.ConfigMapKeyRef.Name,
+			"key":  source.ConfigMapKeyRef.Key,
+		}
+	}
+	return valueFrom
+}
+
+// validateApplication checks the parts of an application spec the CRD schema cannot express
+func validateApplication(app platformv1.ApplicationSpec) error {
+	if err := validateEnv(app); err != nil {
+		return err
+	}
+	if _, err := applicationValues(app); err != nil {
+		return err
+	}
+	if err := validatePorts(app); err != nil {
+		return err
+	}
+	return validateHealthCheck(app)
+}
+
+// validatePorts checks that port names are unique and every named port reference is declared
+func validatePorts(app platformv1.ApplicationSpec) error {
+	names := make(map[string]bool, len(app.Ports))
+	for _, port := range app.Ports {
+		if names[port.Name] {
+			return fmt.Errorf("application %s: duplicate port name %s", app.Name, port.Name)
+		}
+		names[port.Name] = true
+	}
+
+	refs := map[string]string{}
+	if app.Ingress != nil {
+		refs["ingress"] = app.Ingress.Port
+	}
+	refs["healthCheck"] = app.HealthCheck.PortName
+	for name, probe := range map[string]*platformv1.ProbeSpec{
+		"liveness probe":  app.HealthCheck.Liveness,
+		"readiness probe": app.HealthCheck.Readiness,
+		"startup probe":   app.HealthCheck.Startup,
+	} {
+		if probe != nil {
+			refs[name] = probe.PortName
+		}
+	}
+	for field, portName := range refs {
+		if portName == "" {
+			continue
+		}
+		// Without declared ports the chart only exposes the default http port
+		if !names[portName] && !(len(app.Ports) == 0 && portName == "http") {
+			return fmt.Errorf("application %s: %s references undeclared port %s", app.Name, field, portName)
+		}
+	}
+	return nil
+}
+
+// validateHealthCheck checks that every probe carries the settings its type needs
+func validateHealthCheck(app platformv1.ApplicationSpec) error {
+	for name, probe := range map[string]*platformv1.ProbeSpec{
+		"liveness":  app.HealthCheck.Liveness,
+		"readiness": app.HealthCheck.Readiness,
+		"startup":   app.HealthCheck.Startup,
+	} {
+		if probe == nil || (probe.Enabled != nil && !*probe.Enabled) {
+			continue
+		}
+		switch probe.Type {
+		case "", "http", "tcp":
+			if len(probe.Command) > 0 {
+				return fmt.Errorf("application %s: %s probe sets command but is not an exec probe", app.Name, name)
+			}
+		case "exec":
+			if len(probe.Command) == 0 {
+				return fmt.Errorf("application %s: %s exec probe requires a command", app.Name, name)
+			}
+		default:
+			return fmt.Errorf("application %s: %s probe has unknown type %q", app.Name, name, probe.Type)
+		}
+	}
+	return nil
+}
+
+// validateEnv checks that every environment variable has either a value or exactly one known source
+func validateEnv(app platformv1.ApplicationSpec) error {
+	for _, env := range app.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if env.Value != "" {
+			return fmt.Errorf("application %s: env %s sets both value and valueFrom", app.Name, env.Name)
+		}
+		sources := 0
+		if env.ValueFrom.SecretKeyRef != nil {
+			sources++
+		}
+		if env.ValueFrom.ConfigMapKeyRef != nil {
+			sources++
+		}
+		switch sources {
+		case 0:
+			// Unknown source kinds (fieldRef, resourceFieldRef, ...) are pruned by the API server
+			return fmt.Errorf("application %s: env %s has an unsupported valueFrom source, only secretKeyRef and configMapKeyRef are allowed", app.Name, env.Name)
+		case 2:
+			return fmt.Errorf("application %s: env %s sets more than one valueFrom source", app.Name, env.Name)
+		}
+	}
+	return nil
 }
 
 // createApplication creates or updates an Application in ArgoCD namespace
@@ -588,5 +1344,8 @@ func (r *ApplicationClaimGitOpsReconciler) createApplicationSet(ctx context.Cont
 func (r *ApplicationClaimGitOpsReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&platformv1.ApplicationClaim{}).
+		Watches(newArgoApplication(),
+			handler.EnqueueRequestsFromMapFunc(r.claimsForApplication),
+			builder.WithPredicates(argoApplicationStatusChanged())).
 		Complete(r)
 }
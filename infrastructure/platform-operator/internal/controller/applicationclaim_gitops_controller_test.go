@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+func TestBuildCRDOverridesEnvValueFrom(t *testing.T) {
+	r := &ApplicationClaimGitOpsReconciler{}
+	app := platformv1.ApplicationSpec{
+		Name: "api",
+		Env: []platformv1.EnvVar{
+			{Name: "LOG_LEVEL", Value: "debug"},
+			{Name: "DB_PASSWORD", ValueFrom: &platformv1.EnvVarSource{
+				SecretKeyRef: &platformv1.SecretKeySelector{Name: "db-credentials", Key: "password"},
+			}},
+			{Name: "FEATURE_FLAGS", ValueFrom: &platformv1.EnvVarSource{
+				ConfigMapKeyRef: &platformv1.ConfigMapKeySelector{Name: "app-config", Key: "flags"},
+			}},
+		},
+	}
+
+	env := r.buildCRDOverrides(app)["env"].([]map[string]interface{})
+	expected := []map[string]interface{}{
+		{"name": "LOG_LEVEL", "value": "debug"},
+		{"name": "DB_PASSWORD", "valueFrom": map[string]interface{}{
+			"secretKeyRef": map[string]interface{}{"name": "db-credentials", "key": "password"},
+		}},
+		{"name": "FEATURE_FLAGS", "valueFrom": map[string]interface{}{
+			"configMapKeyRef": map[string]interface{}{"name": "app-config", "key": "flags"},
+		}},
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("unexpected env overrides:\n got: %v\nwant: %v", env, expected)
+	}
+	if err := validateEnv(app); err != nil {
+		t.Errorf("expected env to be valid, got %v", err)
+	}
+}
+
+func TestValidateEnvRejectsUnknownSource(t *testing.T) {
+	secretRef := &platformv1.SecretKeySelector{Name: "db", Key: "password"}
+	tests := map[string]platformv1.EnvVar{
+		"unknown source": {Name: "POD_IP", ValueFrom: &platformv1.EnvVarSource{}},
+		"two sources": {Name: "X", ValueFrom: &platformv1.EnvVarSource{
+			SecretKeyRef:    secretRef,
+			ConfigMapKeyRef: &platformv1.ConfigMapKeySelector{Name: "cfg", Key: "x"},
+		}},
+		"value and source": {Name: "X", Value: "y", ValueFrom: &platformv1.EnvVarSource{SecretKeyRef: secretRef}},
+	}
+	for name, env := range tests {
+		err := validateEnv(platformv1.ApplicationSpec{Name: "api", Env: []platformv1.EnvVar{env}})
+		if err == nil || !strings.Contains(err.Error(), env.Name) {
+			t.Errorf("%s: expected error mentioning %s, got %v", name, env.Name, err)
+		}
+	}
+}
+
+func TestBuildHealthCheckValues(t *testing.T) {
+	disabled := false
+	hc := platformv1.HealthCheckSpec{
+		Path:          "/healthz",
+		Port:          9090,
+		PeriodSeconds: 15,
+		Readiness:     &platformv1.ProbeSpec{Type: "tcp", TimeoutSeconds: 2},
+		Startup:       &platformv1.ProbeSpec{Type: "exec", Command: []string{"cat", "/tmp/started"}, FailureThreshold: 30},
+	}
+
+	values := buildHealthCheckValues(hc, "")
+	expected := map[string]interface{}{
+		"liveness": map[string]interface{}{
+			"enabled": true, "path": "/healthz", "port": int32(9090), "periodSeconds": int32(15),
+		},
+		"readiness": map[string]interface{}{
+			"enabled": true, "type": "tcp", "port": int32(9090), "periodSeconds": int32(15), "timeoutSeconds": int32(2),
+		},
+		"startup": map[string]interface{}{
+			"enabled": true, "type": "exec", "command": []string{"cat", "/tmp/started"}, "failureThreshold": int32(30),
+		},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected health check values:\n got: %v\nwant: %v", values, expected)
+	}
+
+	// Disabled probes are rendered explicitly so they override the chart defaults
+	values = buildHealthCheckValues(platformv1.HealthCheckSpec{Readiness: &platformv1.ProbeSpec{Enabled: &disabled}}, "")
+	if !reflect.DeepEqual(values, map[string]interface{}{"readiness": map[string]interface{}{"enabled": false}}) {
+		t.Errorf("unexpected values for disabled readiness probe: %v", values)
+	}
+
+	if values := buildHealthCheckValues(platformv1.HealthCheckSpec{}, ""); len(values) != 0 {
+		t.Errorf("expected no values without health check settings, got %v", values)
+	}
+}
+
+func TestValidateHealthCheck(t *testing.T) {
+	valid := platformv1.ApplicationSpec{Name: "api", HealthCheck: platformv1.HealthCheckSpec{
+		Liveness: &platformv1.ProbeSpec{Type: "exec", Command: []string{"true"}},
+	}}
+	if err := validateHealthCheck(valid); err != nil {
+		t.Errorf("expected valid health check, got %v", err)
+	}
+
+	invalid := platformv1.ApplicationSpec{Name: "api", HealthCheck: platformv1.HealthCheckSpec{
+		Startup: &platformv1.ProbeSpec{Type: "exec"},
+	}}
+	if err := validateHealthCheck(invalid); err == nil || !strings.Contains(err.Error(), "startup") {
+		t.Errorf("expected startup exec probe without command to be rejected, got %v", err)
+	}
+}
+
+func TestBuildCRDOverridesMultiPort(t *testing.T) {
+	r := &ApplicationClaimGitOpsReconciler{}
+	app := platformv1.ApplicationSpec{
+		Name: "orders",
+		Ports: []platformv1.PortSpec{
+			{Name: "grpc", Port: 9090},
+			{Name: "metrics", Port: 9100, ServicePort: 9100},
+			{Name: "web", Port: 8080, ServicePort: 80},
+		},
+		Ingress:     &platformv1.IngressSpec{Enabled: true, Host: "orders.local", Port: "web"},
+		HealthCheck: platformv1.HealthCheckSpec{Readiness: &platformv1.ProbeSpec{Type: "tcp", PortName: "grpc"}},
+	}
+	if err := validateApplication(app); err != nil {
+		t.Fatalf("expected application to be valid, got %v", err)
+	}
+
+	overrides := r.buildCRDOverrides(app)
+	ports := overrides["service"].(map[string]interface{})["ports"]
+	expectedPorts := []map[string]interface{}{
+		{"name": "grpc", "port": int32(9090), "targetPort": int32(9090), "protocol": "TCP"},
+		{"name": "metrics", "port": int32(9100), "targetPort": int32(9100), "protocol": "TCP"},
+		{"name": "web", "port": int32(80), "targetPort": int32(8080), "protocol": "TCP"},
+	}
+	if !reflect.DeepEqual(ports, expectedPorts) {
+		t.Errorf("unexpected service ports:\n got: %v\nwant: %v", ports, expectedPorts)
+	}
+	if port := overrides["ingress"].(map[string]interface{})["port"]; port != "web" {
+		t.Errorf("expected ingress to target web, got %v", port)
+	}
+
+	// Without an http port, probes fall back to the first declared port
+	healthCheck := overrides["healthCheck"].(map[string]interface{})
+	expectedHealthCheck := map[string]interface{}{
+		"liveness":  map[string]interface{}{"port": "grpc"},
+		"readiness": map[string]interface{}{"enabled": true, "type": "tcp", "port": "grpc"},
+		"startup":   map[string]interface{}{"port": "grpc"},
+	}
+	if !reflect.DeepEqual(healthCheck, expectedHealthCheck) {
+		t.Errorf("unexpected health check values:\n got: %v\nwant: %v", healthCheck, expectedHealthCheck)
+	}
+
+	app.Ingress.Port = "admin"
+	if err := validateApplication(app); err == nil || !strings.Contains(err.Error(), "admin") {
+		t.Errorf("expected undeclared ingress port to be rejected, got %v", err)
+	}
+}
+
+func TestImagePullSecretsAndTagPolicy(t *testing.T) {
+	r := &ApplicationClaimGitOpsReconciler{
+		DefaultPullSecrets:    []string{"ghcr-pull-secret", "harbor-pull-secret"},
+		RejectMutableProdTags: true,
+	}
+	app := platformv1.ApplicationSpec{
+		Name:  "api",
+		Image: platformv1.ImageSpec{Repository: "quay.io/acme/api", PullSecrets: []string{"quay-pull-secret", "ghcr-pull-secret"}},
+	}
+
+	pullSecrets := r.buildCRDOverrides(app)["imagePullSecrets"]
+	expected := []map[string]interface{}{
+		{"name": "ghcr-pull-secret"},
+		{"name": "harbor-pull-secret"},
+		{"name": "quay-pull-secret"},
+	}
+	if !reflect.DeepEqual(pullSecrets, expected) {
+		t.Errorf("unexpected pull secrets:\n got: %v\nwant: %v", pullSecrets, expected)
+	}
+
+	prod := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{ClusterType: "prod"}}
+	nonprod := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{ClusterType: "nonprod"}}
+	for _, tag := range []string{"", "latest"} {
+		app.Image.Tag = tag
+		if err := r.validateImagePolicy(prod, app); err == nil {
+			t.Errorf("expected tag %q to be rejected for prod", tag)
+		}
+		if err := r.validateImagePolicy(nonprod, app); err != nil {
+			t.Errorf("expected tag %q to be allowed for nonprod, got %v", tag, err)
+		}
+	}
+	app.Image.Tag = "v1.4.2"
+	if err := r.validateImagePolicy(prod, app); err != nil {
+		t.Errorf("expected pinned tag to be allowed for prod, got %v", err)
+	}
+}
+
+func TestGenerateValuesPrecedence(t *testing.T) {
+	d := defaults.New()
+	d.EnvironmentValues["prod"] = map[string]interface{}{
+		"replicaCount":   2,
+		"podAnnotations": map[string]interface{}{"team": "platform", "tier": "shared"},
+		"ingress":        map[string]interface{}{"className": "nginx"},
+	}
+	r := &ApplicationClaimGitOpsReconciler{Defaults: d}
+	claim := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{Environment: "prod"}}
+	app := platformv1.ApplicationSpec{
+		Name:     "api",
+		Replicas: 3,
+		Ingress:  &platformv1.IngressSpec{Enabled: true, Host: "api.example.com", Annotations: map[string]string{"a": "1"}},
+		Values:   runtime.RawExtension{Raw: []byte(`{"podAnnotations":{"tier":"api"},"ingress":{"annotations":{"b":"2"}},"securityContext":{"runAsNonRoot":true}}`)},
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(r.generateValuesFromCRD(claim, app)), &values); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"replicaCount":    3,
+		"podAnnotations":  map[string]interface{}{"team": "platform", "tier": "api"},
+		"securityContext": map[string]interface{}{"runAsNonRoot": true},
+		"ingress": map[string]interface{}{
+			"className":   "nginx",
+			"enabled":     true,
+			"host":        "api.example.com",
+			"annotations": map[string]interface{}{"a": "1", "b": "2"},
+		},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected merged values:\n got: %v\nwant: %v", values, expected)
+	}
+	if _, ok := d.EnvironmentValues["prod"]["securityContext"]; ok {
+		t.Errorf("expected the environment values not to be modified")
+	}
+
+	app.Values = runtime.RawExtension{Raw: []byte(`["not", "an", "object"]`)}
+	if err := validateApplication(app); err == nil || !strings.Contains(err.Error(), "values") {
+		t.Errorf("expected non-object values to be rejected, got %v", err)
+	}
+}
+
+func TestReconcileDeleteRemovesOwnedFiles(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	newDeletedClaim := func(name, organization, gitURL string) *platformv1.ApplicationClaim {
+		now := metav1.Now()
+		return &platformv1.ApplicationClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", Finalizers: []string{applicationClaimFinalizer}, DeletionTimestamp: &now},
+			Spec: platformv1.ApplicationClaimSpec{
+				Environment:  "dev",
+				ClusterType:  "nonprod",
+				Organization: organization,
+				GitProvider:  gitprovider.ProviderGit,
+				GiteaURL:     gitURL,
+				Owner:        platformv1.OwnerSpec{Team: "payments"},
+			},
+		}
+	}
+
+	r := &ApplicationClaimGitOpsReconciler{VoltranRepo: "voltran", Branch: "main"}
+	claim := newDeletedClaim("shop", "acme", "")
+	kept := "environments/nonprod/other/applications/api/values.yaml"
+	base := newVoltranRepo(t, map[string]string{
+		r.appSetPath(claim):                                 "kind: ApplicationSet\n",
+		r.applicationsRoot(claim) + "/.gitkeep":             "",
+		r.applicationDir(claim, "api") + "/values.yaml":     "replicaCount: 2\n",
+		r.componentDir(claim, "orders-db") + "/values.yaml": "type: postgresql\n",
+		kept: "replicaCount: 1\n",
+	})
+	claim.Spec.GiteaURL = base
+	// The organization of the second claim does not exist, so its files cannot be removed
+	unreachable := newDeletedClaim("broken", "gone", base)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(claim, unreachable).Build()
+	r.Client, r.Scheme = c, scheme
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "shop"}}); err != nil {
+		t.Fatal(err)
+	}
+	provider := gitprovider.NewLocal(base)
+	files, err := provider.CloneAndExtractFiles(ctx, provider.ConstructCloneURL("acme", "voltran"), "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{r.applicationsRoot(claim) + "/.gitkeep": "", kept: "replicaCount: 1\n"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expected only the kept files after deletion, got %v", files)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "shop"}, &platformv1.ApplicationClaim{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the claim to be released once its files are removed, got %v", err)
+	}
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "broken"}})
+	if err != nil || result.RequeueAfter == 0 {
+		t.Errorf("expected a failed removal to be retried, got %+v, %v", result, err)
+	}
+	remaining := &platformv1.ApplicationClaim{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "broken"}, remaining); err != nil || len(remaining.Finalizers) != 1 {
+		t.Errorf("expected the finalizer to be kept until the files are removed, got %v, %v", remaining.Finalizers, err)
+	}
+}
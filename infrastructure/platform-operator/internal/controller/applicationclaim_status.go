@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+const (
+	// argoCDNamespace namespace where ArgoCD Applications are created by the ApplicationSets
+	argoCDNamespace = "argocd"
+
+	// appLabel and envLabel are set on every Application generated from a claim's ApplicationSet
+	appLabel = "platform.infraforge.io/app"
+	envLabel = "platform.infraforge.io/env"
+)
+
+// argoApplicationGVK is the GroupVersionKind of ArgoCD Applications
+var argoApplicationGVK = schema.GroupVersionKind{
+	Group:   "argoproj.io",
+	Version: "v1alpha1",
+	Kind:    "Application",
+}
+
+// newArgoApplication returns an empty unstructured ArgoCD Application
+func newArgoApplication() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(argoApplicationGVK)
+	return obj
+}
+
+// collectApplicationStatuses builds per-application status entries from the live ArgoCD
+// Applications of the claim and reports whether every enabled application is Synced and Healthy;
+// held applications wait for their dependencies with the reason as message
+func (r *ApplicationClaimGitOpsReconciler) collectApplicationStatuses(ctx context.Context, claim *platformv1.ApplicationClaim, held map[string]string) ([]platformv1.ApplicationStatus, bool, error) {
+	argoApps := &unstructured.UnstructuredList{}
+	argoApps.SetGroupVersionKind(argoApplicationGVK.GroupVersion().WithKind("ApplicationList"))
+	if err := r.List(ctx, argoApps,
+		client.InNamespace(argoCDNamespace),
+		client.MatchingLabels{envLabel: claim.Spec.Environment}); err != nil {
+		return nil, false, fmt.Errorf("failed to list ArgoCD Applications: %w", err)
+	}
+
+	byName := make(map[string]*unstructured.Unstructured, len(argoApps.Items))
+	for i := range argoApps.Items {
+		labels := argoApps.Items[i].GetLabels()
+		if name := labels[appLabel]; name != "" && r.generatedFor(claim, labels) {
+			byName[name] = &argoApps.Items[i]
+		}
+	}
+
+	statuses := []platformv1.ApplicationStatus{}
+	allReady := true
+	for _, app := range claim.Spec.Applications {
+		if !app.Enabled {
+			continue
+		}
+
+		status := platformv1.ApplicationStatus{
+			Name:         app.Name,
+			Version:      app.Image.Tag,
+			SyncStatus:   "Unknown",
+			HealthStatus: "Missing",
+			Message:      "ArgoCD Application not created yet",
+		}
+
+		if reason, ok := held[app.Name]; ok {
+			status.Phase = applicationPhaseWaitingForDependencies
+			status.Message = reason
+		} else if argoApp, ok := byName[app.Name]; ok {
+			r.fillApplicationStatus(ctx, &status, argoApp, app)
+		}
+
+		status.Ready = status.SyncStatus == "Synced" && status.HealthStatus == "Healthy"
+		if status.Phase == "" && status.Ready {
+			status.Phase = "Ready"
+		} else if status.Phase == "" {
+			status.Phase = "Provisioning"
+		}
+		if !status.Ready {
+			allReady = false
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, allReady, nil
+}
+
+// fillApplicationStatus copies sync, health, image and workload information of an ArgoCD Application
+func (r *ApplicationClaimGitOpsReconciler) fillApplicationStatus(ctx context.Context, status *platformv1.ApplicationStatus, argoApp *unstructured.Unstructured, app platformv1.ApplicationSpec) {
+	logger := log.FromContext(ctx)
+
+	status.SyncStatus, status.HealthStatus = argoSyncHealth(argoApp)
+	status.Message, _, _ = unstructured.NestedString(argoApp.Object, "status", "health", "message")
+
+	// Multi-source Applications report one revision per source
+	if revision, _, _ := unstructured.NestedString(argoApp.Object, "status", "sync", "revision"); revision != "" {
+		status.Revision = revision
+	} else if revisions, _, _ := unstructured.NestedStringSlice(argoApp.Object, "status", "sync", "revisions"); len(revisions) > 0 {
+		status.Revision = strings.Join(revisions, ",")
+	}
+
+	// Prefer the image tag actually running over the requested one
+	images, _, _ := unstructured.NestedStringSlice(argoApp.Object, "status", "summary", "images")
+	for _, image := range images {
+		if app.Image.Repository != "" && strings.HasPrefix(image, app.Image.Repository+":") {
+			status.Version = strings.TrimPrefix(image, app.Image.Repository+":")
+			break
+		}
+	}
+
+	endpoints, _, _ := unstructured.NestedStringSlice(argoApp.Object, "status", "summary", "externalURLs")
+
+	resources, _, _ := unstructured.NestedSlice(argoApp.Object, "status", "resources")
+	for _, res := range resources {
+		resource, ok := res.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(resource, "kind")
+		name, _, _ := unstructured.NestedString(resource, "name")
+		namespace, _, _ := unstructured.NestedString(resource, "namespace")
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+
+		switch kind {
+		case "Deployment":
+			deployment := &appsv1.Deployment{}
+			if err := r.Get(ctx, key, deployment); err != nil {
+				if !errors.IsNotFound(err) {
+					logger.Error(err, "failed to get Deployment", "deployment", key)
+				}
+				continue
+			}
+			if deployment.Spec.Replicas != nil {
+				status.Replicas += *deployment.Spec.Replicas
+			}
+			status.AvailableReplicas += deployment.Status.AvailableReplicas
+		case "Service":
+			service := &corev1.Service{}
+			if err := r.Get(ctx, key, service); err != nil {
+				if !errors.IsNotFound(err) {
+					logger.Error(err, "failed to get Service", "service", key)
+				}
+				continue
+			}
+			for _, port := range service.Spec.Ports {
+				endpoints = append(endpoints, fmt.Sprintf("%s.%s.svc.cluster.local:%d", service.Name, service.Namespace, port.Port))
+			}
+		}
+	}
+	status.Endpoints = endpoints
+}
+
+// argoSyncHealth returns the sync and health status of an ArgoCD Application, Unknown until reported
+func argoSyncHealth(argoApp *unstructured.Unstructured) (string, string) {
+	syncStatus, _, _ := unstructured.NestedString(argoApp.Object, "status", "sync", "status")
+	healthStatus, _, _ := unstructured.NestedString(argoApp.Object, "status", "health", "status")
+	if syncStatus == "" {
+		syncStatus = "Unknown"
+	}
+	if healthStatus == "" {
+		healthStatus = "Unknown"
+	}
+	return syncStatus, healthStatus
+}
+
+// claimArgoStates lists the ArgoCD state of every application and component of a claim
+func claimArgoStates(apps []platformv1.ApplicationStatus, components []platformv1.ComponentStatus) []argoState {
+	states := make([]argoState, 0, len(apps)+len(components))
+	for _, app := range apps {
+		states = append(states, argoState{Name: app.Name, SyncStatus: app.SyncStatus, HealthStatus: app.HealthStatus})
+	}
+	for _, comp := range components {
+		states = append(states, argoState{Name: comp.Name, SyncStatus: comp.SyncStatus, HealthStatus: comp.HealthStatus})
+	}
+	return states
+}
+
+// claimsForApplication maps an ArgoCD Application or component event to the ApplicationClaims that generated it
+func (r *ApplicationClaimGitOpsReconciler) claimsForApplication(ctx context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	appName, componentName, env := labels[appLabel], labels[componentLabel], labels[envLabel]
+	if (appName == "" && componentName == "") || env == "" {
+		return nil
+	}
+
+	claims := &platformv1.ApplicationClaimList{}
+	if err := r.List(ctx, claims); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ApplicationClaims for Application", "application", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, claim := range claims.Items {
+		if claim.Spec.Environment != env || !r.generatedFor(&claim, labels) {
+			continue
+		}
+		if claimGenerates(&claim, appName, componentName) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// generatedFor reports whether an Application with the given labels deploys to the claim's namespace
+// Applications generated before the namespace label was introduced match on environment only
+func (r *ApplicationClaimGitOpsReconciler) generatedFor(claim *platformv1.ApplicationClaim, labels map[string]string) bool {
+	namespace, ok := labels[namespaceLabel]
+	return !ok || namespace == r.applicationNamespace(claim)
+}
+
+// claimGenerates reports whether the claim declares the named application or component
+func claimGenerates(claim *platformv1.ApplicationClaim, appName, componentName string) bool {
+	for _, app := range claim.Spec.Applications {
+		if appName != "" && app.Name == appName {
+			return true
+		}
+	}
+	for _, comp := range claim.Spec.Components {
+		if componentName != "" && comp.Name == componentName {
+			return true
+		}
+	}
+	return false
+}
+
+// argoApplicationStatusChanged only passes Application updates that change sync or health state
+func argoApplicationStatusChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, okOld := e.ObjectOld.(*unstructured.Unstructured)
+			newObj, okNew := e.ObjectNew.(*unstructured.Unstructured)
+			if !okOld || !okNew {
+				return true
+			}
+			for _, field := range [][]string{
+				{"status", "sync", "status"},
+				{"status", "health", "status"},
+			} {
+				oldVal, _, _ := unstructured.NestedString(oldObj.Object, field...)
+				newVal, _, _ := unstructured.NestedString(newObj.Object, field...)
+				if oldVal != newVal {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+func newArgoApplicationFixture(name, app, env, syncStatus, health string, resources ...interface{}) *unstructured.Unstructured {
+	obj := newArgoApplication()
+	obj.SetName(name)
+	obj.SetNamespace(argoCDNamespace)
+	obj.SetLabels(map[string]string{appLabel: app, envLabel: env})
+	obj.Object["status"] = map[string]interface{}{
+		"sync":      map[string]interface{}{"status": syncStatus, "revision": "abc123"},
+		"health":    map[string]interface{}{"status": health},
+		"summary":   map[string]interface{}{"images": []interface{}{"ghcr.io/infraforge/api:v1.2.3"}},
+		"resources": resources,
+	}
+	return obj
+}
+
+func TestCollectApplicationStatuses(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-dev", Namespace: "dev"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+	}
+
+	objs := []client.Object{
+		deployment,
+		newArgoApplicationFixture("api-dev", "api", "dev", "Synced", "Healthy",
+			map[string]interface{}{"kind": "Deployment", "name": "api-dev", "namespace": "dev"}),
+		newArgoApplicationFixture("web-dev", "web", "dev", "OutOfSync", "Progressing"),
+		newArgoApplicationFixture("api-qa", "api", "qa", "Synced", "Healthy"),
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r := &ApplicationClaimGitOpsReconciler{Client: c, Scheme: scheme}
+
+	claim := &platformv1.ApplicationClaim{
+		Spec: platformv1.ApplicationClaimSpec{
+			Environment: "dev",
+			Applications: []platformv1.ApplicationSpec{
+				{Name: "api", Enabled: true, Image: platformv1.ImageSpec{Repository: "ghcr.io/infraforge/api", Tag: "v1.2.2"}},
+				{Name: "web", Enabled: true},
+				{Name: "worker", Enabled: true},
+				{Name: "legacy", Enabled: false},
+			},
+		},
+	}
+
+	statuses, allReady, err := r.collectApplicationStatuses(context.Background(), claim, nil)
+	if err != nil {
+		t.Fatalf("collectApplicationStatuses failed: %v", err)
+	}
+	if allReady {
+		t.Errorf("expected claim not to be ready while web is progressing")
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses for enabled apps, got %d", len(statuses))
+	}
+
+	api := statuses[0]
+	if !api.Ready || api.Version != "v1.2.3" || api.Replicas != 2 || api.AvailableReplicas != 1 || api.Revision != "abc123" {
+		t.Errorf("unexpected api status: %+v", api)
+	}
+	if web := statuses[1]; web.Ready || web.SyncStatus != "OutOfSync" || web.HealthStatus != "Progressing" {
+		t.Errorf("unexpected web status: %+v", web)
+	}
+	if worker := statuses[2]; worker.Ready || worker.HealthStatus != "Missing" {
+		t.Errorf("unexpected worker status: %+v", worker)
+	}
+
+	// Once every enabled application is Synced and Healthy the claim is ready
+	claim.Spec.Applications = claim.Spec.Applications[:1]
+	if _, allReady, _ = r.collectApplicationStatuses(context.Background(), claim, nil); !allReady {
+		t.Errorf("expected claim to be ready when all enabled apps are Synced and Healthy")
+	}
+}
@@ -12,12 +12,15 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	platformv1 "github.com/infraforge/platform-operator/api/v1"
-	"github.com/infraforge/platform-operator/pkg/gitea"
+	"github.com/infraforge/platform-operator/internal/defaults"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+	"github.com/infraforge/platform-operator/pkg/sops"
 )
 
 // BootstrapReconciler reconciles a BootstrapClaim object
@@ -31,11 +34,23 @@ type BootstrapReconciler struct {
 
 	// ChartsPath embedded charts directory path (contains both microservice and platform templates)
 	ChartsPath string
+
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+	// Encryptor encrypts the sensitive values of generated files with sops, nil to commit them in plain text
+	Encryptor *sops.Encryptor
+
+	// Recorder emits Events on claims, none when nil
+	Recorder record.EventRecorder
 }
 
+// bootstrapSteps conditions a BootstrapClaim goes through before it is Ready
+var bootstrapSteps = []string{ConditionRendered, ConditionGitPushed, ConditionSynced}
+
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=bootstrapclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=bootstrapclaims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=bootstrapclaims/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile handles BootstrapClaim reconciliation
 func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -65,8 +80,29 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Always reconcile to handle spec changes
 	// Check if gitea resources already exist before recreating
 
-	// Create GiteaClient dynamically from claim
-	giteaClient := gitea.NewClient(claim.Spec.GiteaURL, r.GiteaUsername, r.GiteaToken)
+	// Fill unset fields the same way the defaulting webhook does
+	defaults.OrBuiltin(r.Defaults).ApplyBootstrapClaim(claim)
+
+	// Create the Git provider dynamically from claim
+	cfg := gitprovider.Config{
+		Provider: gitProviderOf(claim.Spec.GitProvider, r.Defaults),
+		URL:      claim.Spec.GiteaURL,
+		Username: r.GiteaUsername,
+		Token:    r.GiteaToken,
+	}
+	// Chart files are pushed as they are: ArgoCD reads chart templates and default values
+	// straight from Git, only the generated voltran files are encrypted
+	chartsProvider, err := gitprovider.New(cfg)
+	if err != nil {
+		r.updateStatusFailed(ctx, claim, ConditionGitPushed, ReasonPushFailed, "Invalid Git provider: "+err.Error())
+		return ctrl.Result{}, err
+	}
+	cfg.Encoder = r.Encryptor
+	provider, err := gitprovider.New(cfg)
+	if err != nil {
+		r.updateStatusFailed(ctx, claim, ConditionGitPushed, ReasonPushFailed, "Invalid Git provider: "+err.Error())
+		return ctrl.Result{}, err
+	}
 
 	// Update status to Bootstrapping
 	claim.Status.Phase = "Bootstrapping"
@@ -77,9 +113,9 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// Step 1: Create organization
 	logger.Info("Creating Gitea organization", "org", claim.Spec.Organization)
-	if err := giteaClient.CreateOrganization(ctx, claim.Spec.Organization, "Platform organization"); err != nil {
+	if err := provider.CreateOrganization(ctx, claim.Spec.Organization, "Platform organization"); err != nil {
 		logger.Error(err, "failed to create organization")
-		r.updateStatusFailed(ctx, claim, "Failed to create organization: "+err.Error())
+		r.updateStatusFailed(ctx, claim, ConditionGitPushed, ReasonPushFailed, "Failed to create organization: "+err.Error())
 		return ctrl.Result{}, err
 	}
 
@@ -88,22 +124,12 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	repoURLs := make(map[string]string)
 
 	chartsRepo := claim.Spec.Repositories.Charts
-	if chartsRepo == "" {
-		chartsRepo = "charts"
-	}
 	voltranRepo := claim.Spec.Repositories.Voltran
-	if voltranRepo == "" {
-		voltranRepo = "voltran"
-	}
-
 	branch := claim.Spec.GitOps.Branch
-	if branch == "" {
-		branch = "main"
-	}
 
 	repos := []string{chartsRepo, voltranRepo}
 	for _, repoName := range repos {
-		_, err := giteaClient.CreateRepository(ctx, claim.Spec.Organization, gitea.CreateRepoOptions{
+		_, err := provider.CreateRepository(ctx, claim.Spec.Organization, gitprovider.CreateRepoOptions{
 			Name:          repoName,
 			Description:   fmt.Sprintf("Platform %s repository", repoName),
 			Private:       false,
@@ -112,11 +138,11 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		})
 		if err != nil {
 			logger.Error(err, "failed to create repository", "repo", repoName)
-			r.updateStatusFailed(ctx, claim, fmt.Sprintf("Failed to create repository %s: %v", repoName, err))
+			r.updateStatusFailed(ctx, claim, ConditionGitPushed, ReasonPushFailed, fmt.Sprintf("Failed to create repository %s: %v", repoName, err))
 			return ctrl.Result{}, err
 		}
 		// Use internal cluster URL instead of API's external clone_url
-		cloneURL := giteaClient.ConstructCloneURL(claim.Spec.Organization, repoName)
+		cloneURL := provider.ConstructCloneURL(claim.Spec.Organization, repoName)
 		repoURLs[repoName] = cloneURL
 		logger.Info("Repository created", "name", repoName, "url", cloneURL)
 	}
@@ -136,9 +162,6 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Check if external charts repository is specified
 	if claim.Spec.ChartsRepository != nil {
 		repoType := claim.Spec.ChartsRepository.Type
-		if repoType == "" {
-			repoType = "git" // Default to git for backwards compatibility
-		}
 
 		logger.Info("Chart repository mode", "url", claim.Spec.ChartsRepository.URL, "type", repoType)
 
@@ -147,21 +170,18 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			// Charts are pulled directly from OCI registry by ArgoCD
 			logger.Info("OCI mode: Skipping chart upload to Gitea (charts live in OCI registry)")
 			chartFiles = make(map[string]string) // Empty files - we'll only create GitOps structure
-			claim.Status.ChartsUploaded = true // Mark as uploaded (skipped)
+			claim.Status.ChartsUploaded = true   // Mark as uploaded (skipped)
 		} else {
 			// Clone from Git repository
 			chartsBranch := claim.Spec.ChartsRepository.Branch
-			if chartsBranch == "" {
-				chartsBranch = "main"
-			}
 			chartsPath := claim.Spec.ChartsRepository.Path
 
 			logger.Info("Cloning charts from Git repository", "branch", chartsBranch, "path", chartsPath)
 			var err error
-			chartFiles, err = giteaClient.CloneAndExtractFiles(ctx, claim.Spec.ChartsRepository.URL, chartsBranch, chartsPath)
+			chartFiles, err = provider.CloneAndExtractFiles(ctx, claim.Spec.ChartsRepository.URL, chartsBranch, chartsPath)
 			if err != nil {
 				logger.Error(err, "failed to clone charts from Git repository")
-				r.updateStatusFailed(ctx, claim, "Failed to clone charts: "+err.Error())
+				r.updateStatusFailed(ctx, claim, ConditionRendered, ReasonBootstrapFailed, "Failed to clone charts: "+err.Error())
 				return ctrl.Result{}, err
 			}
 		}
@@ -172,15 +192,15 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		chartFiles, err = r.loadChartsFromEmbedded(r.ChartsPath)
 		if err != nil {
 			logger.Error(err, "failed to load charts")
-			r.updateStatusFailed(ctx, claim, "Failed to load charts: "+err.Error())
+			r.updateStatusFailed(ctx, claim, ConditionRendered, ReasonBootstrapFailed, "Failed to load charts: "+err.Error())
 			return ctrl.Result{}, err
 		}
 	}
 
-	if err := giteaClient.PushFiles(ctx, repoURLs[chartsRepo], branch, chartFiles,
+	if _, err := chartsProvider.PushFiles(ctx, repoURLs[chartsRepo], branch, chartFiles,
 		"Initial charts upload by operator", "Platform Operator", "operator@platform.local"); err != nil {
 		logger.Error(err, "failed to push charts")
-		r.updateStatusFailed(ctx, claim, "Failed to push charts: "+err.Error())
+		r.updateStatusFailed(ctx, claim, ConditionGitPushed, ReasonPushFailed, "Failed to push charts: "+err.Error())
 		return ctrl.Result{}, err
 	}
 
@@ -193,25 +213,23 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	logger.Info("Generating root application structure", "repo", voltranRepo)
 
 	clusterType := claim.Spec.GitOps.ClusterType
-	if clusterType == "" {
-		clusterType = "nonprod"
-	}
-
 	environments := claim.Spec.GitOps.Environments
-	if len(environments) == 0 {
-		environments = []string{"dev", "qa", "sandbox", "staging", "prod"}
-	}
 
 	voltranFiles := r.generateVoltranStructure(claim.Spec.Organization, chartsRepo,
 		clusterType, environments, branch, voltranRepo, claim.Spec.GiteaURL)
+	conditions := r.conditions(claim)
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered,
+		fmt.Sprintf("rendered %d chart files and %d GitOps files", len(chartFiles), len(voltranFiles)))
 
-	if err := giteaClient.PushFiles(ctx, repoURLs[voltranRepo], branch, voltranFiles,
+	if _, err := provider.PushFiles(ctx, repoURLs[voltranRepo], branch, voltranFiles,
 		"Initial GitOps structure by operator", "Platform Operator", "operator@platform.local"); err != nil {
 		logger.Error(err, "failed to push voltran structure")
-		r.updateStatusFailed(ctx, claim, "Failed to push GitOps structure: "+err.Error())
+		r.updateStatusFailed(ctx, claim, ConditionGitPushed, ReasonPushFailed, "Failed to push GitOps structure: "+err.Error())
 		return ctrl.Result{}, err
 	}
 
+	conditions.set(ConditionGitPushed, metav1.ConditionTrue, ReasonPushed,
+		fmt.Sprintf("charts and GitOps structure pushed to %s and %s", chartsRepo, voltranRepo))
 	claim.Status.RootAppGenerated = true
 	if err := r.Status().Update(ctx, claim); err != nil {
 		return ctrl.Result{}, err
@@ -221,14 +239,15 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	logger.Info("Deploying root applications to ArgoCD namespace")
 	if err := r.deployRootApplications(ctx, voltranFiles); err != nil {
 		logger.Error(err, "failed to deploy root applications")
-		r.updateStatusFailed(ctx, claim, "Failed to deploy root applications: "+err.Error())
+		r.updateStatusFailed(ctx, claim, ConditionSynced, ReasonBootstrapFailed, "Failed to deploy root applications: "+err.Error())
 		return ctrl.Result{}, err
 	}
 	logger.Info("Successfully deployed root applications to ArgoCD")
+	conditions.set(ConditionSynced, metav1.ConditionTrue, ReasonSynced, "root applications deployed to ArgoCD")
 
 	// Generate ArgoCD setup manifests in the GitOps repo
 	logger.Info("Generating ArgoCD setup manifests")
-	if err := r.generateArgoCDSetup(ctx, claim, giteaClient, repoURLs[voltranRepo], branch); err != nil {
+	if err := r.generateArgoCDSetup(ctx, claim, provider, repoURLs[voltranRepo], branch); err != nil {
 		logger.Error(err, "failed to generate ArgoCD setup manifests")
 		// Don't fail the whole reconciliation, just log the error
 		claim.Status.Message = fmt.Sprintf("Bootstrap completed but ArgoCD setup generation failed: %v", err)
@@ -240,6 +259,7 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	claim.Status.Phase = "Ready"
 	claim.Status.Ready = true
 	claim.Status.LastUpdated = metav1.Now()
+	conditions.setReady(bootstrapSteps...)
 	if err := r.Status().Update(ctx, claim); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -412,23 +432,28 @@ spec:
 `, clusterType, giteaURL, org, voltranRepo, clusterType, branch)
 }
 
-// updateStatusFailed updates the status to Failed
-func (r *BootstrapReconciler) updateStatusFailed(ctx context.Context, claim *platformv1.BootstrapClaim, message string) {
+// updateStatusFailed updates the status to Failed and records the step that failed
+func (r *BootstrapReconciler) updateStatusFailed(ctx context.Context, claim *platformv1.BootstrapClaim, step, reason, message string) {
 	claim.Status.Phase = "Failed"
 	claim.Status.Ready = false
 	claim.Status.Message = message
 	claim.Status.LastUpdated = metav1.Now()
+	conditions := r.conditions(claim)
+	conditions.set(step, metav1.ConditionFalse, reason, message)
+	conditions.setReady(bootstrapSteps...)
 	r.Status().Update(ctx, claim)
 }
 
+// conditions returns the condition setter of the claim
+func (r *BootstrapReconciler) conditions(claim *platformv1.BootstrapClaim) *claimConditions {
+	return newClaimConditions(claim, &claim.Status.Conditions, r.Recorder)
+}
+
 // generateArgoCDSetup generates ArgoCD setup manifests in the GitOps repo
-func (r *BootstrapReconciler) generateArgoCDSetup(ctx context.Context, claim *platformv1.BootstrapClaim, giteaClient *gitea.Client, voltranURL, branch string) error {
+func (r *BootstrapReconciler) generateArgoCDSetup(ctx context.Context, claim *platformv1.BootstrapClaim, provider gitprovider.GitProvider, voltranURL, branch string) error {
 	logger := log.FromContext(ctx)
 
 	clusterType := claim.Spec.GitOps.ClusterType
-	if clusterType == "" {
-		clusterType = "nonprod"
-	}
 
 	// Generate ArgoCD setup manifests
 	setupFiles := make(map[string]string)
@@ -514,7 +539,25 @@ stringData:
     }
 `
 
-	// 4. README with instructions
+	// 4. Progressive syncs, needed for the RollingSync strategy ordering dependent applications
+	setupFiles["argocd-setup/04-progressive-syncs.yaml"] = `# Enable ApplicationSet progressive syncs
+# ApplicationClaims order dependent applications with the RollingSync strategy, which the
+# ApplicationSet controller ignores unless progressive syncs are enabled
+# Restart the controller after applying:
+#   kubectl rollout restart -n argocd deployment/argocd-applicationset-controller
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: argocd-cmd-params-cm
+  namespace: argocd
+  labels:
+    app.kubernetes.io/name: argocd-cmd-params-cm
+    app.kubernetes.io/part-of: argocd
+data:
+  applicationsetcontroller.enable.progressive.syncs: "true"
+`
+
+	// 5. README with instructions
 	setupFiles["argocd-setup/README.md"] = fmt.Sprintf(`# ArgoCD Setup Manifests
 
 These manifests were automatically generated by the Platform Operator to set up ArgoCD for your GitOps workflow.
@@ -544,6 +587,11 @@ These manifests were automatically generated by the Platform Operator to set up
     kubectl apply -f argocd-setup/01-repo-secret.yaml
     kubectl apply -f argocd-setup/02-helm-oci-secret.yaml
     kubectl apply -f argocd-setup/03-github-token-secret.yaml
+    kubectl apply -f argocd-setup/04-progressive-syncs.yaml
+
+   Restart the ApplicationSet controller so that it picks up progressive syncs:
+
+    kubectl rollout restart -n argocd deployment/argocd-applicationset-controller
 
 3. **Deploy the root applications**:
 
@@ -564,7 +612,8 @@ These manifests were automatically generated by the Platform Operator to set up
 - **01-repo-secret.yaml**: Configures ArgoCD to authenticate with your Gitea repository
 - **02-helm-oci-secret.yaml**: Configures ArgoCD to pull Helm charts from GitHub Container Registry
 - **03-github-token-secret.yaml**: Configures image pull secrets for pulling container images from GHCR
-- **root-apps/%s/*.yaml**: Root applications that watch for ApplicationSets (already in voltran repo)
+- **04-progressive-syncs.yaml**: Enables the RollingSync strategy that deploys dependent applications after their dependencies
+%s- **root-apps/%s/*.yaml**: Root applications that watch for ApplicationSets (already in voltran repo)
 
 ## Next Steps
 
@@ -584,7 +633,7 @@ After applying these manifests:
     kubectl port-forward svc/argocd-server -n argocd 8080:443
 
    Then visit https://localhost:8080
-
+%s
 ## Troubleshooting
 
 - If applications show as "Unknown" in ArgoCD, ensure the OCI registry credentials are correct
@@ -592,10 +641,15 @@ After applying these manifests:
 - For image pull issues, ensure the GitHub token secret exists in the platform-operator-system namespace
 
 Generated by Platform Operator at $(date)
-`, clusterType, clusterType, clusterType)
+`, clusterType, clusterType, encryptionManifests(r.Encryptor), clusterType, encryptionReadme(r.Encryptor))
+
+	// 6. sops creation rules and helm-secrets configuration when values are encrypted
+	for path, content := range encryptionSetupFiles(r.Encryptor) {
+		setupFiles[path] = content
+	}
 
 	// Push the setup files to Gitea
-	if err := giteaClient.PushFiles(ctx, voltranURL, branch, setupFiles,
+	if _, err := provider.PushFiles(ctx, voltranURL, branch, setupFiles,
 		"Add ArgoCD setup manifests", "Platform Operator", "operator@platform.local"); err != nil {
 		return fmt.Errorf("failed to push ArgoCD setup manifests: %w", err)
 	}
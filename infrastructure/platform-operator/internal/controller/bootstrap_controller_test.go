@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+	"github.com/infraforge/platform-operator/pkg/sops"
+)
+
+func TestBootstrapPushesChartsUnencrypted(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	// The plain remote needs both repositories
+	base := newVoltranRepo(t, map[string]string{"README.md": "voltran\n"})
+	charts := newVoltranRepo(t, map[string]string{"README.md": "charts\n"})
+	if err := os.Rename(filepath.Join(charts, "acme", "voltran.git"), filepath.Join(base, "acme", "charts.git")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A chart shipping a Secret template and default credentials
+	chartFiles := map[string]string{
+		"api/Chart.yaml":  "apiVersion: v2\nname: api\nversion: 0.1.0\n",
+		"api/values.yaml": "database:\n  password: changeme\nenv:\n  - name: LOG_LEVEL\n    value: info\n",
+		"api/templates/secret.yaml": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: {{ .Release.Name }}\n" +
+			"stringData:\n  password: {{ .Values.database.password | quote }}\n",
+	}
+	chartsDir := t.TempDir()
+	for path, content := range chartFiles {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(chartsDir, path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(chartsDir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptor, err := sops.NewEncryptor([]age.Identity{identity}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claim := &platformv1.BootstrapClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+		Spec: platformv1.BootstrapClaimSpec{
+			GiteaURL:     base,
+			GitProvider:  gitprovider.ProviderGit,
+			Organization: "acme",
+			Repositories: platformv1.RepositoriesSpec{Charts: "charts", Voltran: "voltran"},
+			GitOps:       platformv1.GitOpsSpec{Branch: "main", ClusterType: "nonprod", Environments: []string{"dev"}},
+		},
+		Status: platformv1.BootstrapClaimStatus{Phase: "Pending"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(claim).WithStatusSubresource(claim).Build()
+	r := &BootstrapReconciler{Client: c, Scheme: scheme, ChartsPath: chartsDir, Encryptor: encryptor}
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "platform"}}); err != nil {
+		t.Fatalf("expected the bootstrap to succeed, got %v", err)
+	}
+
+	provider := gitprovider.NewLocal(base)
+	pushed, err := provider.CloneAndExtractFiles(ctx, provider.ConstructCloneURL("acme", "charts"), "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range chartFiles {
+		if pushed[path] != content {
+			t.Errorf("expected chart file %s to be pushed as is, got:\n%s", path, pushed[path])
+		}
+	}
+
+	voltran, err := provider.CloneAndExtractFiles(ctx, provider.ConstructCloneURL("acme", "voltran"), "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sops.IsEncrypted([]byte(voltran["argocd-setup/01-repo-secret.yaml"])) {
+		t.Errorf("expected the generated repository Secret to be encrypted:\n%s", voltran["argocd-setup/01-repo-secret.yaml"])
+	}
+
+	// Every setup manifest has its own number and is documented
+	readme := voltran["argocd-setup/README.md"]
+	for _, manifest := range []string{"04-progressive-syncs.yaml", "05-argocd-helm-secrets.yaml"} {
+		if voltran["argocd-setup/"+manifest] == "" || !strings.Contains(readme, "**"+manifest+"**") {
+			t.Errorf("expected argocd-setup/%s to be pushed and listed in the README", manifest)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition types shared by the claim kinds; each step builds on the previous one and Ready
+// summarizes them
+const (
+	// ConditionRendered the generated files could be rendered from the claim spec
+	ConditionRendered = "Rendered"
+
+	// ConditionGitPushed the generated files are on the voltran branch
+	ConditionGitPushed = "GitPushed"
+
+	// ConditionOperatorsReady the operators the platform services depend on are installed and available
+	ConditionOperatorsReady = "OperatorsReady"
+
+	// ConditionSynced ArgoCD synced everything generated for the claim
+	ConditionSynced = "Synced"
+
+	// ConditionHealthy everything ArgoCD deployed for the claim is healthy
+	ConditionHealthy = "Healthy"
+
+	// ConditionReady the claim is fully deployed
+	ConditionReady = "Ready"
+)
+
+// Condition and Event reasons
+const (
+	ReasonRendered           = "Rendered"
+	ReasonInvalidSpec        = "InvalidSpec"
+	ReasonConflict           = "Conflict"
+	ReasonPushed             = "Pushed"
+	ReasonPushFailed         = "PushFailed"
+	ReasonInvalidCredentials = "InvalidCredentials"
+	ReasonAwaitingReview     = "AwaitingReview"
+	ReasonPullRequestClosed  = "PullRequestClosed"
+	ReasonOperatorsReady     = "OperatorsReady"
+	ReasonWaitingForOperator = "WaitingForOperator"
+	ReasonSynced             = "Synced"
+	ReasonOutOfSync          = "OutOfSync"
+	ReasonHealthy            = "Healthy"
+	ReasonProgressing        = "Progressing"
+	ReasonDegraded           = "Degraded"
+	ReasonPending            = "Pending"
+	ReasonReady              = "Ready"
+	ReasonBootstrapFailed    = "BootstrapFailed"
+	ReasonInSync             = "InSync"
+	ReasonDriftDetected      = "DriftDetected"
+	ReasonDriftCorrected     = "DriftCorrected"
+)
+
+// warningReasons reasons of conditions that need attention, reported with Warning Events; other
+// reasons are progress and reported with Normal Events
+var warningReasons = map[string]bool{
+	ReasonInvalidSpec:        true,
+	ReasonConflict:           true,
+	ReasonPushFailed:         true,
+	ReasonInvalidCredentials: true,
+	ReasonPullRequestClosed:  true,
+	ReasonBootstrapFailed:    true,
+	ReasonDegraded:           true,
+	ReasonDriftDetected:      true,
+}
+
+// claimConditions sets the conditions of a claim's current generation and emits an Event for
+// every condition whose status or reason changes, so kubectl describe shows the history
+type claimConditions struct {
+	object     client.Object
+	conditions *[]metav1.Condition
+	recorder   record.EventRecorder
+}
+
+// newClaimConditions returns the condition setter of a claim; a nil recorder emits no Events
+func newClaimConditions(object client.Object, conditions *[]metav1.Condition, recorder record.EventRecorder) *claimConditions {
+	return &claimConditions{object: object, conditions: conditions, recorder: recorder}
+}
+
+// set records a condition for the claim's current generation; an Event is emitted when its
+// status or reason changes or a new generation reaches it
+func (c *claimConditions) set(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	c.apply(conditionType, status, reason, message, true)
+}
+
+// apply records a condition and, when emit is set, an Event if its status or reason changed
+func (c *claimConditions) apply(conditionType string, status metav1.ConditionStatus, reason, message string, emit bool) {
+	previous := meta.FindStatusCondition(*c.conditions, conditionType)
+	transition := previous == nil || previous.Status != status || previous.Reason != reason ||
+		previous.ObservedGeneration != c.object.GetGeneration()
+
+	meta.SetStatusCondition(c.conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: c.object.GetGeneration(),
+	})
+
+	if emit && transition && status != metav1.ConditionUnknown && c.recorder != nil {
+		eventType := corev1.EventTypeNormal
+		if warningReasons[reason] {
+			eventType = corev1.EventTypeWarning
+		}
+		c.recorder.Event(c.object, eventType, reason, fmt.Sprintf("%s: %s", conditionType, message))
+	}
+}
+
+// setReady derives Ready from the step conditions: true once all of them are, otherwise false
+// with the reason and message of the first step that is not. The step already emitted the Event
+// explaining why the claim is not ready.
+func (c *claimConditions) setReady(steps ...string) {
+	for _, step := range steps {
+		condition := meta.FindStatusCondition(*c.conditions, step)
+		switch {
+		case condition == nil:
+			c.apply(ConditionReady, metav1.ConditionFalse, ReasonPending, fmt.Sprintf("waiting for %s", step), false)
+			return
+		case condition.Status != metav1.ConditionTrue:
+			c.apply(ConditionReady, metav1.ConditionFalse, condition.Reason, condition.Message, false)
+			return
+		}
+	}
+	c.set(ConditionReady, metav1.ConditionTrue, ReasonReady, "all steps completed")
+}
+
+// argoState sync and health of the ArgoCD Application of an application, component or service
+type argoState struct {
+	Name         string
+	SyncStatus   string
+	HealthStatus string
+}
+
+// setArgoConditions sets Synced and Healthy from the ArgoCD Applications generated for the claim
+func (c *claimConditions) setArgoConditions(states []argoState) {
+	var outOfSync, unhealthy []string
+	degraded := false
+	for _, state := range states {
+		if state.SyncStatus != "Synced" {
+			outOfSync = append(outOfSync, fmt.Sprintf("%s (%s)", state.Name, state.SyncStatus))
+		}
+		if state.HealthStatus != "Healthy" {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s (%s)", state.Name, state.HealthStatus))
+			degraded = degraded || state.HealthStatus == "Degraded"
+		}
+	}
+
+	if len(outOfSync) == 0 {
+		c.set(ConditionSynced, metav1.ConditionTrue, ReasonSynced, fmt.Sprintf("%d ArgoCD Applications synced", len(states)))
+	} else {
+		c.set(ConditionSynced, metav1.ConditionFalse, ReasonOutOfSync, "not synced: "+strings.Join(outOfSync, ", "))
+	}
+
+	switch {
+	case len(unhealthy) == 0:
+		c.set(ConditionHealthy, metav1.ConditionTrue, ReasonHealthy, fmt.Sprintf("%d ArgoCD Applications healthy", len(states)))
+	case degraded:
+		c.set(ConditionHealthy, metav1.ConditionFalse, ReasonDegraded, "not healthy: "+strings.Join(unhealthy, ", "))
+	default:
+		c.set(ConditionHealthy, metav1.ConditionFalse, ReasonProgressing, "not healthy: "+strings.Join(unhealthy, ", "))
+	}
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// drainEvents returns the Events recorded so far
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestClaimConditionsEvents(t *testing.T) {
+	claim := &platformv1.ApplicationClaim{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "team-a", Generation: 1}}
+	recorder := record.NewFakeRecorder(10)
+	conditions := newClaimConditions(claim, &claim.Status.Conditions, recorder)
+
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered, "rendered 3 files")
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered, "rendered 3 files")
+	conditions.set(ConditionGitPushed, metav1.ConditionFalse, ReasonPushFailed, "connection refused")
+	conditions.setReady(ConditionRendered, ConditionGitPushed)
+
+	events := drainEvents(recorder)
+	want := []string{
+		"Normal Rendered Rendered: rendered 3 files",
+		"Warning PushFailed GitPushed: connection refused",
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: expected %q, got %q", i, want[i], events[i])
+		}
+	}
+
+	ready := meta.FindStatusCondition(claim.Status.Conditions, ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonPushFailed || ready.ObservedGeneration != 1 {
+		t.Errorf("expected Ready to report the failed push, got %+v", ready)
+	}
+
+	// A new generation reaching the same state is reported again
+	claim.Generation = 2
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered, "rendered 3 files")
+	conditions.set(ConditionGitPushed, metav1.ConditionTrue, ReasonPushed, "commit abc")
+	conditions.setReady(ConditionRendered, ConditionGitPushed)
+	if events := drainEvents(recorder); len(events) != 3 || events[2] != "Normal Ready Ready: all steps completed" {
+		t.Errorf("unexpected events for the new generation %v", events)
+	}
+}
+
+func TestSetReadyWaitsForMissingSteps(t *testing.T) {
+	claim := &platformv1.ApplicationClaim{ObjectMeta: metav1.ObjectMeta{Generation: 1}}
+	conditions := newClaimConditions(claim, &claim.Status.Conditions, nil)
+
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered, "rendered")
+	conditions.setReady(ConditionRendered, ConditionGitPushed)
+
+	ready := meta.FindStatusCondition(claim.Status.Conditions, ConditionReady)
+	if ready == nil || ready.Reason != ReasonPending || ready.Message != "waiting for GitPushed" {
+		t.Errorf("unexpected Ready condition %+v", ready)
+	}
+}
+
+func TestSetArgoConditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		states        []argoState
+		syncedReason  string
+		healthyReason string
+	}{
+		{
+			name:          "all synced and healthy",
+			states:        []argoState{{Name: "api", SyncStatus: "Synced", HealthStatus: "Healthy"}},
+			syncedReason:  ReasonSynced,
+			healthyReason: ReasonHealthy,
+		},
+		{
+			name: "rolling out",
+			states: []argoState{
+				{Name: "api", SyncStatus: "Synced", HealthStatus: "Healthy"},
+				{Name: "web", SyncStatus: "Unknown", HealthStatus: "Missing"},
+			},
+			syncedReason:  ReasonOutOfSync,
+			healthyReason: ReasonProgressing,
+		},
+		{
+			name: "degraded",
+			states: []argoState{
+				{Name: "api", SyncStatus: "Synced", HealthStatus: "Degraded"},
+				{Name: "web", SyncStatus: "Synced", HealthStatus: "Progressing"},
+			},
+			syncedReason:  ReasonSynced,
+			healthyReason: ReasonDegraded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := &platformv1.PlatformApplicationClaim{ObjectMeta: metav1.ObjectMeta{Generation: 1}}
+			conditions := newClaimConditions(claim, &claim.Status.Conditions, nil)
+			conditions.setArgoConditions(tt.states)
+
+			if c := meta.FindStatusCondition(claim.Status.Conditions, ConditionSynced); c == nil || c.Reason != tt.syncedReason {
+				t.Errorf("expected Synced reason %s, got %+v", tt.syncedReason, c)
+			}
+			if c := meta.FindStatusCondition(claim.Status.Conditions, ConditionHealthy); c == nil || c.Reason != tt.healthyReason {
+				t.Errorf("expected Healthy reason %s, got %+v", tt.healthyReason, c)
+			}
+		})
+	}
+}
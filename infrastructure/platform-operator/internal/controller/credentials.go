@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+// Keys of a claim's Git credentials Secret; basic-auth and ssh-auth Secrets can be used as is
+const (
+	credentialsUsernameKey   = corev1.BasicAuthUsernameKey
+	credentialsTokenKey      = "token"
+	credentialsSSHKey        = corev1.SSHAuthPrivateKey
+	credentialsSSHURLKey     = "sshURL"
+	credentialsKnownHostsKey = "known_hosts"
+)
+
+// claimGitProvider returns the Git provider of a claim. cfg holds the provider, server URL and
+// operator credentials; the credentials Secret is only looked up in the claim's own namespace, so
+// a claim cannot borrow the Secret of another team, and values it does not set fall back to the
+// operator credentials.
+func claimGitProvider(ctx context.Context, reader client.Reader, namespace string, ref *platformv1.GitCredentialsSecretRef, cfg gitprovider.Config) (gitprovider.GitProvider, error) {
+	if ref == nil {
+		return gitprovider.New(cfg)
+	}
+
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get Git credentials Secret %s/%s: %w", namespace, ref.Name, err)
+	}
+
+	if value := secret.Data[credentialsUsernameKey]; len(value) > 0 {
+		cfg.Username = string(value)
+	}
+	if value := secret.Data[credentialsTokenKey]; len(value) > 0 {
+		cfg.Token = string(value)
+	}
+
+	cfg.SSHPrivateKey = secret.Data[credentialsSSHKey]
+	if len(cfg.SSHPrivateKey) == 0 {
+		if len(secret.Data[credentialsTokenKey]) == 0 {
+			return nil, fmt.Errorf("Git credentials Secret %s/%s has neither %s nor %s", namespace, ref.Name, credentialsTokenKey, credentialsSSHKey)
+		}
+		return gitprovider.New(cfg)
+	}
+	cfg.SSHURL = string(secret.Data[credentialsSSHURLKey])
+	if cfg.SSHURL == "" {
+		return nil, fmt.Errorf("Git credentials Secret %s/%s has %s but no %s", namespace, ref.Name, credentialsSSHKey, credentialsSSHURLKey)
+	}
+	cfg.KnownHosts = secret.Data[credentialsKnownHostsKey]
+	provider, err := gitprovider.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Git credentials Secret %s/%s: %w", namespace, ref.Name, err)
+	}
+	return provider, nil
+}
+
+// gitProviderOf returns the Git provider of a claim, the operator default when unset
+func gitProviderOf(provider string, d *defaults.Defaults) string {
+	if provider != "" {
+		return provider
+	}
+	return defaults.OrBuiltin(d).GitProvider
+}
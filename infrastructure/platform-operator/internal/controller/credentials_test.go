@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+func TestClaimGitProvider(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	secret := func(namespace, name string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Data: map[string][]byte{}}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		secret("team-a", "git-token", map[string]string{credentialsUsernameKey: "team-a-bot", credentialsTokenKey: "t0ken"}),
+		secret("team-a", "git-ssh", map[string]string{credentialsSSHKey: "not a key", credentialsSSHURLKey: "ssh://git@gitea-ssh:22"}),
+		secret("team-a", "git-ssh-no-url", map[string]string{credentialsSSHKey: "not a key"}),
+		secret("team-a", "git-empty", map[string]string{credentialsUsernameKey: "team-a-bot"}),
+		secret("team-b", "git-team-b", map[string]string{credentialsTokenKey: "other"}),
+	).Build()
+	operator := gitprovider.Config{URL: "http://gitea:3000", Username: "admin", Token: "admin-token"}
+
+	tests := []struct {
+		name    string
+		ref     *platformv1.GitCredentialsSecretRef
+		wantErr string
+	}{
+		{name: "operator credentials", ref: nil},
+		{name: "token secret", ref: &platformv1.GitCredentialsSecretRef{Name: "git-token"}},
+		{name: "secret of another namespace", ref: &platformv1.GitCredentialsSecretRef{Name: "git-team-b"}, wantErr: "not found"},
+		{name: "invalid ssh key", ref: &platformv1.GitCredentialsSecretRef{Name: "git-ssh"}, wantErr: "invalid SSH private key"},
+		{name: "ssh key without url", ref: &platformv1.GitCredentialsSecretRef{Name: "git-ssh-no-url"}, wantErr: "no sshURL"},
+		{name: "no credentials", ref: &platformv1.GitCredentialsSecretRef{Name: "git-empty"}, wantErr: "neither token nor ssh-privatekey"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := claimGitProvider(context.Background(), c, "team-a", tt.ref, operator)
+			if tt.wantErr == "" {
+				if err != nil || provider == nil {
+					t.Fatalf("expected a client, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Deletion falls back to the operator credentials when the Secret is gone
+	_, err := claimGitProvider(context.Background(), c, "team-b", &platformv1.GitCredentialsSecretRef{Name: "git-ssh"}, operator)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error for a missing Secret, got %v", err)
+	}
+
+	// The provider of the claim, or the operator default, selects the implementation
+	if gitProviderOf("", nil) != gitprovider.ProviderGitea {
+		t.Errorf("expected gitea as the built-in default provider")
+	}
+	github := operator
+	github.Provider = gitProviderOf(gitprovider.ProviderGitHub, nil)
+	provider, err := claimGitProvider(context.Background(), c, "team-a", &platformv1.GitCredentialsSecretRef{Name: "git-token"}, github)
+	if _, ok := provider.(*gitprovider.GitHub); err != nil || !ok {
+		t.Errorf("expected a GitHub provider, got %T, %v", provider, err)
+	}
+}
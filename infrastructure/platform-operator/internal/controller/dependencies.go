@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+const (
+	// syncWaveLabel is set on every generated Application with its sync wave; the progressive
+	// sync steps of the ApplicationSet select on it
+	syncWaveLabel = "platform.infraforge.io/sync-wave"
+
+	// syncWaveAnnotation orders Applications synced by the same parent Application
+	syncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+
+	// applicationPhaseWaitingForDependencies phase of applications held back until the components
+	// and platform services they depend on are Synced and Healthy
+	applicationPhaseWaitingForDependencies = "WaitingForDependencies"
+)
+
+// applicationWaves returns the sync wave of every enabled application of the claim. Applications
+// without dependencies are in wave 0, dependencies on components or platform services (deployed
+// by other ApplicationSets) move an application to wave 1 and every application comes one wave
+// after the applications it depends on. Dependencies on disabled applications are ignored.
+// Waves only order the applications of the claim's ApplicationSet; components and platform
+// services are waited for by heldApplications.
+func applicationWaves(claim *platformv1.ApplicationClaim) (map[string]int, error) {
+	apps := map[string]platformv1.ApplicationSpec{}
+	for _, app := range claim.Spec.Applications {
+		if app.Enabled {
+			apps[app.Name] = app
+		}
+	}
+	declared := map[string]bool{}
+	for _, app := range claim.Spec.Applications {
+		declared[app.Name] = true
+	}
+
+	waves := map[string]int{}
+	visiting := map[string]bool{}
+	var visit func(name string, path []string) (int, error)
+	visit = func(name string, path []string) (int, error) {
+		if wave, ok := waves[name]; ok {
+			return wave, nil
+		}
+		path = append(path, name)
+		if visiting[name] {
+			return 0, fmt.Errorf("application dependency cycle %s", strings.Join(path, " -> "))
+		}
+		visiting[name] = true
+
+		wave := 0
+		for _, dep := range apps[name].DependsOn {
+			if _, ok := apps[dep]; ok {
+				depWave, err := visit(dep, path)
+				if err != nil {
+					return 0, err
+				}
+				wave = max(wave, depWave+1)
+			} else if !declared[dep] {
+				wave = max(wave, 1)
+			}
+		}
+
+		visiting[name] = false
+		waves[name] = wave
+		return wave, nil
+	}
+
+	// Visit in declaration order so the reported cycle is stable
+	for _, app := range claim.Spec.Applications {
+		if !app.Enabled {
+			continue
+		}
+		if _, err := visit(app.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return waves, nil
+}
+
+// rollingSyncSteps returns the ApplicationSet progressive sync steps deploying one wave after the
+// other, nil when every application is in the same wave. ArgoCD only honors them with
+// applicationsetcontroller.enable.progressive.syncs set, see the bootstrap argocd-setup manifests.
+func rollingSyncSteps(waves map[string]int) []map[string]interface{} {
+	seen := map[int]bool{}
+	var ordered []int
+	for _, wave := range waves {
+		if !seen[wave] {
+			seen[wave] = true
+			ordered = append(ordered, wave)
+		}
+	}
+	if len(ordered) < 2 {
+		return nil
+	}
+	sort.Ints(ordered)
+
+	steps := make([]map[string]interface{}, len(ordered))
+	for i, wave := range ordered {
+		steps[i] = map[string]interface{}{
+			"matchExpressions": []map[string]interface{}{
+				{
+					"key":      syncWaveLabel,
+					"operator": "In",
+					"values":   []string{strconv.Itoa(wave)},
+				},
+			},
+		}
+	}
+	return steps
+}
+
+// resolveExternalDependencies checks that every dependency which is not an application of the
+// claim names one of its components or a platform service of a PlatformApplicationClaim for the
+// same environment in the claim's namespace, and returns whether each of these external
+// dependencies is Synced and Healthy
+func (r *ApplicationClaimGitOpsReconciler) resolveExternalDependencies(ctx context.Context, claim *platformv1.ApplicationClaim) (map[string]bool, error) {
+	apps := map[string]bool{}
+	for _, app := range claim.Spec.Applications {
+		apps[app.Name] = true
+	}
+	components := map[string]bool{}
+	for _, comp := range claim.Spec.Components {
+		components[comp.Name] = true
+	}
+
+	ready := map[string]bool{}
+	var componentReady, platformReady map[string]bool
+	for _, app := range claim.Spec.Applications {
+		if !app.Enabled {
+			continue
+		}
+		for _, dep := range app.DependsOn {
+			switch {
+			case apps[dep]:
+				continue
+			case components[dep]:
+				if componentReady == nil {
+					// Components are deployed by the claim's components ApplicationSet
+					statuses, _, err := r.collectComponentStatuses(ctx, claim)
+					if err != nil {
+						return nil, err
+					}
+					componentReady = map[string]bool{}
+					for _, status := range statuses {
+						componentReady[status.Name] = status.Ready
+					}
+				}
+				ready[dep] = componentReady[dep]
+				continue
+			}
+
+			if platformReady == nil {
+				// Only list platform claims when a dependency is not declared by the claim itself
+				platformClaims := &platformv1.PlatformApplicationClaimList{}
+				if err := r.List(ctx, platformClaims, client.InNamespace(claim.Namespace)); err != nil {
+					return nil, fmt.Errorf("failed to list PlatformApplicationClaims: %w", err)
+				}
+				platformReady = map[string]bool{}
+				for _, pc := range platformClaims.Items {
+					if pc.Spec.Environment != claim.Spec.Environment {
+						continue
+					}
+					for _, svc := range pc.Spec.Services {
+						if svc.Enabled {
+							platformReady[svc.Name] = platformReady[svc.Name] || serviceReady(pc.Status.Services, svc.Name)
+						}
+					}
+				}
+			}
+			serviceIsReady, ok := platformReady[dep]
+			if !ok {
+				return nil, fmt.Errorf("application %s depends on %s, which is neither an application, a component nor a platform service of environment %s",
+					app.Name, dep, claim.Spec.Environment)
+			}
+			ready[dep] = serviceIsReady
+		}
+	}
+	return ready, nil
+}
+
+// serviceReady reports whether the platform service is Synced and Healthy
+func serviceReady(statuses []platformv1.PlatformServiceStatus, name string) bool {
+	for _, status := range statuses {
+		if status.Name == name {
+			return status.Ready
+		}
+	}
+	return false
+}
+
+// heldApplications returns the enabled applications to hold back, keyed by name with the reason:
+// applications depending on a component or platform service that is not Synced and Healthy yet,
+// and applications depending on a held application. ready holds the readiness of the external
+// dependencies. Applications already rendered stay rendered, so that a restarting database does
+// not prune the applications using it.
+func heldApplications(claim *platformv1.ApplicationClaim, waves map[string]int, ready map[string]bool) map[string]string {
+	rendered := map[string]bool{}
+	for _, status := range claim.Status.Applications {
+		rendered[status.Name] = status.Phase != applicationPhaseWaitingForDependencies
+	}
+
+	// Earlier waves first, so that held application dependencies are known
+	var apps []platformv1.ApplicationSpec
+	for _, app := range claim.Spec.Applications {
+		if _, ok := waves[app.Name]; ok {
+			apps = append(apps, app)
+		}
+	}
+	sort.SliceStable(apps, func(i, j int) bool { return waves[apps[i].Name] < waves[apps[j].Name] })
+
+	held := map[string]string{}
+	for _, app := range apps {
+		if rendered[app.Name] {
+			continue
+		}
+		var waiting []string
+		for _, dep := range app.DependsOn {
+			if depReady, external := ready[dep]; external && !depReady {
+				waiting = append(waiting, dep)
+			} else if _, ok := held[dep]; ok {
+				waiting = append(waiting, dep)
+			}
+		}
+		if len(waiting) > 0 {
+			held[app.Name] = "waiting for " + strings.Join(waiting, ", ")
+		}
+	}
+	return held
+}
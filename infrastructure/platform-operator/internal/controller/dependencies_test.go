@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// newDependencyClaim returns a dev ApplicationClaim where order-service waits for postgres and
+// user-service, and user-service for redis
+func newDependencyClaim() *platformv1.ApplicationClaim {
+	return &platformv1.ApplicationClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+		Spec: platformv1.ApplicationClaimSpec{
+			GiteaURL:     "http://gitea:3000",
+			Organization: "acme",
+			Environment:  "dev",
+			ClusterType:  "nonprod",
+			Applications: []platformv1.ApplicationSpec{
+				{Name: "order-service", Enabled: true, DependsOn: []string{"postgres", "user-service"}},
+				{Name: "user-service", Enabled: true, DependsOn: []string{"redis"}},
+				{Name: "web", Enabled: true, DependsOn: []string{"legacy"}},
+				{Name: "legacy", Enabled: false},
+			},
+		},
+	}
+}
+
+func TestApplicationWaves(t *testing.T) {
+	claim := newDependencyClaim()
+	waves, err := applicationWaves(claim)
+	if err != nil {
+		t.Fatalf("applicationWaves failed: %v", err)
+	}
+	expected := map[string]int{"order-service": 2, "user-service": 1, "web": 0}
+	if !reflect.DeepEqual(waves, expected) {
+		t.Errorf("unexpected waves %v, want %v", waves, expected)
+	}
+
+	r := &ApplicationClaimGitOpsReconciler{VoltranRepo: "voltran", Branch: "main"}
+	var appSet map[string]interface{}
+	if err := yaml.Unmarshal([]byte(r.generateApplicationSet(claim, waves)), &appSet); err != nil {
+		t.Fatal(err)
+	}
+	spec := appSet["spec"].(map[string]interface{})
+	steps := spec["strategy"].(map[string]interface{})["rollingSync"].(map[string]interface{})["steps"].([]interface{})
+	if len(steps) != 3 {
+		t.Fatalf("expected a rolling sync step per wave, got %v", steps)
+	}
+	last := steps[2].(map[string]interface{})["matchExpressions"].([]interface{})[0].(map[string]interface{})
+	if last["key"] != syncWaveLabel || !reflect.DeepEqual(last["values"], []interface{}{"2"}) {
+		t.Errorf("expected the last step to select wave 2, got %v", last)
+	}
+	annotations := spec["template"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	if annotations[syncWaveAnnotation] != "{{syncWave}}" {
+		t.Errorf("expected the sync-wave annotation in the template, got %v", annotations)
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(r.generateConfigJSON(claim, claim.Spec.Applications[0], chartSource{}, waves["order-service"])), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["syncWave"] != "2" {
+		t.Errorf("expected syncWave 2 in config.json, got %v", config["syncWave"])
+	}
+
+	// Without dependencies all applications start together
+	if steps := rollingSyncSteps(map[string]int{"a": 0, "b": 0}); steps != nil {
+		t.Errorf("expected no rolling sync for a single wave, got %v", steps)
+	}
+
+	claim.Spec.Applications[1].DependsOn = []string{"order-service"}
+	if _, err := applicationWaves(claim); err == nil || !strings.Contains(err.Error(), "order-service -> user-service -> order-service") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
+
+func TestResolveExternalDependencies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	platform := &platformv1.PlatformApplicationClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop-platform", Namespace: "shop"},
+		Spec: platformv1.PlatformApplicationClaimSpec{
+			Environment: "dev",
+			Services:    []platformv1.PlatformServiceSpec{{Name: "postgres", Type: "postgresql", Enabled: true}},
+		},
+	}
+	claim := newDependencyClaim()
+	claim.Spec.Components = []platformv1.ComponentSpec{{Name: "redis", Type: "redis"}}
+
+	platform.Status.Services = []platformv1.PlatformServiceStatus{{Name: "postgres", Type: "postgresql", Ready: true}}
+	redis := newArgoApplicationFixture("redis-shop", "", "dev", "Synced", "Progressing")
+	redis.SetLabels(map[string]string{componentLabel: "redis", envLabel: "dev", namespaceLabel: "dev"})
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(platform, redis).Build()
+	r := &ApplicationClaimGitOpsReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+
+	ready, err := r.resolveExternalDependencies(ctx, claim)
+	if err != nil {
+		t.Fatalf("expected components and platform services to resolve, got %v", err)
+	}
+	if !reflect.DeepEqual(ready, map[string]bool{"postgres": true, "redis": false}) {
+		t.Errorf("expected ready postgres and progressing redis, got %v", ready)
+	}
+
+	claim.Spec.Environment = "qa"
+	if _, err := r.resolveExternalDependencies(ctx, claim); err == nil || !strings.Contains(err.Error(), "postgres") {
+		t.Errorf("expected postgres of another environment not to resolve, got %v", err)
+	}
+}
+
+func TestHeldApplications(t *testing.T) {
+	claim := newDependencyClaim()
+	waves, err := applicationWaves(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// user-service waits for redis, order-service for user-service
+	held := heldApplications(claim, waves, map[string]bool{"postgres": true, "redis": false})
+	if len(held) != 2 || held["user-service"] != "waiting for redis" || held["order-service"] != "waiting for user-service" {
+		t.Errorf("unexpected held applications %v", held)
+	}
+	if held := heldApplications(claim, waves, map[string]bool{"postgres": true, "redis": true}); len(held) != 0 {
+		t.Errorf("expected nothing to be held once dependencies are ready, got %v", held)
+	}
+
+	// Applications already deployed are not taken down when a dependency becomes unhealthy
+	claim.Status.Applications = []platformv1.ApplicationStatus{
+		{Name: "user-service", Phase: "Ready"},
+		{Name: "order-service", Phase: applicationPhaseWaitingForDependencies},
+	}
+	held = heldApplications(claim, waves, map[string]bool{"postgres": false, "redis": false})
+	if len(held) != 1 || held["order-service"] != "waiting for postgres" {
+		t.Errorf("expected only the undeployed order-service to be held, got %v", held)
+	}
+
+	// Applications depending only on external services share a wave, so without holding them
+	// nothing would order them after their dependencies
+	external := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{Applications: []platformv1.ApplicationSpec{
+		{Name: "api", Enabled: true, DependsOn: []string{"postgres"}},
+		{Name: "worker", Enabled: true, DependsOn: []string{"postgres", "queue"}},
+	}}}
+	waves, _ = applicationWaves(external)
+	if steps := rollingSyncSteps(waves); steps != nil {
+		t.Errorf("expected a single wave without rolling sync, got %v", steps)
+	}
+	held = heldApplications(external, waves, map[string]bool{"postgres": false, "queue": true})
+	if held["api"] != "waiting for postgres" || held["worker"] != "waiting for postgres" {
+		t.Errorf("expected both applications to wait for postgres, got %v", held)
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+const (
+	// DriftPolicyAlert only reports voltran files changed outside the operator
+	DriftPolicyAlert = "alert"
+
+	// DriftPolicySelfHeal reports and restores voltran files changed outside the operator
+	DriftPolicySelfHeal = "selfHeal"
+
+	// DefaultDriftCheckInterval how often reconciled claims are compared with the voltran branch
+	DefaultDriftCheckInterval = 10 * time.Minute
+
+	// driftedCondition reports voltran files that differ from the files rendered from the claim
+	driftedCondition = "Drifted"
+
+	// maxDriftedPaths number of differing paths listed in the Drifted condition message
+	maxDriftedPaths = 10
+)
+
+// driftCheckInterval returns the requeue interval of reconciled claims
+func driftCheckInterval(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return DefaultDriftCheckInterval
+	}
+	return interval
+}
+
+// renderedHash returns a hash of the files and owned paths of a change
+func renderedHash(change gitOpsChange) string {
+	paths := make([]string, 0, len(change.Files))
+	for path := range change.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s\x00%d\x00%s", path, len(change.Files[path]), change.Files[path])
+	}
+	owned := append([]string(nil), change.Owned...)
+	sort.Strings(owned)
+	fmt.Fprintf(h, "owned\x00%s", strings.Join(owned, "\x00"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// syncDirect pushes the generated files of a claim straight to the voltran branch and returns the
+// commit holding them. When the files rendered for the claim generation were already pushed, the
+// branch is compared with them first: differences are drift (hand edits), which is reported in the
+// Drifted condition and only restored with the selfHeal policy. Files the operator renders
+// differently at the same generation, e.g. after an upgrade or a defaults change, are pushed.
+func syncDirect(ctx context.Context, provider gitprovider.GitProvider, claim metav1.Object, observedGeneration int64, lastCommit, lastRendered, policy string, change gitOpsChange, conditions *claimConditions) (string, error) {
+	logger := log.FromContext(ctx)
+	repoURL := provider.ConstructCloneURL(change.Organization, change.Repo)
+	commitMsg := change.CommitMsg
+
+	var drifted []string
+	pushed := claim.GetGeneration() == observedGeneration && lastCommit != ""
+	if pushed && lastRendered != renderedHash(change) {
+		logger.Info("Operator renders the pushed generation differently, pushing the files", "generation", observedGeneration)
+		pushed = false
+	}
+	if pushed {
+		paths, head, err := provider.DiffFiles(ctx, repoURL, change.Branch, change.Owned, change.Files)
+		if err != nil {
+			return "", err
+		}
+		if len(paths) == 0 {
+			setDriftedCondition(conditions, nil, false)
+			return head, nil
+		}
+
+		logger.Info("Voltran files drifted from the claim", "paths", paths, "policy", policy)
+		if policy == DriftPolicyAlert {
+			setDriftedCondition(conditions, paths, false)
+			return lastCommit, nil
+		}
+		drifted = paths
+		commitMsg = fmt.Sprintf("Restore drifted files of %s/%s by operator", claim.GetNamespace(), claim.GetName())
+	}
+
+	sha, err := provider.SyncFiles(ctx, repoURL, change.Branch, change.Owned, change.Files, commitMsg,
+		"Platform Operator", "operator@platform.local")
+	if err != nil {
+		return "", err
+	}
+	setDriftedCondition(conditions, drifted, drifted != nil)
+	return sha, nil
+}
+
+// reportDrift compares the voltran branch with the files of an already merged claim generation in
+// pullRequest mode and reports differences in the Drifted condition; restoring them needs a
+// reviewed change, so drift is never healed here
+func reportDrift(ctx context.Context, provider gitprovider.GitProvider, claim metav1.Object, observedGeneration int64, change gitOpsChange, conditions *claimConditions) error {
+	if claim.GetGeneration() != observedGeneration {
+		return nil
+	}
+	repoURL := provider.ConstructCloneURL(change.Organization, change.Repo)
+	paths, _, err := provider.DiffFiles(ctx, repoURL, change.Branch, change.Owned, change.Files)
+	if err != nil {
+		return err
+	}
+	if len(paths) > 0 {
+		log.FromContext(ctx).Info("Voltran files drifted from the claim", "paths", paths, "policy", DriftPolicyAlert)
+	}
+	setDriftedCondition(conditions, paths, false)
+	return nil
+}
+
+// setDriftedCondition records the paths that differ from the generated files; restored reports
+// that they were pushed again
+func setDriftedCondition(conditions *claimConditions, paths []string, restored bool) {
+	switch {
+	case len(paths) == 0:
+		conditions.set(driftedCondition, metav1.ConditionFalse, ReasonInSync, "voltran files match the claim")
+	case restored:
+		conditions.set(driftedCondition, metav1.ConditionFalse, ReasonDriftCorrected, "restored files changed outside the operator: "+listPaths(paths))
+	default:
+		conditions.set(driftedCondition, metav1.ConditionTrue, ReasonDriftDetected, "files changed outside the operator: "+listPaths(paths))
+	}
+}
+
+// listPaths joins paths for a condition message, shortening long lists
+func listPaths(paths []string) string {
+	if len(paths) <= maxDriftedPaths {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:maxDriftedPaths], ", "), len(paths)-maxDriftedPaths)
+}
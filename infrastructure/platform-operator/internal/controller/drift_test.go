@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/pkg/gitea"
+)
+
+// newVoltranRepo creates the bare repository acme/voltran.git seeded with files on branch main
+// and returns the base URL a gitea.Client clones it from
+func newVoltranRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	base := t.TempDir()
+	bare := filepath.Join(base, "acme", "voltran.git")
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatal(err)
+	}
+
+	work := t.TempDir()
+	repo, err := git.PlainInit(work, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))); err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(work, path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(work, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@local", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+	return base
+}
+
+func TestSyncDirectDrift(t *testing.T) {
+	generated := map[string]string{"apps/api/values.yaml": "replicaCount: 2\n"}
+	giteaClient := gitea.NewClient(newVoltranRepo(t, generated), "", "")
+	repoURL := giteaClient.ConstructCloneURL("acme", "voltran")
+	ctx := context.Background()
+
+	claim := &platformv1.ApplicationClaim{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "team-a", Generation: 1}}
+	change := gitOpsChange{Organization: "acme", Repo: "voltran", Branch: "main", Owned: []string{"apps"}, Files: generated, CommitMsg: "update"}
+	rendered := renderedHash(change)
+	var conditions []metav1.Condition
+	claimConditions := newClaimConditions(claim, &conditions, nil)
+
+	// Someone edits a generated file and adds one next to it by hand
+	if _, err := giteaClient.PushFiles(ctx, repoURL, "main",
+		map[string]string{"apps/api/values.yaml": "replicaCount: 5\n", "apps/api/extra.yaml": "x: 1\n"},
+		"hand edit", "someone", "someone@local"); err != nil {
+		t.Fatal(err)
+	}
+
+	sha, err := syncDirect(ctx, giteaClient, claim, 1, "seed", rendered, DriftPolicyAlert, change, claimConditions)
+	if err != nil || sha != "seed" {
+		t.Fatalf("expected the alert policy to keep the last commit, got %q, %v", sha, err)
+	}
+	c := meta.FindStatusCondition(conditions, driftedCondition)
+	if c == nil || c.Status != metav1.ConditionTrue || c.Message != "files changed outside the operator: apps/api/extra.yaml, apps/api/values.yaml" {
+		t.Errorf("unexpected Drifted condition %+v", c)
+	}
+	if files, _ := giteaClient.CloneAndExtractFiles(ctx, repoURL, "main", ""); files["apps/api/values.yaml"] != "replicaCount: 5\n" {
+		t.Errorf("expected the alert policy to leave the branch alone, got %v", files)
+	}
+
+	if _, err := syncDirect(ctx, giteaClient, claim, 1, "seed", rendered, DriftPolicySelfHeal, change, claimConditions); err != nil {
+		t.Fatal(err)
+	}
+	c = meta.FindStatusCondition(conditions, driftedCondition)
+	if c == nil || c.Status != metav1.ConditionFalse || c.Reason != ReasonDriftCorrected {
+		t.Errorf("unexpected Drifted condition after self-healing %+v", c)
+	}
+	files, _ := giteaClient.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	if files["apps/api/values.yaml"] != "replicaCount: 2\n" || files["apps/api/extra.yaml"] != "" {
+		t.Errorf("expected the generated files to be restored, got %v", files)
+	}
+
+	if _, err := syncDirect(ctx, giteaClient, claim, 1, "seed", rendered, DriftPolicySelfHeal, change, claimConditions); err != nil {
+		t.Fatal(err)
+	}
+	if c := meta.FindStatusCondition(conditions, driftedCondition); c == nil || c.Reason != ReasonInSync {
+		t.Errorf("expected the claim to be in sync, got %+v", c)
+	}
+
+	// The operator renders the same generation differently, e.g. after an upgrade
+	change.Files = map[string]string{"apps/api/values.yaml": "replicaCount: 2\nrevisionHistoryLimit: 3\n"}
+	if _, err := syncDirect(ctx, giteaClient, claim, 1, "seed", rendered, DriftPolicyAlert, change, claimConditions); err != nil {
+		t.Fatal(err)
+	}
+	files, _ = giteaClient.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	if files["apps/api/values.yaml"] != "replicaCount: 2\nrevisionHistoryLimit: 3\n" {
+		t.Errorf("expected the re-rendered files to be pushed with the alert policy, got %v", files)
+	}
+	rendered = renderedHash(change)
+
+	// A new generation is pushed whatever the policy
+	claim.Generation = 2
+	change.Files = map[string]string{"apps/api/values.yaml": "replicaCount: 3\n"}
+	if _, err := syncDirect(ctx, giteaClient, claim, 1, "seed", rendered, DriftPolicyAlert, change, claimConditions); err != nil {
+		t.Fatal(err)
+	}
+	files, _ = giteaClient.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	if files["apps/api/values.yaml"] != "replicaCount: 3\n" {
+		t.Errorf("expected the new generation to be pushed, got %v", files)
+	}
+}
+
+func TestListPaths(t *testing.T) {
+	var paths []string
+	for i := 0; i < maxDriftedPaths+2; i++ {
+		paths = append(paths, "p")
+	}
+	if got := listPaths(paths); !strings.HasSuffix(got, "p and 2 more") {
+		t.Errorf("unexpected shortened list %q", got)
+	}
+}
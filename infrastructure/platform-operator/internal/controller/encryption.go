@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"github.com/infraforge/platform-operator/pkg/sops"
+)
+
+const (
+	// helmSecretsKeyFile age key mounted into the ArgoCD repo-server from the
+	// helm-secrets-private-keys Secret
+	helmSecretsKeyFile = "/helm-secrets-private-keys/key.txt"
+
+	// helmSecretsScheme value file scheme letting helm-secrets decrypt a values file with the
+	// mounted age key before Helm reads it
+	helmSecretsScheme = "secrets+age-import://" + helmSecretsKeyFile + "?"
+)
+
+// valueFile returns the ApplicationSet value file reference of a generated values.yaml, read
+// through helm-secrets when generated files are encrypted
+func valueFile(enc *sops.Encryptor, path string) string {
+	if enc == nil {
+		return path
+	}
+	return helmSecretsScheme + path
+}
+
+// encryptionSetupFiles returns the voltran files configuring the sops CLI and ArgoCD for
+// encrypted values, none when encryption is disabled
+func encryptionSetupFiles(enc *sops.Encryptor) map[string]string {
+	if enc == nil {
+		return nil
+	}
+	return map[string]string{
+		".sops.yaml": enc.CreationRules(),
+		"argocd-setup/05-argocd-helm-secrets.yaml": `# ArgoCD configuration for values encrypted with sops
+# Allows helm-secrets value file schemes; the repo-server needs the helm-secrets plugin and the
+# helm-secrets-private-keys Secret mounted at /helm-secrets-private-keys (see README.md)
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: argocd-cm
+  namespace: argocd
+  labels:
+    app.kubernetes.io/name: argocd-cm
+    app.kubernetes.io/part-of: argocd
+data:
+  helm.valuesFileSchemes: secrets+age-import, secrets, https
+`,
+	}
+}
+
+// encryptionManifests lists the argocd-setup manifests of encryptionSetupFiles for the README,
+// empty when encryption is disabled
+func encryptionManifests(enc *sops.Encryptor) string {
+	if enc == nil {
+		return ""
+	}
+	return "- **05-argocd-helm-secrets.yaml**: Lets ArgoCD read values.yaml files encrypted with sops through helm-secrets\n"
+}
+
+// encryptionReadme documents how to decrypt the voltran repository, empty when encryption is disabled
+func encryptionReadme(enc *sops.Encryptor) string {
+	if enc == nil {
+		return ""
+	}
+	return `
+## Encrypted Values
+
+Secret manifests and the sensitive keys of values.yaml files (credentials, Secret data and
+environment variable values) are encrypted with [sops](https://github.com/getsops/sops) for the
+age recipients listed in .sops.yaml. Decrypt them with the operator's age key:
+
+    export SOPS_AGE_KEY_FILE=/path/to/key.txt
+    sops -d argocd-setup/01-repo-secret.yaml | kubectl apply -f -
+    sops -d argocd-setup/02-helm-oci-secret.yaml | kubectl apply -f -
+    sops -d argocd-setup/03-github-token-secret.yaml | kubectl apply -f -
+    kubectl apply -f argocd-setup/04-progressive-syncs.yaml
+    kubectl apply -f argocd-setup/05-argocd-helm-secrets.yaml
+
+ArgoCD decrypts values.yaml files through helm-secrets:
+
+1. Store the age key in the argocd namespace:
+
+    kubectl -n argocd create secret generic helm-secrets-private-keys --from-file=key.txt=/path/to/key.txt
+
+2. Install helm-secrets, sops and age in the argocd-repo-server and mount the
+   helm-secrets-private-keys Secret at /helm-secrets-private-keys
+   (see https://github.com/jkroepke/helm-secrets/wiki/ArgoCD-Integration)
+`
+}
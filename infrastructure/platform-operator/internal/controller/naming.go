@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultApplicationNamespaceTemplate deploys applications into the environment namespace
+	DefaultApplicationNamespaceTemplate = "{env}"
+
+	// DefaultPlatformNamespaceTemplate deploys platform services into <env>-platform
+	DefaultPlatformNamespaceTemplate = "{env}-platform"
+
+	// namespaceLabel is set on every Application generated for a claim with its destination namespace
+	namespaceLabel = "platform.infraforge.io/namespace"
+)
+
+// invalidDNSLabelChars matches characters not allowed in a Kubernetes namespace name
+var invalidDNSLabelChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// namespaceVars values available to namespace templates
+type namespaceVars struct {
+	Team           string
+	Env            string
+	Cluster        string
+	ClaimName      string
+	ClaimNamespace string
+}
+
+// renderNamespace expands a namespace template such as "{team}-{env}" and turns the result
+// into a valid namespace name. Supported placeholders: {team}, {env}, {cluster}, {claim}, {namespace}
+func renderNamespace(template string, vars namespaceVars) string {
+	name := strings.NewReplacer(
+		"{team}", vars.Team,
+		"{env}", vars.Env,
+		"{cluster}", vars.Cluster,
+		"{claim}", vars.ClaimName,
+		"{namespace}", vars.ClaimNamespace,
+	).Replace(template)
+
+	name = invalidDNSLabelChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.Trim(name, "-")
+}
+
+// claimPrecedes reports whether claim a was created before claim b; the older claim keeps
+// a contested namespace or voltran path. Ties are broken by namespace/name
+func claimPrecedes(a, b metav1.Object) bool {
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return a.GetNamespace()+"/"+a.GetName() < b.GetNamespace()+"/"+b.GetName()
+}
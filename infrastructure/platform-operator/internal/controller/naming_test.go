@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+func TestRenderNamespace(t *testing.T) {
+	vars := namespaceVars{Team: "Payments_Core", Env: "dev", Cluster: "nonprod", ClaimName: "shop", ClaimNamespace: "default"}
+
+	cases := map[string]string{
+		"{env}":                   "dev",
+		"{env}-platform":          "dev-platform",
+		"{team}-{env}":            "payments-core-dev",
+		"{cluster}-{claim}-{env}": "nonprod-shop-dev",
+		"-{namespace}-":           "default",
+	}
+	for template, want := range cases {
+		if got := renderNamespace(template, vars); got != want {
+			t.Errorf("renderNamespace(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestApplicationNamespaceAndPaths(t *testing.T) {
+	claim := &platformv1.ApplicationClaim{
+		Spec: platformv1.ApplicationClaimSpec{
+			Environment: "dev",
+			ClusterType: "nonprod",
+			Owner:       platformv1.OwnerSpec{Team: "payments"},
+		},
+	}
+
+	// The default template keeps the bootstrap layout
+	r := &ApplicationClaimGitOpsReconciler{}
+	if ns := r.applicationNamespace(claim); ns != "dev" {
+		t.Errorf("expected default namespace dev, got %s", ns)
+	}
+	if root := r.applicationsRoot(claim); root != "environments/nonprod/dev/applications" {
+		t.Errorf("unexpected applications root %s", root)
+	}
+
+	r.NamespaceTemplate = "{team}-{env}"
+	if ns := r.applicationNamespace(claim); ns != "payments-dev" {
+		t.Errorf("expected templated namespace payments-dev, got %s", ns)
+	}
+	if path := r.appSetPath(claim); path != "appsets/nonprod/apps/payments-dev-appset.yaml" {
+		t.Errorf("unexpected appset path %s", path)
+	}
+	if root := r.applicationsRoot(claim); root != "environments/nonprod/dev/namespaces/payments-dev/applications" {
+		t.Errorf("unexpected applications root %s", root)
+	}
+
+	// Spec.Namespace wins over the template
+	claim.Spec.Namespace = "checkout"
+	if ns := r.applicationNamespace(claim); ns != "checkout" {
+		t.Errorf("expected spec namespace checkout, got %s", ns)
+	}
+}
+
+func TestFindConflictingClaim(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	newClaim := func(name, team string, created time.Time) *platformv1.ApplicationClaim {
+		return &platformv1.ApplicationClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				UID:               types.UID("uid-" + name),
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: platformv1.ApplicationClaimSpec{
+				Environment: "dev",
+				ClusterType: "nonprod",
+				Owner:       platformv1.OwnerSpec{Team: team},
+			},
+		}
+	}
+
+	now := time.Now()
+	payments := newClaim("payments", "payments", now.Add(-time.Hour))
+	search := newClaim("search", "search", now.Add(-time.Minute))
+	late := newClaim("payments-late", "payments", now)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(payments, search, late).Build()
+	ctx := context.Background()
+
+	// With the default template every dev claim shares the dev namespace
+	r := &ApplicationClaimGitOpsReconciler{Client: c, Scheme: scheme}
+	if conflict, err := r.findConflictingClaim(ctx, search); err != nil || conflict == nil || conflict.Name != "payments" {
+		t.Errorf("expected search to conflict with payments, got %v (err %v)", conflict, err)
+	}
+	if conflict, _ := r.findConflictingClaim(ctx, payments); conflict != nil {
+		t.Errorf("expected the oldest claim to keep the namespace, got conflict with %s", conflict.Name)
+	}
+
+	// Per-team namespaces only conflict within a team
+	r.NamespaceTemplate = "{team}-{env}"
+	if conflict, _ := r.findConflictingClaim(ctx, search); conflict != nil {
+		t.Errorf("expected no conflict for search-dev, got %s", conflict.Name)
+	}
+	if conflict, _ := r.findConflictingClaim(ctx, late); conflict == nil || conflict.Name != "payments" {
+		t.Errorf("expected payments-late to conflict with payments, got %v", conflict)
+	}
+}
+
+func TestPlatformNamespaceAndPaths(t *testing.T) {
+	claim := &platformv1.PlatformApplicationClaim{
+		Spec: platformv1.PlatformApplicationClaimSpec{
+			Environment: "dev",
+			ClusterType: "nonprod",
+			Owner:       platformv1.OwnerSpec{Team: "payments"},
+		},
+	}
+
+	r := &PlatformApplicationClaimReconciler{}
+	if ns := r.platformNamespace(claim); ns != "dev-platform" {
+		t.Errorf("expected default namespace dev-platform, got %s", ns)
+	}
+	if path := r.platformAppSetPath(claim); path != "appsets/nonprod/platform/dev-platform-appset.yaml" {
+		t.Errorf("unexpected appset path %s", path)
+	}
+
+	claim.Spec.Namespace = "payments-data"
+	if path := r.platformAppSetPath(claim); path != "appsets/nonprod/platform/payments-data-platform-appset.yaml" {
+		t.Errorf("unexpected appset path %s", path)
+	}
+	if root := r.platformServicesRoot(claim); root != "environments/nonprod/dev/namespaces/payments-data/platform" {
+		t.Errorf("unexpected platform services root %s", root)
+	}
+}
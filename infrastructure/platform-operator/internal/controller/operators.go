@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// servicePhaseWaitingForOperator phase of platform services and claims held back until the
+// operator of their service type is ready
+const servicePhaseWaitingForOperator = "WaitingForOperator"
+
+// customResourceDefinitionGVK is the GroupVersionKind of CustomResourceDefinitions, read as
+// unstructured so that the apiextensions types are not needed
+var customResourceDefinitionGVK = schema.GroupVersionKind{
+	Group:   "apiextensions.k8s.io",
+	Version: "v1",
+	Kind:    "CustomResourceDefinition",
+}
+
+// platformOperator Kubernetes operator a platform service type depends on, installed from a Helm
+// chart into the <Name>-system namespace
+type platformOperator struct {
+	// Name ArgoCD Application and Helm release name
+	Name    string
+	RepoURL string
+	Chart   string
+	Version string
+	// Values Helm values of the release, the chart defaults when nil
+	Values map[string]interface{}
+
+	// CRD custom resource definition the operator installs for the service resources
+	CRD string
+	// Deployment operator Deployment that must be available before services are created
+	Deployment string
+}
+
+// platformOperators operator of every platform service type that needs one
+var platformOperators = map[string]platformOperator{
+	"postgresql": {
+		Name:       "cloudnative-pg",
+		RepoURL:    "https://cloudnative-pg.github.io/charts",
+		Chart:      "cloudnative-pg",
+		Version:    "0.20.0",
+		CRD:        "clusters.postgresql.cnpg.io",
+		Deployment: "cloudnative-pg",
+	},
+	"redis": {
+		// The redis chart renders OT-Container-Kit RedisClusters
+		Name:       "redis-operator",
+		RepoURL:    "https://ot-container-kit.github.io/helm-charts",
+		Chart:      "redis-operator",
+		Version:    "0.15.9",
+		CRD:        "redisclusters.redis.redis.opstreelabs.in",
+		Deployment: "redis-operator",
+	},
+	"rabbitmq": {
+		Name:       "rabbitmq-cluster-operator",
+		RepoURL:    "https://charts.bitnami.com/bitnami",
+		Chart:      "rabbitmq-cluster-operator",
+		Version:    "4.2.0",
+		CRD:        "rabbitmqclusters.rabbitmq.com",
+		Deployment: "rabbitmq-cluster-operator",
+	},
+	"mongodb": {
+		Name:       "mongodb-community-operator",
+		RepoURL:    "https://mongodb.github.io/helm-charts",
+		Chart:      "community-operator",
+		Version:    "0.9.0",
+		Values:     map[string]interface{}{"operator": map[string]interface{}{"watchNamespace": "*"}},
+		CRD:        "mongodbcommunity.mongodbcommunity.mongodb.com",
+		Deployment: "mongodb-kubernetes-operator",
+	},
+	"kafka": {
+		Name:       "strimzi-kafka-operator",
+		RepoURL:    "https://strimzi.io/charts/",
+		Chart:      "strimzi-kafka-operator",
+		Version:    "0.39.0",
+		Values:     map[string]interface{}{"watchAnyNamespace": true},
+		CRD:        "kafkas.kafka.strimzi.io",
+		Deployment: "strimzi-cluster-operator",
+	},
+}
+
+// namespace returns the namespace the operator is installed into
+func (o platformOperator) namespace() string {
+	return o.Name + "-system"
+}
+
+// requiredOperators returns the operators of the enabled services, sorted by name
+func requiredOperators(services []platformv1.PlatformServiceSpec) []platformOperator {
+	seen := map[string]bool{}
+	var operators []platformOperator
+	for _, service := range services {
+		operator, ok := platformOperators[service.Type]
+		if !service.Enabled || !ok || seen[operator.Name] {
+			continue
+		}
+		seen[operator.Name] = true
+		operators = append(operators, operator)
+	}
+	sort.Slice(operators, func(i, j int) bool { return operators[i].Name < operators[j].Name })
+	return operators
+}
+
+// operatorApplicationPath returns the voltran path of an operator's ArgoCD Application; the
+// platform root Application of the cluster type creates it
+func operatorApplicationPath(clusterType string, operator platformOperator) string {
+	return fmt.Sprintf("appsets/%s/platform/operator-%s.yaml", clusterType, operator.Name)
+}
+
+// generateOperatorApplication generates the ArgoCD Application installing an operator
+// CRDs of operators exceed the last-applied annotation limit, so they are applied server side
+func generateOperatorApplication(clusterType string, operator platformOperator) string {
+	source := map[string]interface{}{
+		"repoURL":        operator.RepoURL,
+		"chart":          operator.Chart,
+		"targetRevision": operator.Version,
+	}
+	if operator.Values != nil {
+		source["helm"] = map[string]interface{}{"valuesObject": operator.Values}
+	}
+
+	app := map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata": map[string]interface{}{
+			"name":      operator.Name,
+			"namespace": argoCDNamespace,
+			"labels": map[string]string{
+				"platform.infraforge.io/cluster":  clusterType,
+				"platform.infraforge.io/type":     "operator",
+				"platform.infraforge.io/operator": operator.Name,
+			},
+		},
+		"spec": map[string]interface{}{
+			"project": "default",
+			"source":  source,
+			"destination": map[string]interface{}{
+				"server":    "https://kubernetes.default.svc",
+				"namespace": operator.namespace(),
+			},
+			"syncPolicy": map[string]interface{}{
+				"automated": map[string]interface{}{
+					"prune":    true,
+					"selfHeal": true,
+				},
+				"syncOptions": []string{"CreateNamespace=true", "ServerSideApply=true"},
+			},
+		},
+	}
+
+	data, _ := yaml.Marshal(app)
+	return string(data)
+}
+
+// releasedOperatorPaths returns the voltran paths of the operator Applications of the deleted
+// claim that no other PlatformApplicationClaim of its organization and cluster type requires.
+// Claims being deleted do not count, so that claims deleted together release their operators.
+func releasedOperatorPaths(ctx context.Context, c client.Reader, claim *platformv1.PlatformApplicationClaim) ([]string, error) {
+	claims := &platformv1.PlatformApplicationClaimList{}
+	if err := c.List(ctx, claims); err != nil {
+		return nil, fmt.Errorf("failed to list PlatformApplicationClaims: %w", err)
+	}
+	used := map[string]bool{}
+	for _, other := range claims.Items {
+		if other.UID == claim.UID || other.DeletionTimestamp != nil ||
+			other.Spec.Organization != claim.Spec.Organization || other.Spec.ClusterType != claim.Spec.ClusterType {
+			continue
+		}
+		for _, operator := range requiredOperators(other.Spec.Services) {
+			used[operator.Name] = true
+		}
+	}
+
+	var paths []string
+	for _, operator := range requiredOperators(claim.Spec.Services) {
+		if !used[operator.Name] {
+			paths = append(paths, operatorApplicationPath(claim.Spec.ClusterType, operator))
+		}
+	}
+	return paths, nil
+}
+
+// operatorReadiness reports why an operator cannot serve its services yet: its CRD is not
+// installed or its Deployment is not available. An empty string means it is ready.
+func operatorReadiness(ctx context.Context, c client.Reader, operator platformOperator) (string, error) {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(customResourceDefinitionGVK)
+	if err := c.Get(ctx, types.NamespacedName{Name: operator.CRD}, crd); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("CRD %s is not installed", operator.CRD), nil
+		}
+		return "", fmt.Errorf("failed to get CRD %s: %w", operator.CRD, err)
+	}
+
+	key := types.NamespacedName{Namespace: operator.namespace(), Name: operator.Deployment}
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deployment); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("Deployment %s does not exist", key), nil
+		}
+		return "", fmt.Errorf("failed to get Deployment %s: %w", key, err)
+	}
+	if !deploymentAvailable(deployment) {
+		return fmt.Sprintf("Deployment %s is not available", key), nil
+	}
+	return "", nil
+}
+
+// deploymentAvailable reports whether a Deployment has the Available condition, or available
+// replicas before the controller reports conditions
+func deploymentAvailable(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return deployment.Status.AvailableReplicas > 0
+}
+
+// waitingOperators returns the operators among operators that are not ready, keyed by name with
+// the reason
+func waitingOperators(ctx context.Context, c client.Reader, operators []platformOperator) (map[string]string, error) {
+	waiting := map[string]string{}
+	for _, operator := range operators {
+		reason, err := operatorReadiness(ctx, c, operator)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			waiting[operator.Name] = reason
+		}
+	}
+	return waiting, nil
+}
+
+// describeWaitingOperators lists the waiting operators with their reasons for a status message
+func describeWaitingOperators(waiting map[string]string) string {
+	names := make([]string, 0, len(waiting))
+	for name := range waiting {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	described := make([]string, 0, len(names))
+	for _, name := range names {
+		described = append(described, fmt.Sprintf("%s (%s)", name, waiting[name]))
+	}
+	return strings.Join(described, ", ")
+}
+
+// heldServices returns the enabled services of the claim whose operator is waiting, keyed by name
+// with the reason. Services already rendered stay rendered, so that an operator restart does not
+// prune running databases.
+func heldServices(claim *platformv1.PlatformApplicationClaim, waiting map[string]string) map[string]string {
+	rendered := map[string]bool{}
+	for _, status := range claim.Status.Services {
+		rendered[status.Name] = status.Phase != servicePhaseWaitingForOperator
+	}
+
+	held := map[string]string{}
+	for _, service := range claim.Spec.Services {
+		operator, ok := platformOperators[service.Type]
+		if !service.Enabled || !ok || rendered[service.Name] {
+			continue
+		}
+		if reason, waits := waiting[operator.Name]; waits {
+			held[service.Name] = fmt.Sprintf("waiting for operator %s: %s", operator.Name, reason)
+		}
+	}
+	return held
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+func TestRequiredOperators(t *testing.T) {
+	operators := requiredOperators([]platformv1.PlatformServiceSpec{
+		{Name: "orders-db", Type: "postgresql", Enabled: true},
+		{Name: "cache", Type: "redis", Enabled: true},
+		{Name: "users-db", Type: "postgresql", Enabled: true},
+		{Name: "events", Type: "kafka", Enabled: false},
+		{Name: "search", Type: "elasticsearch", Enabled: true},
+	})
+	if len(operators) != 2 || operators[0].Name != "cloudnative-pg" || operators[1].Name != "redis-operator" {
+		t.Fatalf("expected cloudnative-pg and redis-operator once each, got %+v", operators)
+	}
+
+	app := generateOperatorApplication("prod", operators[0])
+	for _, want := range []string{"name: cloudnative-pg", "namespace: cloudnative-pg-system", "targetRevision: 0.20.0", "ServerSideApply=true"} {
+		if !strings.Contains(app, want) {
+			t.Errorf("expected %q in the operator Application:\n%s", want, app)
+		}
+	}
+	if path := operatorApplicationPath("prod", operators[0]); path != "appsets/prod/platform/operator-cloudnative-pg.yaml" {
+		t.Errorf("unexpected operator Application path %s", path)
+	}
+}
+
+func TestWaitingOperators(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	crd := func(name string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(customResourceDefinitionGVK)
+		obj.SetName(name)
+		return obj
+	}
+	deployment := func(namespace, name string, available corev1.ConditionStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: available},
+			}},
+		}
+	}
+
+	// cloudnative-pg is up, redis-operator is starting, rabbitmq has no CRD and kafka no Deployment
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		crd("clusters.postgresql.cnpg.io"),
+		deployment("cloudnative-pg-system", "cloudnative-pg", corev1.ConditionTrue),
+		crd("redisclusters.redis.redis.opstreelabs.in"),
+		deployment("redis-operator-system", "redis-operator", corev1.ConditionFalse),
+		crd("kafkas.kafka.strimzi.io"),
+	).Build()
+
+	operators := []platformOperator{
+		platformOperators["postgresql"], platformOperators["redis"], platformOperators["rabbitmq"], platformOperators["kafka"],
+	}
+	waiting, err := waitingOperators(context.Background(), c, operators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := waiting["cloudnative-pg"]; ok || len(waiting) != 3 {
+		t.Fatalf("expected every operator but cloudnative-pg to wait, got %v", waiting)
+	}
+	if !strings.Contains(waiting["redis-operator"], "not available") ||
+		!strings.Contains(waiting["rabbitmq-cluster-operator"], "CRD rabbitmqclusters.rabbitmq.com") ||
+		!strings.Contains(waiting["strimzi-kafka-operator"], "does not exist") {
+		t.Errorf("unexpected reasons %v", waiting)
+	}
+
+	claim := &platformv1.PlatformApplicationClaim{
+		Spec: platformv1.PlatformApplicationClaimSpec{Services: []platformv1.PlatformServiceSpec{
+			{Name: "orders-db", Type: "postgresql", Enabled: true},
+			{Name: "cache", Type: "redis", Enabled: true},
+			{Name: "sessions", Type: "redis", Enabled: true},
+			{Name: "queue", Type: "rabbitmq", Enabled: true},
+		}},
+		Status: platformv1.PlatformApplicationClaimStatus{Services: []platformv1.PlatformServiceStatus{
+			{Name: "cache", Phase: "Ready"},
+			{Name: "queue", Phase: servicePhaseWaitingForOperator},
+		}},
+	}
+	held := heldServices(claim, waiting)
+	if len(held) != 2 || held["sessions"] == "" || held["queue"] == "" {
+		t.Errorf("expected the new redis and the waiting rabbitmq services to be held, got %v", held)
+	}
+}
+
+func TestReleasedOperatorPaths(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	now := metav1.Now()
+	newClaim := func(name, clusterType string, deleting bool, serviceTypes ...string) *platformv1.PlatformApplicationClaim {
+		claim := &platformv1.PlatformApplicationClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", UID: types.UID(name)},
+			Spec:       platformv1.PlatformApplicationClaimSpec{Organization: "acme", ClusterType: clusterType},
+		}
+		if deleting {
+			claim.DeletionTimestamp = &now
+			claim.Finalizers = []string{platformClaimFinalizer}
+		}
+		for _, serviceType := range serviceTypes {
+			claim.Spec.Services = append(claim.Spec.Services, platformv1.PlatformServiceSpec{Name: serviceType, Type: serviceType, Enabled: true})
+		}
+		return claim
+	}
+
+	deleted := newClaim("shop", "nonprod", true, "postgresql", "redis", "kafka")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		deleted,
+		// postgresql is still used, redis only by another cluster type and a claim being deleted
+		newClaim("billing", "nonprod", false, "postgresql"),
+		newClaim("billing-prod", "prod", false, "redis"),
+		newClaim("legacy", "nonprod", true, "redis"),
+	).Build()
+
+	paths, err := releasedOperatorPaths(context.Background(), c, deleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"appsets/nonprod/platform/operator-redis-operator.yaml", "appsets/nonprod/platform/operator-strimzi-kafka-operator.yaml"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %v to be released, got %v", want, paths)
+	}
+}
@@ -7,18 +7,30 @@ import (
 
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	platformv1 "github.com/infraforge/platform-operator/api/v1"
-	"github.com/infraforge/platform-operator/pkg/gitea"
+	"github.com/infraforge/platform-operator/internal/defaults"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+	"github.com/infraforge/platform-operator/pkg/sops"
 )
 
+// platformClaimFinalizer guards removal of the GitOps files generated for a PlatformApplicationClaim
+const platformClaimFinalizer = "platform.infraforge.io/gitops-cleanup"
+
+// platformClaimSteps conditions a PlatformApplicationClaim goes through before it is Ready
+var platformClaimSteps = []string{ConditionRendered, ConditionGitPushed, ConditionOperatorsReady, ConditionSynced, ConditionHealthy}
+
 // PlatformApplicationClaimReconciler reconciles a PlatformApplicationClaim object
 type PlatformApplicationClaimReconciler struct {
 	client.Client
@@ -29,11 +41,39 @@ type PlatformApplicationClaimReconciler struct {
 	GiteaToken    string
 	VoltranRepo   string
 	Branch        string
+
+	// NamespaceTemplate destination namespace of claims without Spec.Namespace (e.g. "{team}-{env}-platform")
+	NamespaceTemplate string
+
+	// PullRequestClusterTypes cluster types whose claims are applied through voltran pull requests
+	// unless Spec.GitOpsMode says otherwise
+	PullRequestClusterTypes []string
+
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+	// DriftCheckInterval how often reconciled claims are compared with the voltran branch,
+	// DefaultDriftCheckInterval when zero
+	DriftCheckInterval time.Duration
+
+	// Encryptor encrypts the sensitive values of generated files with sops, nil to commit them in plain text
+	Encryptor *sops.Encryptor
+
+	// Recorder emits Events on claims, none when nil
+	Recorder record.EventRecorder
+
+	// APIReader reads credentials Secrets from the API server so that not every Secret of the
+	// cluster is cached; the client when nil
+	APIReader client.Reader
 }
 
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=platformapplicationclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=platformapplicationclaims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=platform.infraforge.io,resources=platformapplicationclaims/finalizers,verbs=update
+//+kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
 
 // Reconcile handles PlatformApplicationClaim reconciliation
 // This will process platform services like PostgreSQL, Redis, RabbitMQ, etc.
@@ -51,6 +91,20 @@ func (r *PlatformApplicationClaimReconciler) Reconcile(ctx context.Context, req
 		return ctrl.Result{}, err
 	}
 
+	// Handle deletion - remove generated files from Git before releasing the claim
+	if !claim.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, claim)
+	}
+
+	// Ensure finalizer is present so deletion cleans up the voltran repository
+	if !controllerutil.ContainsFinalizer(claim, platformClaimFinalizer) {
+		controllerutil.AddFinalizer(claim, platformClaimFinalizer)
+		if err := r.Update(ctx, claim); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	// Initialize status if needed
 	if claim.Status.Phase == "" {
 		claim.Status.Phase = "Pending"
@@ -61,17 +115,53 @@ func (r *PlatformApplicationClaimReconciler) Reconcile(ctx context.Context, req
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Skip if already ready
-	if claim.Status.Phase == "Ready" && claim.Status.Ready {
-		return ctrl.Result{}, nil
+	// Refuse to overwrite the namespace or voltran files of an older claim
+	conflict, err := r.findConflictingClaim(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conflict != nil {
+		logger.Info("Refusing conflicting PlatformApplicationClaim", "conflictsWith", conflict.Namespace+"/"+conflict.Name)
+		claim.Status.Phase = "Failed"
+		claim.Status.Ready = false
+		claim.Status.Message = fmt.Sprintf("namespace %s or its GitOps paths are already used by PlatformApplicationClaim %s/%s",
+			r.platformNamespace(claim), conflict.Namespace, conflict.Name)
+		claim.Status.LastUpdated = metav1.Now()
+		conditions := r.conditions(claim)
+		conditions.set(ConditionRendered, metav1.ConditionFalse, ReasonConflict, claim.Status.Message)
+		conditions.setReady(platformClaimSteps...)
+		if err := r.Status().Update(ctx, claim); err != nil {
+			return ctrl.Result{}, err
+		}
+		// Retry in case the other claim goes away
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
 	}
 
-	// Create GiteaClient dynamically from claim
-	giteaClient := gitea.NewClient(claim.Spec.GiteaURL, r.GiteaUsername, r.GiteaToken)
+	// Always reconcile, also Ready claims of a pushed generation: their voltran files are compared
+	// with the claim for drift and their status is refreshed from ArgoCD
+
+	// Fill unset fields the same way the defaulting webhook does
+	defaults.OrBuiltin(r.Defaults).ApplyPlatformApplicationClaim(claim)
+
+	// Services whose operator is not up yet are held back, so that ArgoCD does not sync custom
+	// resources before their CRDs exist
+	operators := requiredOperators(claim.Spec.Services)
+	waiting, err := waitingOperators(ctx, r.Client, operators)
+	if err != nil {
+		logger.Error(err, "failed to check operators")
+		return ctrl.Result{}, err
+	}
+	held := heldServices(claim, waiting)
+	if len(waiting) > 0 {
+		logger.Info("Waiting for operators", "operators", describeWaitingOperators(waiting), "heldServices", len(held))
+	}
 
-	// Skip operator installation check - operators are already installed
-	// This was causing an infinite loop because isOperatorInstalled wasn't working correctly
-	logger.Info("Skipping operator installation check - assuming operators are already installed")
+	// Git provider of the claim, with the credentials of its Secret
+	provider, err := r.gitProvider(ctx, claim)
+	if err != nil {
+		logger.Error(err, "failed to set up Git provider")
+		return r.updateStatusPushFailed(ctx, claim, held, ReasonInvalidCredentials, err)
+	}
 
 	// Generate ApplicationSet and values.yaml for platform services
 	logger.Info("Generating platform ApplicationSet and values", "environment", claim.Spec.Environment)
@@ -79,11 +169,20 @@ func (r *PlatformApplicationClaimReconciler) Reconcile(ctx context.Context, req
 	files := make(map[string]string)
 
 	// Generate ApplicationSet for platform services
-	appSetPath := fmt.Sprintf("appsets/%s/platform/%s-platform-appset.yaml", claim.Spec.ClusterType, claim.Spec.Environment)
-	appSetContent := r.generatePlatformApplicationSet(claim, giteaClient)
+	appSetPath := r.platformAppSetPath(claim)
+	appSetContent := r.generatePlatformApplicationSet(claim, provider, held)
 	files[appSetPath] = appSetContent
 	logger.Info("Generated platform ApplicationSet content", "path", appSetPath, "length", len(appSetContent))
 
+	// Keep the platform directory itself; everything else under it is owned by the claim
+	files[r.platformServicesRoot(claim)+"/.gitkeep"] = ""
+
+	// Operators are shared by the claims of the cluster type: never owned, only removed with the
+	// last claim using them
+	for _, operator := range operators {
+		files[operatorApplicationPath(claim.Spec.ClusterType, operator)] = generateOperatorApplication(claim.Spec.ClusterType, operator)
+	}
+
 	// Generate values.yaml for each service
 	enabledCount := 0
 	for _, service := range claim.Spec.Services {
@@ -93,29 +192,77 @@ func (r *PlatformApplicationClaimReconciler) Reconcile(ctx context.Context, req
 			continue
 		}
 		enabledCount++
+		if reason, ok := held[service.Name]; ok {
+			logger.Info("Holding back platform service", "name", service.Name, "reason", reason)
+			continue
+		}
 
-		valuesPath := fmt.Sprintf("environments/%s/%s/platform/%s/values.yaml", claim.Spec.ClusterType, claim.Spec.Environment, service.Name)
-		valuesContent := r.generatePlatformValuesYAML(claim, service, giteaClient)
+		valuesPath := r.platformServiceDir(claim, service.Name) + "/values.yaml"
+		valuesContent := r.generatePlatformValuesYAML(claim, service, provider)
 		files[valuesPath] = valuesContent
 		logger.Info("Generated platform service files", "service", service.Name, "valuesPath", valuesPath)
 	}
 
 	logger.Info("Total platform files to push", "fileCount", len(files), "enabledServices", enabledCount)
+	conditions := r.conditions(claim)
+	conditions.set(ConditionRendered, metav1.ConditionTrue, ReasonRendered,
+		fmt.Sprintf("rendered %d files for %d services", len(files), enabledCount))
 
 	// Push to Gitea - use internal clone URL
-	voltranURL := giteaClient.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo)
+	voltranURL := provider.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo)
 	commitMsg := fmt.Sprintf("Update %s environment platform services by operator", claim.Spec.Environment)
+	owned := r.ownedPaths(claim)
+
+	change := gitOpsChange{
+		Organization: claim.Spec.Organization,
+		Repo:         r.VoltranRepo,
+		Branch:       r.Branch,
+		Owned:        owned,
+		Files:        files,
+		CommitMsg:    commitMsg,
+		Title: fmt.Sprintf("Update %s platform services of PlatformApplicationClaim %s/%s",
+			claim.Spec.Environment, claim.Namespace, claim.Name),
+	}
 
-	logger.Info("Pushing platform files to Gitea", "url", voltranURL, "branch", r.Branch, "commitMsg", commitMsg)
-
-	if err := giteaClient.PushFiles(ctx, voltranURL, r.Branch, files, commitMsg,
-		"Platform Operator", "operator@platform.local"); err != nil {
-		logger.Error(err, "failed to push to Git", "url", voltranURL)
-		// Don't update status on git errors, just retry
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	var sha string
+	if usePullRequest(claim.Spec.GitOpsMode, claim.Spec.ClusterType, r.PullRequestClusterTypes) {
+		// Changes reach the branch through a reviewed pull request
+		pr, mergedSHA, err := syncPullRequest(ctx, provider, claim, claim.Status.PullRequest, change)
+		if err != nil {
+			logger.Error(err, "failed to sync pull request", "url", voltranURL)
+			return r.updateStatusPushFailed(ctx, claim, held, ReasonPushFailed, err)
+		}
+		claim.Status.PullRequest = pr
+		setPullRequestCondition(&claim.Status.Conditions, pr, claim.Generation)
+		if pr != nil && pr.State != pullRequestMerged {
+			return r.updateStatusPullRequest(ctx, claim, held)
+		}
+		sha = mergedSHA
+		if sha == "" {
+			sha = claim.Status.LastCommit
+		}
+		if err := reportDrift(ctx, provider, claim, claim.Status.ObservedGeneration, change, conditions); err != nil {
+			logger.Error(err, "failed to check drift", "url", voltranURL)
+			return r.updateStatusPushFailed(ctx, claim, held, ReasonPushFailed, err)
+		}
+	} else {
+		logger.Info("Pushing platform files to Gitea", "url", voltranURL, "branch", r.Branch, "commitMsg", commitMsg)
+
+		// Sync prunes services that were removed from the claim or disabled
+		pushed, err := syncDirect(ctx, provider, claim, claim.Status.ObservedGeneration, claim.Status.LastCommit,
+			claim.Status.RenderedHash, claim.Spec.DriftPolicy, change, conditions)
+		if err != nil {
+			logger.Error(err, "failed to push to Git", "url", voltranURL)
+			return r.updateStatusPushFailed(ctx, claim, held, ReasonPushFailed, err)
+		}
+		sha = pushed
+		claim.Status.RenderedHash = renderedHash(change)
+		claim.Status.PullRequest = nil
+		setPullRequestCondition(&claim.Status.Conditions, nil, claim.Generation)
 	}
 
-	logger.Info("Successfully pushed platform files to Git")
+	logger.Info("Platform files are on the voltran branch", "commit", sha)
+	conditions.set(ConditionGitPushed, metav1.ConditionTrue, ReasonPushed, fmt.Sprintf("generated files are in voltran commit %s", sha))
 
 	// DISABLED: Direct Application creation - Root Apps will watch ApplicationSets and create them
 	// // Create individual Applications in ArgoCD namespace for platform services
@@ -133,28 +280,279 @@ func (r *PlatformApplicationClaimReconciler) Reconcile(ctx context.Context, req
 	// 	logger.Info("Created Application", "name", service.Name)
 	// }
 
-	// Update status to Ready only if not already ready
-	if claim.Status.Phase != "Ready" || !claim.Status.Ready {
+	// Record the pushed commit; the generation only once no service is held back, so that
+	// releasing held services is pushed rather than taken for drift
+	if len(held) == 0 {
+		claim.Status.ObservedGeneration = claim.Generation
+	}
+	claim.Status.LastCommit = sha
+	claim.Status.CommitURL = provider.CommitURL(claim.Spec.Organization, r.VoltranRepo, sha)
+
+	// Pushed is not deployed - report readiness from the live ArgoCD Applications
+	servicesReady, err := r.refreshServiceStatuses(ctx, claim, held)
+	if err != nil {
+		logger.Error(err, "failed to collect service statuses")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	if len(waiting) == 0 {
+		conditions.set(ConditionOperatorsReady, metav1.ConditionTrue, ReasonOperatorsReady, fmt.Sprintf("%d operators ready", len(operators)))
+	} else {
+		conditions.set(ConditionOperatorsReady, metav1.ConditionFalse, ReasonWaitingForOperator,
+			"waiting for operators: "+describeWaitingOperators(waiting))
+	}
+	conditions.setReady(platformClaimSteps...)
+	claim.Status.Message = ""
+	claim.Status.Ready = servicesReady && len(waiting) == 0
+	switch {
+	case len(waiting) > 0:
+		claim.Status.Phase = servicePhaseWaitingForOperator
+		claim.Status.Message = "waiting for operators: " + describeWaitingOperators(waiting)
+	case servicesReady:
 		claim.Status.Phase = "Ready"
-		claim.Status.Ready = true
-		claim.Status.ServicesReady = true
-		claim.Status.LastUpdated = metav1.Now()
-		if err := r.Status().Update(ctx, claim); err != nil {
-			logger.Error(err, "failed to update status")
-			return ctrl.Result{Requeue: true}, nil
-		}
+	default:
+		claim.Status.Phase = "Provisioning"
+	}
+	claim.Status.LastUpdated = metav1.Now()
+	if err := r.Status().Update(ctx, claim); err != nil {
+		logger.Error(err, "failed to update status")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if !claim.Status.Ready {
+		// Application watch triggers on changes; poll as a safety net while rolling out and
+		// while operators come up, which nothing watches
+		logger.Info("Waiting for operators and platform services to become Synced and Healthy")
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
 	logger.Info("PlatformApplicationClaim reconciliation completed successfully")
+	// Compare the voltran files with the claim again later
+	return ctrl.Result{RequeueAfter: driftCheckInterval(r.DriftCheckInterval)}, nil
+}
+
+// updateStatusPullRequest reports a pull request that is not merged yet; open pull requests are
+// polled, a pull request closed without merging waits for the next claim change. The services
+// deployed from earlier generations are still reported.
+func (r *PlatformApplicationClaimReconciler) updateStatusPullRequest(ctx context.Context, claim *platformv1.PlatformApplicationClaim, held map[string]string) (ctrl.Result, error) {
+	pr := claim.Status.PullRequest
+	if _, err := r.refreshServiceStatuses(ctx, claim, held); err != nil {
+		return ctrl.Result{}, err
+	}
+	claim.Status.Ready = false
+	claim.Status.Message = meta.FindStatusCondition(claim.Status.Conditions, pullRequestCondition).Message
+	claim.Status.LastUpdated = metav1.Now()
+	reason := ReasonAwaitingReview
+	if pr.State == pullRequestClosed {
+		claim.Status.Phase = "Failed"
+		reason = ReasonPullRequestClosed
+	} else {
+		claim.Status.Phase = "AwaitingReview"
+	}
+	conditions := r.conditions(claim)
+	conditions.set(ConditionGitPushed, metav1.ConditionFalse, reason, claim.Status.Message)
+	conditions.setReady(platformClaimSteps...)
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+	if pr.State == pullRequestClosed {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+}
+
+// updateStatusPushFailed reports that the generated files could not reach the voltran branch
+// and retries shortly; the services deployed from earlier pushes are still reported
+func (r *PlatformApplicationClaimReconciler) updateStatusPushFailed(ctx context.Context, claim *platformv1.PlatformApplicationClaim, held map[string]string, reason string, pushErr error) (ctrl.Result, error) {
+	if _, err := r.refreshServiceStatuses(ctx, claim, held); err != nil {
+		return ctrl.Result{}, err
+	}
+	claim.Status.Ready = false
+	claim.Status.Message = "Failed to push to Git: " + pushErr.Error()
+	claim.Status.LastUpdated = metav1.Now()
+	conditions := r.conditions(claim)
+	conditions.set(ConditionGitPushed, metav1.ConditionFalse, reason, pushErr.Error())
+	conditions.setReady(platformClaimSteps...)
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+}
+
+// gitProvider returns the Git provider of the claim, with the credentials of its Secret if it has one
+func (r *PlatformApplicationClaimReconciler) gitProvider(ctx context.Context, claim *platformv1.PlatformApplicationClaim) (gitprovider.GitProvider, error) {
+	reader := client.Reader(r.Client)
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	return claimGitProvider(ctx, reader, claim.Namespace, claim.Spec.CredentialsSecretRef, r.gitConfig(claim))
+}
+
+// gitConfig returns the Git provider configuration of the claim with the operator credentials
+func (r *PlatformApplicationClaimReconciler) gitConfig(claim *platformv1.PlatformApplicationClaim) gitprovider.Config {
+	return gitprovider.Config{
+		Provider: gitProviderOf(claim.Spec.GitProvider, r.Defaults),
+		URL:      claim.Spec.GiteaURL,
+		Username: r.GiteaUsername,
+		Token:    r.GiteaToken,
+		Encoder:  r.Encryptor,
+	}
+}
+
+// conditions returns the condition setter of the claim
+func (r *PlatformApplicationClaimReconciler) conditions(claim *platformv1.PlatformApplicationClaim) *claimConditions {
+	return newClaimConditions(claim, &claim.Status.Conditions, r.Recorder)
+}
+
+// reconcileDelete removes everything the claim generated in the voltran repository and
+// releases the finalizer only after the removal has been pushed
+func (r *PlatformApplicationClaimReconciler) reconcileDelete(ctx context.Context, claim *platformv1.PlatformApplicationClaim) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(claim, platformClaimFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// A claim refused for a conflict never wrote anything; the files belong to the other claim
+	conflict, err := r.findConflictingClaim(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conflict != nil {
+		logger.Info("Skipping GitOps cleanup of conflicting PlatformApplicationClaim", "conflictsWith", conflict.Namespace+"/"+conflict.Name)
+		controllerutil.RemoveFinalizer(claim, platformClaimFinalizer)
+		return ctrl.Result{}, r.Update(ctx, claim)
+	}
+
+	// Remove the platform ApplicationSet and every service directory, keeping the empty environment layout,
+	// and the operators no other claim of the cluster type uses
+	released, err := releasedOperatorPaths(ctx, r.Client, claim)
+	if err != nil {
+		logger.Error(err, "failed to find the operators released by the claim")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	owned := append(r.ownedPaths(claim), released...)
+	files := map[string]string{r.platformServicesRoot(claim) + "/.gitkeep": ""}
+
+	provider, err := r.gitProvider(ctx, claim)
+	if errors.IsNotFound(err) {
+		// The Secret may be deleted together with the namespace; the files belong to the operator
+		logger.Info("Git credentials Secret is gone, removing GitOps files with the operator credentials")
+		provider, err = gitprovider.New(r.gitConfig(claim))
+	}
+	if err != nil {
+		logger.Error(err, "failed to set up Git provider")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	closeOpenPullRequest(ctx, provider, claim.Status.PullRequest, claim.Spec.Organization, r.VoltranRepo)
+	voltranURL := provider.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo)
+	commitMsg := fmt.Sprintf("Remove %s environment platform services of %s/%s by operator",
+		claim.Spec.Environment, claim.Namespace, claim.Name)
+
+	logger.Info("Removing platform GitOps files for deleted claim", "url", voltranURL, "paths", owned)
+
+	if _, err := provider.SyncFiles(ctx, voltranURL, r.Branch, owned, files, commitMsg,
+		"Platform Operator", "operator@platform.local"); err != nil {
+		logger.Error(err, "failed to remove platform GitOps files", "url", voltranURL)
+		// Keep the finalizer until the removal reaches Git
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	controllerutil.RemoveFinalizer(claim, platformClaimFinalizer)
+	if err := r.Update(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("PlatformApplicationClaim GitOps files removed, finalizer released")
 	return ctrl.Result{}, nil
 }
 
+// platformNamespace returns the namespace the claim's platform services are deployed to:
+// Spec.Namespace when set, the operator's namespace template otherwise
+func (r *PlatformApplicationClaimReconciler) platformNamespace(claim *platformv1.PlatformApplicationClaim) string {
+	if claim.Spec.Namespace != "" {
+		return claim.Spec.Namespace
+	}
+	template := r.NamespaceTemplate
+	if template == "" {
+		template = DefaultPlatformNamespaceTemplate
+	}
+	return renderNamespace(template, namespaceVars{
+		Team:           claim.Spec.Owner.Team,
+		Env:            claim.Spec.Environment,
+		Cluster:        claim.Spec.ClusterType,
+		ClaimName:      claim.Name,
+		ClaimNamespace: claim.Namespace,
+	})
+}
+
+// platformScope returns the name the claim's voltran files and Applications are keyed by
+// Claims deploying to the <env>-platform namespace keep the bootstrap layout keyed by environment
+func (r *PlatformApplicationClaimReconciler) platformScope(claim *platformv1.PlatformApplicationClaim) string {
+	namespace := r.platformNamespace(claim)
+	if namespace == claim.Spec.Environment+"-platform" {
+		return claim.Spec.Environment
+	}
+	return namespace
+}
+
+// platformAppSetPath returns the voltran path of the claim's platform ApplicationSet
+func (r *PlatformApplicationClaimReconciler) platformAppSetPath(claim *platformv1.PlatformApplicationClaim) string {
+	return fmt.Sprintf("appsets/%s/platform/%s-platform-appset.yaml", claim.Spec.ClusterType, r.platformScope(claim))
+}
+
+// platformServicesRoot returns the voltran directory owned by the claim for its platform services
+func (r *PlatformApplicationClaimReconciler) platformServicesRoot(claim *platformv1.PlatformApplicationClaim) string {
+	scope := r.platformScope(claim)
+	if scope == claim.Spec.Environment {
+		return fmt.Sprintf("environments/%s/%s/platform", claim.Spec.ClusterType, claim.Spec.Environment)
+	}
+	return fmt.Sprintf("environments/%s/%s/namespaces/%s/platform", claim.Spec.ClusterType, claim.Spec.Environment, scope)
+}
+
+// platformServiceDir returns the voltran directory holding the generated files of a platform service
+func (r *PlatformApplicationClaimReconciler) platformServiceDir(claim *platformv1.PlatformApplicationClaim, serviceName string) string {
+	return r.platformServicesRoot(claim) + "/" + serviceName
+}
+
+// ownedPaths returns every voltran path the claim generates and prunes
+func (r *PlatformApplicationClaimReconciler) ownedPaths(claim *platformv1.PlatformApplicationClaim) []string {
+	return []string{r.platformAppSetPath(claim), r.platformServicesRoot(claim)}
+}
+
+// findConflictingClaim returns an older PlatformApplicationClaim that already deploys to the same
+// namespace or writes the same voltran paths as claim, nil if there is none
+func (r *PlatformApplicationClaimReconciler) findConflictingClaim(ctx context.Context, claim *platformv1.PlatformApplicationClaim) (*platformv1.PlatformApplicationClaim, error) {
+	claims := &platformv1.PlatformApplicationClaimList{}
+	if err := r.List(ctx, claims); err != nil {
+		return nil, fmt.Errorf("failed to list PlatformApplicationClaims: %w", err)
+	}
+
+	namespace := r.platformNamespace(claim)
+	owned := map[string]bool{}
+	for _, path := range r.ownedPaths(claim) {
+		owned[path] = true
+	}
+
+	for i := range claims.Items {
+		other := &claims.Items[i]
+		if other.UID == claim.UID || !claimPrecedes(other, claim) {
+			continue
+		}
+		if r.platformNamespace(other) == namespace {
+			return other, nil
+		}
+		for _, path := range r.ownedPaths(other) {
+			if owned[path] {
+				return other, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 // generatePlatformApplication generates a simple ArgoCD Application manifest for platform services
 func (r *PlatformApplicationClaimReconciler) generatePlatformApplication(claim *platformv1.PlatformApplicationClaim, service platformv1.PlatformServiceSpec) string {
-	chartName := service.Chart.Name
-	if chartName == "" {
-		chartName = service.Type // fallback to type
-	}
+	chartName := defaults.OrBuiltin(r.Defaults).ServiceChart(service.Type, service.Chart.Name)
 
 	// Parse custom values from CRD
 	var customValues map[string]interface{}
@@ -172,12 +570,13 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformApplication(claim *
 		"apiVersion": "argoproj.io/v1alpha1",
 		"kind":       "Application",
 		"metadata": map[string]interface{}{
-			"name":      fmt.Sprintf("%s-%s", service.Name, claim.Spec.Environment),
+			"name":      fmt.Sprintf("%s-%s", service.Name, r.platformScope(claim)),
 			"namespace": "argocd",
 			"labels": map[string]string{
 				"platform.infraforge.io/service":     service.Name,
 				"platform.infraforge.io/environment": claim.Spec.Environment,
 				"platform.infraforge.io/type":        "platform",
+				namespaceLabel:                       r.platformNamespace(claim),
 			},
 		},
 		"spec": map[string]interface{}{
@@ -192,7 +591,7 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformApplication(claim *
 			},
 			"destination": map[string]interface{}{
 				"server":    "https://kubernetes.default.svc",
-				"namespace": claim.Spec.Environment,
+				"namespace": r.platformNamespace(claim),
 			},
 			"syncPolicy": map[string]interface{}{
 				"automated": map[string]interface{}{
@@ -253,46 +652,30 @@ func (r *PlatformApplicationClaimReconciler) createApplication(ctx context.Conte
 }
 
 // generatePlatformApplicationSet generates ArgoCD ApplicationSet for platform services
-func (r *PlatformApplicationClaimReconciler) generatePlatformApplicationSet(claim *platformv1.PlatformApplicationClaim, giteaClient *gitea.Client) string {
+// Held services are left out until their operator is ready
+func (r *PlatformApplicationClaimReconciler) generatePlatformApplicationSet(claim *platformv1.PlatformApplicationClaim, provider gitprovider.GitProvider, held map[string]string) string {
 	// Build list of enabled services with chart mapping
 	var elements []map[string]interface{}
 	for _, service := range claim.Spec.Services {
-		if !service.Enabled {
+		if _, ok := held[service.Name]; !service.Enabled || ok {
 			continue
 		}
 
-		// Map service name to actual chart name
-		chartName := service.Chart.Name
-		if chartName == "" {
-			// Default chart mapping
-			switch service.Type {
-			case "postgresql":
-				chartName = "postgresql"
-			case "redis":
-				chartName = "redis"
-			case "rabbitmq":
-				chartName = "rabbitmq"
-			case "mongodb":
-				chartName = "mongodb"
-			case "kafka":
-				chartName = "kafka"
-			default:
-				chartName = service.Type
-			}
-		}
-
 		elements = append(elements, map[string]interface{}{
 			"name":  service.Name,
-			"chart": chartName,
+			"chart": defaults.OrBuiltin(r.Defaults).ServiceChart(service.Type, service.Chart.Name),
 		})
 	}
 
+	scope := r.platformScope(claim)
+	namespace := r.platformNamespace(claim)
+
 	// Use List Generator with explicit chart mapping
 	appSet := map[string]interface{}{
 		"apiVersion": "argoproj.io/v1alpha1",
 		"kind":       "ApplicationSet",
 		"metadata": map[string]interface{}{
-			"name":      fmt.Sprintf("%s-platform", claim.Spec.Environment),
+			"name":      fmt.Sprintf("%s-platform", scope),
 			"namespace": "argocd",
 			"labels": map[string]string{
 				"platform.infraforge.io/environment": claim.Spec.Environment,
@@ -310,11 +693,12 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformApplicationSet(clai
 			},
 			"template": map[string]interface{}{
 				"metadata": map[string]interface{}{
-					"name": fmt.Sprintf("{{name}}-%s", claim.Spec.Environment),
+					"name": fmt.Sprintf("{{name}}-%s", scope),
 					"labels": map[string]string{
-						"platform.infraforge.io/service": "{{name}}",
-						"platform.infraforge.io/env":     claim.Spec.Environment,
-						"platform.infraforge.io/type":    "platform",
+						serviceLabel:                  "{{name}}",
+						envLabel:                      claim.Spec.Environment,
+						namespaceLabel:                namespace,
+						"platform.infraforge.io/type": "platform",
 					},
 				},
 				"spec": map[string]interface{}{
@@ -327,21 +711,20 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformApplicationSet(clai
 							"targetRevision": "*",
 							"helm": map[string]interface{}{
 								"valueFiles": []string{
-									fmt.Sprintf("$values/environments/%s/%s/platform/{{name}}/values.yaml",
-										claim.Spec.ClusterType, claim.Spec.Environment),
+									valueFile(r.Encryptor, "$values/"+r.platformServicesRoot(claim)+"/{{name}}/values.yaml"),
 								},
 							},
 						},
 						{
 							// Source 2: Values from voltran repository
-							"repoURL":        giteaClient.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo),
+							"repoURL":        provider.ConstructCloneURL(claim.Spec.Organization, r.VoltranRepo),
 							"targetRevision": r.Branch,
 							"ref":            "values",
 						},
 					},
 					"destination": map[string]interface{}{
 						"server":    "https://kubernetes.default.svc",
-						"namespace": fmt.Sprintf("%s-platform", claim.Spec.Environment),
+						"namespace": namespace,
 					},
 					"syncPolicy": map[string]interface{}{
 						"automated": map[string]interface{}{
@@ -359,10 +742,9 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformApplicationSet(clai
 	return string(data)
 }
 
-
 // generatePlatformValuesYAML generates Helm values.yaml for a platform service
 // Since charts are now in Gitea, we just generate values from CRD spec
-func (r *PlatformApplicationClaimReconciler) generatePlatformValuesYAML(claim *platformv1.PlatformApplicationClaim, service platformv1.PlatformServiceSpec, giteaClient *gitea.Client) string {
+func (r *PlatformApplicationClaimReconciler) generatePlatformValuesYAML(claim *platformv1.PlatformApplicationClaim, service platformv1.PlatformServiceSpec, provider gitprovider.GitProvider) string {
 	logger := log.Log.WithName("generatePlatformValuesYAML")
 
 	// Parse custom values from CRD
@@ -378,24 +760,26 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformValuesYAML(claim *p
 		customValues = make(map[string]interface{})
 	}
 
-	// Get storage class from claim or use default
-	storageClass := claim.Spec.StorageClass
-	if storageClass == "" {
-		storageClass = "standard" // Default for Kind cluster
-	}
+	values := buildPlatformServiceValues(service.Name, service.Type, service.Version, claim.Spec.StorageClass, customValues)
 
+	data, _ := yaml.Marshal(values)
+	return string(data)
+}
+
+// buildPlatformServiceValues builds Helm values for a platform service chart from
+// type-specific defaults, deep-merged with the custom values
+// Shared by PlatformApplicationClaim services and ApplicationClaim components; version and
+// storageClass are expected to be defaulted by the caller
+func buildPlatformServiceValues(name, serviceType, version, storageClass string, customValues map[string]interface{}) map[string]interface{} {
 	// Add service-specific defaults
 	values := make(map[string]interface{})
-	values["name"] = service.Name
-	values["type"] = service.Type
+	values["name"] = name
+	values["type"] = serviceType
 
 	// Set default values based on service type
-	switch service.Type {
+	switch serviceType {
 	case "postgresql":
-		values["version"] = service.Version
-		if values["version"] == "" {
-			values["version"] = "15"
-		}
+		values["version"] = version
 		// Structure values properly for the PostgreSQL chart
 		values["postgresql"] = map[string]interface{}{
 			"storage": map[string]interface{}{
@@ -414,10 +798,7 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformValuesYAML(claim *p
 			},
 		}
 	case "redis":
-		values["version"] = service.Version
-		if values["version"] == "" {
-			values["version"] = "7.0"
-		}
+		values["version"] = version
 		// Structure values properly for the Redis chart
 		values["redis"] = map[string]interface{}{
 			"storage": map[string]interface{}{
@@ -450,8 +831,7 @@ func (r *PlatformApplicationClaimReconciler) generatePlatformValuesYAML(claim *p
 		}
 	}
 
-	data, _ := yaml.Marshal(values)
-	return string(data)
+	return values
 }
 
 // mergeDeep recursively merges src into dst
@@ -523,149 +903,12 @@ func (r *PlatformApplicationClaimReconciler) createApplicationSet(ctx context.Co
 	return nil
 }
 
-// detectRequiredOperators detects which operators need to be installed
-func (r *PlatformApplicationClaimReconciler) detectRequiredOperators(claim *platformv1.PlatformApplicationClaim) []string {
-	var operators []string
-	operatorMap := make(map[string]bool)
-
-	for _, service := range claim.Spec.Services {
-		if !service.Enabled {
-			continue
-		}
-
-		var operatorName string
-		switch service.Type {
-		case "postgresql":
-			operatorName = "cloudnative-pg"
-		case "redis":
-			operatorName = "redis-operator"
-		case "rabbitmq":
-			operatorName = "rabbitmq-operator"
-		case "mongodb":
-			operatorName = "mongodb-operator"
-		}
-
-		if operatorName != "" && !operatorMap[operatorName] {
-			operatorMap[operatorName] = true
-			// Check if operator is already installed
-			if !r.isOperatorInstalled(operatorName) {
-				operators = append(operators, operatorName)
-			}
-		}
-	}
-
-	return operators
-}
-
-// isOperatorInstalled checks if an operator is already installed
-func (r *PlatformApplicationClaimReconciler) isOperatorInstalled(operatorName string) bool {
-	// For now, always return true if operators exist in ArgoCD
-	// This is a simplified check - we assume if the ArgoCD Application exists, the operator is installed
-	ctx := context.Background()
-
-	app := &unstructured.Unstructured{}
-	app.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "argoproj.io",
-		Version: "v1alpha1",
-		Kind:    "Application",
-	})
-
-	err := r.Get(ctx, client.ObjectKey{
-		Namespace: "argocd",
-		Name:      operatorName,
-	}, app)
-
-	// If the ArgoCD Application exists, we consider the operator installed
-	return err == nil
-}
-
-// installOperators installs the required operators via ArgoCD
-func (r *PlatformApplicationClaimReconciler) installOperators(ctx context.Context, operators []string) error {
-	logger := log.FromContext(ctx)
-
-	for _, operatorName := range operators {
-		logger.Info("Installing operator via ArgoCD", "operator", operatorName)
-
-		var appManifest string
-		switch operatorName {
-		case "cloudnative-pg":
-			appManifest = r.generateOperatorApplication("cloudnative-pg", "https://cloudnative-pg.github.io/charts", "cloudnative-pg", "1.22.0")
-		case "redis-operator":
-			appManifest = r.generateOperatorApplication("redis-operator", "https://spotahome.github.io/redis-operator", "redis-operator", "3.2.9")
-		}
-
-		if appManifest != "" {
-			if err := r.createOperatorApplication(ctx, appManifest); err != nil {
-				return fmt.Errorf("failed to install %s: %w", operatorName, err)
-			}
-		}
-	}
-
-	return nil
-}
-
-// generateOperatorApplication generates ArgoCD Application for operator installation
-func (r *PlatformApplicationClaimReconciler) generateOperatorApplication(name, repoURL, chart, version string) string {
-	app := map[string]interface{}{
-		"apiVersion": "argoproj.io/v1alpha1",
-		"kind":       "Application",
-		"metadata": map[string]interface{}{
-			"name":      name,
-			"namespace": "argocd",
-			"labels": map[string]string{
-				"platform.infraforge.io/type":     "operator",
-				"platform.infraforge.io/operator": name,
-			},
-		},
-		"spec": map[string]interface{}{
-			"project": "default",
-			"source": map[string]interface{}{
-				"repoURL":        repoURL,
-				"chart":          chart,
-				"targetRevision": version,
-				"helm": map[string]interface{}{
-					"values": "",
-				},
-			},
-			"destination": map[string]interface{}{
-				"server":    "https://kubernetes.default.svc",
-				"namespace": name + "-system",
-			},
-			"syncPolicy": map[string]interface{}{
-				"automated": map[string]interface{}{
-					"prune":    true,
-					"selfHeal": true,
-				},
-				"syncOptions": []string{"CreateNamespace=true"},
-			},
-		},
-	}
-
-	data, _ := yaml.Marshal(app)
-	return string(data)
-}
-
-// createOperatorApplication creates the operator Application in ArgoCD
-func (r *PlatformApplicationClaimReconciler) createOperatorApplication(ctx context.Context, appYAML string) error {
-	obj := &unstructured.Unstructured{}
-	if err := yaml.Unmarshal([]byte(appYAML), &obj.Object); err != nil {
-		return fmt.Errorf("failed to unmarshal Application: %w", err)
-	}
-
-	obj.SetNamespace("argocd")
-
-	// Try to create, if already exists it's fine
-	err := r.Create(ctx, obj)
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return fmt.Errorf("failed to create Application: %w", err)
-	}
-
-	return nil
-}
-
 // SetupWithManager sets up the controller with the Manager
 func (r *PlatformApplicationClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&platformv1.PlatformApplicationClaim{}).
+		Watches(newArgoApplication(),
+			handler.EnqueueRequestsFromMapFunc(r.claimsForService),
+			builder.WithPredicates(argoApplicationStatusChanged())).
 		Complete(r)
 }
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+// newPlatformReconcileFixture returns a reconciler for the PlatformApplicationClaim team-a/shop
+// with a ready cloudnative-pg operator, the Healthy ArgoCD Application of its orders-db service
+// and a local voltran repository
+func newPlatformReconcileFixture(t *testing.T) (*PlatformApplicationClaimReconciler, client.Client, *gitprovider.Local) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	base := newVoltranRepo(t, map[string]string{"README.md": "voltran\n"})
+	claim := &platformv1.PlatformApplicationClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "team-a", Generation: 1, Finalizers: []string{platformClaimFinalizer}},
+		Spec: platformv1.PlatformApplicationClaimSpec{
+			Organization: "acme",
+			Environment:  "dev",
+			ClusterType:  "nonprod",
+			GitProvider:  gitprovider.ProviderGit,
+			GiteaURL:     base,
+			DriftPolicy:  DriftPolicyAlert,
+			Services:     []platformv1.PlatformServiceSpec{{Name: "orders-db", Type: "postgresql", Enabled: true}},
+		},
+		Status: platformv1.PlatformApplicationClaimStatus{Phase: "Pending"},
+	}
+
+	r := &PlatformApplicationClaimReconciler{VoltranRepo: "voltran", Branch: "main"}
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(customResourceDefinitionGVK)
+	crd.SetName(platformOperators["postgresql"].CRD)
+	operator := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cloudnative-pg-system", Name: "cloudnative-pg"},
+		Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+		}},
+	}
+	service := newArgoApplicationFixture("orders-db-dev", "", "dev", "Synced", "Healthy")
+	service.SetLabels(map[string]string{serviceLabel: "orders-db", envLabel: "dev", namespaceLabel: r.platformNamespace(claim)})
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(claim, crd, operator, service).
+		WithStatusSubresource(claim).
+		Build()
+	r.Client, r.Scheme = c, scheme
+	return r, c, gitprovider.NewLocal(base)
+}
+
+func TestPlatformReconcileDetectsDriftOnReadyClaim(t *testing.T) {
+	r, c, provider := newPlatformReconcileFixture(t)
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "team-a", Name: "shop"}
+	repoURL := provider.ConstructCloneURL("acme", "voltran")
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	claim := &platformv1.PlatformApplicationClaim{}
+	_ = c.Get(ctx, key, claim)
+	if claim.Status.Phase != "Ready" || claim.Status.ObservedGeneration != 1 {
+		t.Fatalf("expected the claim to be Ready at generation 1, got %s: %s", claim.Status.Phase, claim.Status.Message)
+	}
+	valuesPath := r.platformServiceDir(claim, "orders-db") + "/values.yaml"
+	files, _ := provider.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	generated := files[valuesPath]
+	if generated == "" {
+		t.Fatalf("expected %s to be pushed, got %v", valuesPath, files)
+	}
+
+	// Someone edits the values of the Ready claim by hand
+	if _, err := provider.PushFiles(ctx, repoURL, "main", map[string]string{valuesPath: "instances: 9\n"},
+		"hand edit", "someone", "someone@local"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Get(ctx, key, claim)
+	if !meta.IsStatusConditionTrue(claim.Status.Conditions, driftedCondition) {
+		t.Errorf("expected the hand edit to be reported as drift, got %+v", meta.FindStatusCondition(claim.Status.Conditions, driftedCondition))
+	}
+	if files, _ := provider.CloneAndExtractFiles(ctx, repoURL, "main", ""); files[valuesPath] != "instances: 9\n" {
+		t.Errorf("expected the alert policy to leave the hand edit alone, got %q", files[valuesPath])
+	}
+
+	// The generation is left alone, so only the drift is pushed
+	claim.Spec.DriftPolicy = DriftPolicySelfHeal
+	if err := c.Update(ctx, claim); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Get(ctx, key, claim)
+	if c := meta.FindStatusCondition(claim.Status.Conditions, driftedCondition); c == nil || c.Reason != ReasonDriftCorrected {
+		t.Errorf("expected the drift to be corrected, got %+v", c)
+	}
+	if files, _ := provider.CloneAndExtractFiles(ctx, repoURL, "main", ""); files[valuesPath] != generated {
+		t.Errorf("expected selfHeal to restore the generated values, got %q", files[valuesPath])
+	}
+}
+
+func TestPlatformReconcileRefreshesReadyClaimStatus(t *testing.T) {
+	r, c, _ := newPlatformReconcileFixture(t)
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "team-a", Name: "shop"}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	claim := &platformv1.PlatformApplicationClaim{}
+	_ = c.Get(ctx, key, claim)
+	if claim.Status.Phase != "Ready" || !meta.IsStatusConditionTrue(claim.Status.Conditions, ConditionHealthy) {
+		t.Fatalf("expected the claim to be Ready, got %s: %s", claim.Status.Phase, claim.Status.Message)
+	}
+
+	// setHealth changes the health of the orders-db Application as ArgoCD reports it
+	setHealth := func(health string) {
+		t.Helper()
+		app := newArgoApplication()
+		if err := c.Get(ctx, types.NamespacedName{Namespace: argoCDNamespace, Name: "orders-db-dev"}, app); err != nil {
+			t.Fatal(err)
+		}
+		_ = unstructured.SetNestedField(app.Object, health, "status", "health", "status")
+		if err := c.Update(ctx, app); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setHealth("Degraded")
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Get(ctx, key, claim)
+	if claim.Status.Ready || claim.Status.Phase != "Provisioning" || claim.Status.Services[0].HealthStatus != "Degraded" {
+		t.Errorf("expected the degraded service to be reported, got %s %+v", claim.Status.Phase, claim.Status.Services)
+	}
+	if c := meta.FindStatusCondition(claim.Status.Conditions, ConditionHealthy); c == nil || c.Status != metav1.ConditionFalse || c.Reason != ReasonDegraded {
+		t.Errorf("expected the Healthy condition to report the degraded service, got %+v", c)
+	}
+
+	// The live state is reported even when the files cannot be pushed
+	setHealth("Healthy")
+	claim.Spec.Organization = "gone"
+	if err := c.Update(ctx, claim); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Get(ctx, key, claim)
+	if meta.IsStatusConditionTrue(claim.Status.Conditions, ConditionGitPushed) || !meta.IsStatusConditionTrue(claim.Status.Conditions, ConditionHealthy) ||
+		claim.Status.Services[0].HealthStatus != "Healthy" {
+		t.Errorf("expected a failed push to still refresh the service status, got %+v %+v", claim.Status.Services, claim.Status.Conditions)
+	}
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// serviceLabel is set on every Application generated from a claim's platform ApplicationSet
+const serviceLabel = "platform.infraforge.io/service"
+
+// collectServiceStatuses builds per-service status entries from the live ArgoCD Applications of
+// the claim and reports whether every enabled service is Synced and Healthy; held services wait
+// for their operator with the reason as message
+func (r *PlatformApplicationClaimReconciler) collectServiceStatuses(ctx context.Context, claim *platformv1.PlatformApplicationClaim, held map[string]string) ([]platformv1.PlatformServiceStatus, bool, error) {
+	argoApps := &unstructured.UnstructuredList{}
+	argoApps.SetGroupVersionKind(argoApplicationGVK.GroupVersion().WithKind("ApplicationList"))
+	if err := r.List(ctx, argoApps,
+		client.InNamespace(argoCDNamespace),
+		client.MatchingLabels{envLabel: claim.Spec.Environment, namespaceLabel: r.platformNamespace(claim)},
+		client.HasLabels{serviceLabel}); err != nil {
+		return nil, false, fmt.Errorf("failed to list ArgoCD Applications: %w", err)
+	}
+
+	byName := make(map[string]*unstructured.Unstructured, len(argoApps.Items))
+	for i := range argoApps.Items {
+		byName[argoApps.Items[i].GetLabels()[serviceLabel]] = &argoApps.Items[i]
+	}
+
+	statuses := []platformv1.PlatformServiceStatus{}
+	allReady := true
+	for _, service := range claim.Spec.Services {
+		if !service.Enabled {
+			continue
+		}
+
+		status := platformv1.PlatformServiceStatus{
+			Name:         service.Name,
+			Type:         service.Type,
+			Version:      service.Version,
+			SyncStatus:   "Unknown",
+			HealthStatus: "Missing",
+			Message:      "ArgoCD Application not created yet",
+		}
+
+		if reason, ok := held[service.Name]; ok {
+			status.Phase = servicePhaseWaitingForOperator
+			status.Message = reason
+		} else if argoApp, ok := byName[service.Name]; ok {
+			status.SyncStatus, status.HealthStatus = argoSyncHealth(argoApp)
+			status.Message, _, _ = unstructured.NestedString(argoApp.Object, "status", "health", "message")
+		}
+
+		status.Ready = status.SyncStatus == "Synced" && status.HealthStatus == "Healthy"
+		if status.Phase == "" && status.Ready {
+			status.Phase = "Ready"
+		} else if status.Phase == "" {
+			status.Phase = "Provisioning"
+		}
+		if !status.Ready {
+			allReady = false
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, allReady, nil
+}
+
+// refreshServiceStatuses records the live ArgoCD state of the claim's services in its status and
+// Synced and Healthy conditions and reports whether every enabled service is Synced and Healthy
+func (r *PlatformApplicationClaimReconciler) refreshServiceStatuses(ctx context.Context, claim *platformv1.PlatformApplicationClaim, held map[string]string) (bool, error) {
+	services, servicesReady, err := r.collectServiceStatuses(ctx, claim, held)
+	if err != nil {
+		return false, err
+	}
+	claim.Status.Services = services
+	claim.Status.ServicesReady = servicesReady
+	r.conditions(claim).setArgoConditions(serviceArgoStates(services))
+	return servicesReady, nil
+}
+
+// claimsForService maps an ArgoCD Application event to the PlatformApplicationClaims that generated it
+func (r *PlatformApplicationClaimReconciler) claimsForService(ctx context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	serviceName, env := labels[serviceLabel], labels[envLabel]
+	if serviceName == "" || env == "" {
+		return nil
+	}
+
+	claims := &platformv1.PlatformApplicationClaimList{}
+	if err := r.List(ctx, claims); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list PlatformApplicationClaims for Application", "application", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, claim := range claims.Items {
+		if claim.Spec.Environment != env || labels[namespaceLabel] != r.platformNamespace(&claim) {
+			continue
+		}
+		for _, service := range claim.Spec.Services {
+			if service.Name == serviceName {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+				})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// serviceArgoStates lists the ArgoCD state of every service of a claim
+func serviceArgoStates(services []platformv1.PlatformServiceStatus) []argoState {
+	states := make([]argoState, 0, len(services))
+	for _, service := range services {
+		states = append(states, argoState{Name: service.Name, SyncStatus: service.SyncStatus, HealthStatus: service.HealthStatus})
+	}
+	return states
+}
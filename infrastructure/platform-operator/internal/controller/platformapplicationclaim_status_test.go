@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+func TestCollectServiceStatuses(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	postgres := newArgoApplicationFixture("postgres-dev", "", "dev", "Synced", "Healthy")
+	postgres.SetLabels(map[string]string{serviceLabel: "postgres", envLabel: "dev", namespaceLabel: "dev-platform"})
+	redis := newArgoApplicationFixture("redis-dev", "", "dev", "Synced", "Degraded")
+	redis.SetLabels(map[string]string{serviceLabel: "redis", envLabel: "dev", namespaceLabel: "dev-platform"})
+	other := newArgoApplicationFixture("redis-dev-other", "", "dev", "Synced", "Healthy")
+	other.SetLabels(map[string]string{serviceLabel: "redis", envLabel: "dev", namespaceLabel: "other-platform"})
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(postgres, redis, other).Build()
+	r := &PlatformApplicationClaimReconciler{Client: c, Scheme: scheme}
+
+	claim := &platformv1.PlatformApplicationClaim{
+		Spec: platformv1.PlatformApplicationClaimSpec{
+			Environment: "dev",
+			Services: []platformv1.PlatformServiceSpec{
+				{Name: "postgres", Type: "postgresql", Enabled: true},
+				{Name: "redis", Type: "redis", Enabled: true},
+				{Name: "queue", Type: "rabbitmq", Enabled: true},
+				{Name: "search", Type: "elasticsearch", Enabled: false},
+			},
+		},
+	}
+
+	statuses, allReady, err := r.collectServiceStatuses(context.Background(), claim, nil)
+	if err != nil {
+		t.Fatalf("collectServiceStatuses failed: %v", err)
+	}
+	if allReady {
+		t.Errorf("expected services not to be ready while redis is degraded")
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses for enabled services, got %d", len(statuses))
+	}
+	if !statuses[0].Ready {
+		t.Errorf("expected postgres to be ready: %+v", statuses[0])
+	}
+	if statuses[1].Ready || statuses[1].HealthStatus != "Degraded" {
+		t.Errorf("expected redis of the claim's namespace to be degraded: %+v", statuses[1])
+	}
+	if statuses[2].SyncStatus != "Unknown" || statuses[2].HealthStatus != "Missing" {
+		t.Errorf("expected queue without Application to be missing: %+v", statuses[2])
+	}
+}
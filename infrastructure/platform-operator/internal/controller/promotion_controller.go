@@ -0,0 +1,311 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+)
+
+const (
+	// maxPromotionHistory number of version changes kept in a Promotion's status
+	maxPromotionHistory = 100
+
+	// promotedCondition reports whether every target of the current generation was promoted
+	promotedCondition = "Promoted"
+)
+
+// PromotionReconciler copies image tags and chart versions from a source ApplicationClaim into
+// the ApplicationClaims of the target environments
+type PromotionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+}
+
+// promotedVersion versions of an application copied into target claims
+type promotedVersion struct {
+	ImageTag     string
+	ChartVersion string
+}
+
+//+kubebuilder:rbac:groups=platform.infraforge.io,resources=promotions,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=platform.infraforge.io,resources=promotions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=platform.infraforge.io,resources=applicationclaims,verbs=get;list;watch;update;patch
+
+// Reconcile promotes the source versions into each target in order, stopping at the first target
+// that waits for approval or fails
+func (r *PromotionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling Promotion", "name", req.Name, "namespace", req.Namespace)
+
+	promotion := &platformv1.Promotion{}
+	if err := r.Get(ctx, req.NamespacedName, promotion); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "unable to fetch Promotion")
+		return ctrl.Result{}, err
+	}
+
+	// A new generation promotes every target again
+	if promotion.Status.ObservedGeneration != promotion.Generation || len(promotion.Status.Targets) != len(promotion.Spec.Targets) {
+		promotion.Status.Targets = make([]platformv1.PromotionTargetStatus, len(promotion.Spec.Targets))
+		for i, target := range promotion.Spec.Targets {
+			promotion.Status.Targets[i] = platformv1.PromotionTargetStatus{Environment: target.Environment, Phase: "Pending"}
+		}
+		promotion.Status.Phase = "Pending"
+		promotion.Status.ObservedGeneration = promotion.Generation
+	}
+
+	if promotion.Status.Phase == "Succeeded" {
+		return ctrl.Result{}, nil
+	}
+
+	source, err := r.findClaim(ctx, promotion.Namespace, promotion.Spec.Source.ClaimName, promotion.Spec.Source.Environment, promotion.Spec.Applications)
+	if err != nil {
+		logger.Info("Promotion source not resolved", "reason", err.Error())
+		// Retry in case the source claim is created or fixed
+		return r.updateStatus(ctx, promotion, "Failed", fmt.Sprintf("source: %v", err), time.Minute)
+	}
+
+	versions, err := r.sourceVersions(source, promotion.Spec.Applications)
+	if err != nil {
+		return r.updateStatus(ctx, promotion, "Failed", fmt.Sprintf("source: %v", err), time.Minute)
+	}
+
+	approved := approvedEnvironments(promotion)
+	for i, target := range promotion.Spec.Targets {
+		status := &promotion.Status.Targets[i]
+		if status.Phase == "Promoted" {
+			continue
+		}
+
+		if target.RequireApproval && !approved[target.Environment] {
+			status.Phase = "AwaitingApproval"
+			status.Message = fmt.Sprintf("add %s to the %s annotation to approve", target.Environment, platformv1.PromotionApprovedAnnotation)
+			// The annotation change triggers the next reconcile
+			return r.updateStatus(ctx, promotion, "AwaitingApproval",
+				fmt.Sprintf("waiting for approval of %s", target.Environment), 0)
+		}
+
+		claim, err := r.findClaim(ctx, promotion.Namespace, target.ClaimName, target.Environment, promotion.Spec.Applications)
+		if err == nil {
+			err = r.promoteTarget(ctx, promotion, source, claim, versions)
+		}
+		if errors.IsConflict(err) {
+			return ctrl.Result{}, err
+		}
+		if err != nil {
+			logger.Info("Promotion target failed", "environment", target.Environment, "reason", err.Error())
+			status.Phase = "Failed"
+			status.Message = err.Error()
+			return r.updateStatus(ctx, promotion, "Failed", fmt.Sprintf("%s: %v", target.Environment, err), time.Minute)
+		}
+
+		now := metav1.Now()
+		status.ClaimName = claim.Name
+		status.Phase = "Promoted"
+		status.Message = ""
+		status.PromotedAt = &now
+		logger.Info("Promoted versions", "environment", target.Environment, "claim", claim.Name)
+	}
+
+	logger.Info("Promotion completed successfully")
+	return r.updateStatus(ctx, promotion, "Succeeded", "", 0)
+}
+
+// findClaim returns the named ApplicationClaim, or the single ApplicationClaim of the environment in the
+// namespace that declares the applications (any application when none are named)
+func (r *PromotionReconciler) findClaim(ctx context.Context, namespace, name, environment string, applications []string) (*platformv1.ApplicationClaim, error) {
+	if name != "" {
+		claim := &platformv1.ApplicationClaim{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, claim); err != nil {
+			return nil, fmt.Errorf("ApplicationClaim %s: %w", name, err)
+		}
+		if environment != "" && claim.Spec.Environment != environment {
+			return nil, fmt.Errorf("ApplicationClaim %s targets environment %s, not %s", name, claim.Spec.Environment, environment)
+		}
+		return claim, nil
+	}
+	if environment == "" {
+		return nil, fmt.Errorf("a claim name or environment is required")
+	}
+
+	claims := &platformv1.ApplicationClaimList{}
+	if err := r.List(ctx, claims, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ApplicationClaims: %w", err)
+	}
+	var matches []*platformv1.ApplicationClaim
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if claim.Spec.Environment == environment && declaresApplications(claim, applications) {
+			matches = append(matches, claim)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no ApplicationClaim for environment %s declares the promoted applications", environment)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, claim := range matches {
+			names[i] = claim.Name
+		}
+		return nil, fmt.Errorf("ApplicationClaims %s all match environment %s, set claimName", strings.Join(names, ", "), environment)
+	}
+}
+
+// sourceVersions returns the effective image tag and chart version of every promoted application
+func (r *PromotionReconciler) sourceVersions(source *platformv1.ApplicationClaim, applications []string) (map[string]promotedVersion, error) {
+	// Promote the values the source actually renders, including defaults
+	effective := source.DeepCopy()
+	defaults.OrBuiltin(r.Defaults).ApplyApplicationClaim(effective)
+
+	wanted := map[string]bool{}
+	for _, name := range applications {
+		wanted[name] = true
+	}
+
+	versions := map[string]promotedVersion{}
+	for _, app := range effective.Spec.Applications {
+		// Without names every enabled application is promoted
+		if (len(wanted) == 0 && !app.Enabled) || (len(wanted) > 0 && !wanted[app.Name]) {
+			continue
+		}
+		versions[app.Name] = promotedVersion{ImageTag: app.Image.Tag, ChartVersion: app.Chart.Version}
+	}
+	for _, name := range applications {
+		if _, ok := versions[name]; !ok {
+			return nil, fmt.Errorf("ApplicationClaim %s does not declare application %s", source.Name, name)
+		}
+	}
+	return versions, nil
+}
+
+// promoteTarget copies the versions into the target claim and records every change in the history
+func (r *PromotionReconciler) promoteTarget(ctx context.Context, promotion *platformv1.Promotion, source, target *platformv1.ApplicationClaim, versions map[string]promotedVersion) error {
+	if source.Name == target.Name {
+		return fmt.Errorf("source and target are the same ApplicationClaim %s", target.Name)
+	}
+	for _, name := range promotion.Spec.Applications {
+		if !declaresApplications(target, []string{name}) {
+			return fmt.Errorf("ApplicationClaim %s does not declare application %s", target.Name, name)
+		}
+	}
+
+	var records []platformv1.PromotionRecord
+	now := metav1.Now()
+	for i := range target.Spec.Applications {
+		app := &target.Spec.Applications[i]
+		version, ok := versions[app.Name]
+		if !ok || (app.Image.Tag == version.ImageTag && app.Chart.Version == version.ChartVersion) {
+			continue
+		}
+		records = append(records, platformv1.PromotionRecord{
+			Time:                 now,
+			Generation:           promotion.Generation,
+			SourceClaim:          source.Name,
+			TargetClaim:          target.Name,
+			Environment:          target.Spec.Environment,
+			Application:          app.Name,
+			ImageTag:             version.ImageTag,
+			PreviousImageTag:     app.Image.Tag,
+			ChartVersion:         version.ChartVersion,
+			PreviousChartVersion: app.Chart.Version,
+		})
+		app.Image.Tag = version.ImageTag
+		app.Chart.Version = version.ChartVersion
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	// Rejections by the validating webhook (e.g. mutable prod tags) fail the target
+	if err := r.Update(ctx, target); err != nil {
+		return err
+	}
+
+	history := append(promotion.Status.History, records...)
+	if len(history) > maxPromotionHistory {
+		history = history[len(history)-maxPromotionHistory:]
+	}
+	promotion.Status.History = history
+	return nil
+}
+
+// updateStatus records the phase and the Promoted condition; a non-zero requeueAfter retries later
+func (r *PromotionReconciler) updateStatus(ctx context.Context, promotion *platformv1.Promotion, phase, message string, requeueAfter time.Duration) (ctrl.Result, error) {
+	promotion.Status.Phase = phase
+	promotion.Status.Message = message
+	promotion.Status.LastUpdated = metav1.Now()
+
+	condition := metav1.Condition{
+		Type:               promotedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             phase,
+		Message:            message,
+		ObservedGeneration: promotion.Generation,
+	}
+	if phase == "Succeeded" {
+		condition.Status = metav1.ConditionTrue
+		condition.Message = "every target environment was promoted"
+	}
+	meta.SetStatusCondition(&promotion.Status.Conditions, condition)
+
+	if err := r.Status().Update(ctx, promotion); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// approvedEnvironments returns the environments listed in the approval annotation
+func approvedEnvironments(promotion *platformv1.Promotion) map[string]bool {
+	approved := map[string]bool{}
+	for _, env := range strings.Split(promotion.Annotations[platformv1.PromotionApprovedAnnotation], ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			approved[env] = true
+		}
+	}
+	return approved
+}
+
+// declaresApplications reports whether the claim declares every named application; any claim
+// with applications matches when no names are given
+func declaresApplications(claim *platformv1.ApplicationClaim, names []string) bool {
+	if len(names) == 0 {
+		return len(claim.Spec.Applications) > 0
+	}
+	for _, name := range names {
+		if !claimGenerates(claim, name, "") {
+			return false
+		}
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *PromotionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&platformv1.Promotion{}, builder.WithPredicates(
+			// Spec changes and approvals; status updates must not retrigger the promotion
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Complete(r)
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// newPromotionClaim returns an ApplicationClaim of the environment running api and web at the given tag
+func newPromotionClaim(name, env, tag string) *platformv1.ApplicationClaim {
+	return &platformv1.ApplicationClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec: platformv1.ApplicationClaimSpec{
+			Environment: env,
+			Applications: []platformv1.ApplicationSpec{
+				{Name: "api", Enabled: true, Image: platformv1.ImageSpec{Repository: "ghcr.io/acme/api", Tag: tag}, Chart: platformv1.ChartSpec{Name: "microservice", Version: "1.0.0"}},
+				{Name: "web", Enabled: true, Image: platformv1.ImageSpec{Repository: "ghcr.io/acme/web", Tag: tag}},
+			},
+		},
+	}
+}
+
+func TestPromotionCopiesVersionsWithApproval(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	dev := newPromotionClaim("shop-dev", "dev", "v2.1.0")
+	dev.Spec.Applications[0].Chart.Version = "1.2.0"
+	staging := newPromotionClaim("shop-staging", "staging", "v2.0.0")
+	prod := newPromotionClaim("shop-prod", "prod", "v1.9.0")
+	promotion := &platformv1.Promotion{
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "shop", Generation: 1},
+		Spec: platformv1.PromotionSpec{
+			Source:       platformv1.PromotionSource{Environment: "dev"},
+			Applications: []string{"api"},
+			Targets: []platformv1.PromotionTarget{
+				{Environment: "staging"},
+				{Environment: "prod", RequireApproval: true},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(dev, staging, prod, promotion).
+		WithStatusSubresource(promotion).
+		Build()
+	r := &PromotionReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "shop", Name: "release"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	got := &platformv1.ApplicationClaim{}
+	_ = c.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "shop-staging"}, got)
+	if api := got.Spec.Applications[0]; api.Image.Tag != "v2.1.0" || api.Chart.Version != "1.2.0" {
+		t.Errorf("expected api to be promoted to staging, got %+v", api)
+	}
+	if web := got.Spec.Applications[1]; web.Image.Tag != "v2.0.0" {
+		t.Errorf("expected web to be left alone, got tag %s", web.Image.Tag)
+	}
+	_ = c.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "shop-prod"}, got)
+	if tag := got.Spec.Applications[0].Image.Tag; tag != "v1.9.0" {
+		t.Errorf("expected prod to wait for approval, got tag %s", tag)
+	}
+
+	_ = c.Get(ctx, req.NamespacedName, promotion)
+	if promotion.Status.Phase != "AwaitingApproval" || promotion.Status.Targets[0].Phase != "Promoted" || promotion.Status.Targets[1].Phase != "AwaitingApproval" {
+		t.Fatalf("unexpected status before approval: %+v", promotion.Status)
+	}
+
+	// Approve prod
+	promotion.Annotations = map[string]string{platformv1.PromotionApprovedAnnotation: "prod"}
+	if err := c.Update(ctx, promotion); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	_ = c.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "shop-prod"}, got)
+	if tag := got.Spec.Applications[0].Image.Tag; tag != "v2.1.0" {
+		t.Errorf("expected api to be promoted to prod after approval, got tag %s", tag)
+	}
+	_ = c.Get(ctx, req.NamespacedName, promotion)
+	if promotion.Status.Phase != "Succeeded" {
+		t.Errorf("expected the promotion to succeed, got %s: %s", promotion.Status.Phase, promotion.Status.Message)
+	}
+	history := promotion.Status.History
+	if len(history) != 2 || history[0].Environment != "staging" || history[1].Environment != "prod" ||
+		history[1].PreviousImageTag != "v1.9.0" || history[1].ImageTag != "v2.1.0" || history[1].SourceClaim != "shop-dev" {
+		t.Errorf("unexpected promotion history: %+v", history)
+	}
+}
+
+func TestPromotionFailsOnAmbiguousTarget(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = platformv1.AddToScheme(scheme)
+
+	promotion := &platformv1.Promotion{
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "shop", Generation: 1},
+		Spec: platformv1.PromotionSpec{
+			Source:  platformv1.PromotionSource{ClaimName: "shop-dev"},
+			Targets: []platformv1.PromotionTarget{{Environment: "qa"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(newPromotionClaim("shop-dev", "dev", "v2"), newPromotionClaim("qa-a", "qa", "v1"), newPromotionClaim("qa-b", "qa", "v1"), promotion).
+		WithStatusSubresource(promotion).
+		Build()
+	r := &PromotionReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "shop", Name: "release"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	_ = c.Get(ctx, req.NamespacedName, promotion)
+	if promotion.Status.Phase != "Failed" || promotion.Status.Targets[0].Phase != "Failed" {
+		t.Errorf("expected an ambiguous target to fail, got %+v", promotion.Status)
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+const (
+	// GitOpsModeDirect pushes generated files straight to the voltran branch
+	GitOpsModeDirect = "direct"
+
+	// GitOpsModePullRequest pushes generated files to a claim branch and opens a pull request
+	GitOpsModePullRequest = "pullRequest"
+
+	// pullRequestCondition reports the voltran pull request of the current claim generation
+	pullRequestCondition = "PullRequest"
+
+	// Pull request states recorded in the claim status
+	pullRequestOpen   = "Open"
+	pullRequestMerged = "Merged"
+	pullRequestClosed = "Closed"
+)
+
+// usePullRequest reports whether changes of a claim go through a pull request: the claim's
+// GitOpsMode when set, otherwise whether its cluster type is one of pullRequestClusterTypes
+func usePullRequest(mode, clusterType string, pullRequestClusterTypes []string) bool {
+	switch mode {
+	case GitOpsModePullRequest:
+		return true
+	case GitOpsModeDirect:
+		return false
+	}
+	for _, t := range pullRequestClusterTypes {
+		if t == clusterType {
+			return true
+		}
+	}
+	return false
+}
+
+// pullRequestBranch returns the voltran branch holding the generated files of one claim generation
+func pullRequestBranch(claim metav1.Object) string {
+	return fmt.Sprintf("claim/%s/%s/%d", claim.GetNamespace(), claim.GetName(), claim.GetGeneration())
+}
+
+// gitOpsChange generated files of a claim generation and the voltran repository they go to
+type gitOpsChange struct {
+	Organization string
+	Repo         string
+	Branch       string
+	Owned        []string
+	Files        map[string]string
+	CommitMsg    string
+	Title        string
+}
+
+// syncPullRequest moves the pull request of the claim's current generation and rendered files
+// forward and returns the pull request to record with the commit holding the generated files once
+// they reached the branch. The first call for a generation, or for files rendered differently at
+// the same generation, closes the superseded pull request, pushes the files to the generation
+// branch and opens a pull request; later calls only refresh its state. A nil pull request means
+// the files already match the branch and nothing needs a review.
+func syncPullRequest(ctx context.Context, provider gitprovider.GitProvider, claim metav1.Object, current *platformv1.PullRequestStatus, change gitOpsChange) (*platformv1.PullRequestStatus, string, error) {
+	logger := log.FromContext(ctx)
+
+	rendered := renderedHash(change)
+	if current != nil && current.Generation == claim.GetGeneration() && current.RenderedHash == rendered {
+		if current.State == pullRequestMerged {
+			return current, "", nil
+		}
+		pr, err := provider.GetPullRequest(ctx, change.Organization, change.Repo, current.Number)
+		if err != nil {
+			return nil, "", err
+		}
+		updated := *current
+		updated.URL = pr.HTMLURL
+		switch {
+		case pr.Merged:
+			updated.State = pullRequestMerged
+		case pr.State == "closed":
+			updated.State = pullRequestClosed
+		default:
+			updated.State = pullRequestOpen
+		}
+		return &updated, pr.MergeCommitSHA, nil
+	}
+
+	// A newer generation or rendering replaces the pull request of the previous one
+	if current != nil && current.State == pullRequestOpen {
+		logger.Info("Closing superseded pull request", "number", current.Number, "generation", current.Generation)
+		if err := provider.ClosePullRequest(ctx, change.Organization, change.Repo, current.Number); err != nil {
+			return nil, "", err
+		}
+	}
+
+	repoURL := provider.ConstructCloneURL(change.Organization, change.Repo)
+	branch := pullRequestBranch(claim)
+	sha, committed, err := provider.SyncFilesToBranch(ctx, repoURL, change.Branch, branch, change.Owned, change.Files,
+		change.CommitMsg, "Platform Operator", "operator@platform.local")
+	if err != nil {
+		return nil, "", err
+	}
+	if !committed {
+		return nil, sha, nil
+	}
+
+	pr, err := provider.CreatePullRequest(ctx, change.Organization, change.Repo, gitprovider.CreatePullRequestOptions{
+		Head:  branch,
+		Base:  change.Branch,
+		Title: change.Title,
+		Body: fmt.Sprintf("Generated by the platform operator from %s/%s generation %d.\n\nMerging applies the change.",
+			claim.GetNamespace(), claim.GetName(), claim.GetGeneration()),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Info("Opened pull request", "number", pr.Number, "url", pr.HTMLURL, "branch", branch)
+
+	return &platformv1.PullRequestStatus{
+		Number:       pr.Number,
+		URL:          pr.HTMLURL,
+		Branch:       branch,
+		State:        pullRequestOpen,
+		Generation:   claim.GetGeneration(),
+		RenderedHash: rendered,
+	}, "", nil
+}
+
+// setPullRequestCondition records the pull request state in conditions; without a pull request
+// the condition is removed
+func setPullRequestCondition(conditions *[]metav1.Condition, pr *platformv1.PullRequestStatus, generation int64) {
+	if pr == nil {
+		meta.RemoveStatusCondition(conditions, pullRequestCondition)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               pullRequestCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             pr.State,
+		ObservedGeneration: generation,
+	}
+	switch pr.State {
+	case pullRequestMerged:
+		condition.Status = metav1.ConditionTrue
+		condition.Message = fmt.Sprintf("pull request #%d was merged: %s", pr.Number, pr.URL)
+	case pullRequestClosed:
+		condition.Message = fmt.Sprintf("pull request #%d was closed without merging, update the claim to open a new one: %s", pr.Number, pr.URL)
+	default:
+		condition.Message = fmt.Sprintf("waiting for pull request #%d to be merged: %s", pr.Number, pr.URL)
+	}
+	meta.SetStatusCondition(conditions, condition)
+}
+
+// closeOpenPullRequest closes the claim's pull request if it is still open so a later merge cannot
+// bring back the files of a deleted claim; failures are only logged
+func closeOpenPullRequest(ctx context.Context, provider gitprovider.GitProvider, pr *platformv1.PullRequestStatus, organization, repo string) {
+	if pr == nil || pr.State != pullRequestOpen {
+		return
+	}
+	if err := provider.ClosePullRequest(ctx, organization, repo, pr.Number); err != nil {
+		log.FromContext(ctx).Error(err, "failed to close pull request", "number", pr.Number)
+	}
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/pkg/gitea"
+	"github.com/infraforge/platform-operator/pkg/gitprovider"
+)
+
+func TestUsePullRequest(t *testing.T) {
+	prodOnly := []string{"prod"}
+	cases := []struct {
+		mode, clusterType string
+		want              bool
+	}{
+		{"", "prod", true},
+		{"", "nonprod", false},
+		{GitOpsModeDirect, "prod", false},
+		{GitOpsModePullRequest, "nonprod", true},
+	}
+	for _, c := range cases {
+		if got := usePullRequest(c.mode, c.clusterType, prodOnly); got != c.want {
+			t.Errorf("usePullRequest(%q, %q) = %v, want %v", c.mode, c.clusterType, got, c.want)
+		}
+	}
+	if usePullRequest("", "prod", nil) {
+		t.Errorf("expected direct pushes without pull request cluster types")
+	}
+}
+
+func TestSyncPullRequestRefreshesState(t *testing.T) {
+	merged := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/acme/voltran/pulls/4" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		pr := gitea.PullRequest{Number: 4, State: "open", HTMLURL: "http://gitea/acme/voltran/pulls/4"}
+		if merged {
+			pr.State, pr.Merged, pr.MergeCommitSHA = "closed", true, "f00d"
+		}
+		_ = json.NewEncoder(w).Encode(pr)
+	}))
+	defer server.Close()
+
+	claim := &platformv1.ApplicationClaim{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "team-a", Generation: 3}}
+	if branch := pullRequestBranch(claim); branch != "claim/team-a/shop/3" {
+		t.Errorf("unexpected pull request branch %s", branch)
+	}
+
+	change := gitOpsChange{Organization: "acme", Repo: "voltran", Branch: "main"}
+	current := &platformv1.PullRequestStatus{Number: 4, Branch: pullRequestBranch(claim), State: pullRequestOpen, Generation: 3, RenderedHash: renderedHash(change)}
+	giteaClient := gitea.NewClient(server.URL, "operator", "token")
+	ctx := context.Background()
+
+	pr, sha, err := syncPullRequest(ctx, giteaClient, claim, current, change)
+	if err != nil || pr.State != pullRequestOpen || sha != "" {
+		t.Fatalf("expected an open pull request, got %+v, %q, %v", pr, sha, err)
+	}
+	var conditions []metav1.Condition
+	setPullRequestCondition(&conditions, pr, claim.Generation)
+	if c := meta.FindStatusCondition(conditions, pullRequestCondition); c == nil || c.Status != metav1.ConditionFalse || c.Reason != pullRequestOpen {
+		t.Errorf("unexpected condition for an open pull request: %+v", c)
+	}
+
+	merged = true
+	pr, sha, err = syncPullRequest(ctx, giteaClient, claim, pr, change)
+	if err != nil || pr.State != pullRequestMerged || sha != "f00d" {
+		t.Fatalf("expected a merged pull request at f00d, got %+v, %q, %v", pr, sha, err)
+	}
+	setPullRequestCondition(&conditions, pr, claim.Generation)
+	if !meta.IsStatusConditionTrue(conditions, pullRequestCondition) {
+		t.Errorf("expected the PullRequest condition to be true once merged")
+	}
+
+	setPullRequestCondition(&conditions, nil, claim.Generation)
+	if len(conditions) != 0 {
+		t.Errorf("expected the condition to be removed without a pull request, got %v", conditions)
+	}
+}
+
+func TestSyncPullRequestMerge(t *testing.T) {
+	provider := gitprovider.NewLocal(newVoltranRepo(t, map[string]string{"apps/api/values.yaml": "replicaCount: 1\n"}))
+	claim := &platformv1.ApplicationClaim{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "team-a", Generation: 2}}
+	change := gitOpsChange{Organization: "acme", Repo: "voltran", Branch: "main", Owned: []string{"apps"},
+		Files: map[string]string{"apps/api/values.yaml": "replicaCount: 3\n"}, CommitMsg: "update", Title: "Update shop"}
+	ctx := context.Background()
+
+	pr, sha, err := syncPullRequest(ctx, provider, claim, nil, change)
+	if err != nil || pr == nil || pr.State != pullRequestOpen || sha != "" {
+		t.Fatalf("expected an open pull request, got %+v, %q, %v", pr, sha, err)
+	}
+	if err := provider.MergePullRequest("acme", "voltran", pr.Number); err != nil {
+		t.Fatal(err)
+	}
+
+	pr, sha, err = syncPullRequest(ctx, provider, claim, pr, change)
+	if err != nil || pr.State != pullRequestMerged || sha == "" {
+		t.Fatalf("expected a merged pull request, got %+v, %q, %v", pr, sha, err)
+	}
+	files, _ := provider.CloneAndExtractFiles(ctx, provider.ConstructCloneURL("acme", "voltran"), "main", "")
+	if files["apps/api/values.yaml"] != "replicaCount: 3\n" {
+		t.Errorf("expected the merged files on main, got %v", files)
+	}
+}
+
+func TestSyncPullRequestReleasesHeldServices(t *testing.T) {
+	provider := gitprovider.NewLocal(newVoltranRepo(t, map[string]string{"README.md": "voltran\n"}))
+	claim := &platformv1.PlatformApplicationClaim{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "team-a", Generation: 1}}
+	// orders-db is held back until its operator is ready
+	change := gitOpsChange{Organization: "acme", Repo: "voltran", Branch: "main", Owned: []string{"platform"},
+		Files: map[string]string{"platform/appset.yaml": "kind: ApplicationSet\n"}, CommitMsg: "update", Title: "Update shop"}
+	repoURL := provider.ConstructCloneURL("acme", "voltran")
+	ctx := context.Background()
+
+	pr, _, err := syncPullRequest(ctx, provider, claim, nil, change)
+	if err != nil || pr == nil {
+		t.Fatalf("expected a pull request, got %v", err)
+	}
+	if err := provider.MergePullRequest("acme", "voltran", pr.Number); err != nil {
+		t.Fatal(err)
+	}
+	if pr, _, err = syncPullRequest(ctx, provider, claim, pr, change); err != nil || pr.State != pullRequestMerged {
+		t.Fatalf("expected a merged pull request, got %+v, %v", pr, err)
+	}
+	first := pr.Number
+
+	// The operator is ready: the same generation renders the released service
+	change.Files = map[string]string{"platform/appset.yaml": "kind: ApplicationSet\n", "platform/orders-db/values.yaml": "instances: 1\n"}
+	pr, _, err = syncPullRequest(ctx, provider, claim, pr, change)
+	if err != nil || pr == nil || pr.Number == first || pr.State != pullRequestOpen || pr.Generation != 1 {
+		t.Fatalf("expected a follow-up pull request for the released service, got %+v, %v", pr, err)
+	}
+	if err := provider.MergePullRequest("acme", "voltran", pr.Number); err != nil {
+		t.Fatal(err)
+	}
+	if pr, _, err = syncPullRequest(ctx, provider, claim, pr, change); err != nil || pr.State != pullRequestMerged {
+		t.Fatalf("expected the follow-up pull request to be merged, got %+v, %v", pr, err)
+	}
+	files, _ := provider.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	if files["platform/orders-db/values.yaml"] != "instances: 1\n" {
+		t.Errorf("expected the released service on main, got %v", files)
+	}
+
+	// Hand edits of merged files are drift, not a reason for another pull request
+	if _, err := provider.PushFiles(ctx, repoURL, "main", map[string]string{"platform/orders-db/values.yaml": "instances: 3\n"},
+		"hand edit", "someone", "someone@local"); err != nil {
+		t.Fatal(err)
+	}
+	merged := pr.Number
+	if pr, _, err = syncPullRequest(ctx, provider, claim, pr, change); err != nil || pr.Number != merged || pr.State != pullRequestMerged {
+		t.Errorf("expected the merged pull request to be kept, got %+v, %v", pr, err)
+	}
+}
+
+func TestSyncPullRequestReleasesHeldApplications(t *testing.T) {
+	provider := gitprovider.NewLocal(newVoltranRepo(t, map[string]string{"README.md": "voltran\n"}))
+	claim := newDependencyClaim()
+	claim.Generation = 1
+	waves, err := applicationWaves(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoURL := provider.ConstructCloneURL("acme", "voltran")
+	ctx := context.Background()
+
+	// change renders the values of every application that is not held
+	change := func(held map[string]string) gitOpsChange {
+		files := map[string]string{}
+		for name := range waves {
+			if _, ok := held[name]; !ok {
+				files["apps/"+name+"/values.yaml"] = "name: " + name + "\n"
+			}
+		}
+		return gitOpsChange{Organization: "acme", Repo: "voltran", Branch: "main", Owned: []string{"apps"},
+			Files: files, CommitMsg: "update", Title: "Update shop"}
+	}
+
+	// redis is not healthy yet, so user-service and order-service are held back
+	held := heldApplications(claim, waves, map[string]bool{"postgres": true, "redis": false})
+	pr, _, err := syncPullRequest(ctx, provider, claim, nil, change(held))
+	if err != nil || pr == nil {
+		t.Fatalf("expected a pull request, got %v", err)
+	}
+	if err := provider.MergePullRequest("acme", "voltran", pr.Number); err != nil {
+		t.Fatal(err)
+	}
+	if pr, _, err = syncPullRequest(ctx, provider, claim, pr, change(held)); err != nil || pr.State != pullRequestMerged {
+		t.Fatalf("expected a merged pull request, got %+v, %v", pr, err)
+	}
+	claim.Status.Applications = []platformv1.ApplicationStatus{
+		{Name: "web", Phase: "Ready"},
+		{Name: "user-service", Phase: applicationPhaseWaitingForDependencies},
+		{Name: "order-service", Phase: applicationPhaseWaitingForDependencies},
+	}
+
+	// redis became healthy within the same generation
+	held = heldApplications(claim, waves, map[string]bool{"postgres": true, "redis": true})
+	first := pr.Number
+	pr, _, err = syncPullRequest(ctx, provider, claim, pr, change(held))
+	if err != nil || pr == nil || pr.Number == first || pr.State != pullRequestOpen {
+		t.Fatalf("expected a follow-up pull request for the released applications, got %+v, %v", pr, err)
+	}
+	if err := provider.MergePullRequest("acme", "voltran", pr.Number); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := provider.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	if files["apps/user-service/values.yaml"] == "" || files["apps/order-service/values.yaml"] == "" {
+		t.Errorf("expected the released applications on main, got %v", files)
+	}
+}
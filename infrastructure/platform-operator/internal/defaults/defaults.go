@@ -0,0 +1,238 @@
+// Package defaults holds the values the operator fills into unset claim fields
+// The defaulting webhook writes them into stored claims; the reconcilers apply the same
+// values in memory so claims admitted without the webhook render identically
+package defaults
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// Defaults operator-wide default values for ApplicationClaim, PlatformApplicationClaim and BootstrapClaim
+type Defaults struct {
+	// Chart Helm chart of applications without spec.applications[].chart.name
+	Chart string `yaml:"chart"`
+
+	// ChartVersion Helm chart version of embedded charts and of the default chart in an OCI
+	// registry without chart.version
+	ChartVersion string `yaml:"chartVersion"`
+
+	// ImageTag image tag of applications without image.tag
+	ImageTag string `yaml:"imageTag"`
+
+	// StorageClass storage class of platform services and components
+	StorageClass string `yaml:"storageClass"`
+
+	// ServiceVersions version per platform service / component type
+	ServiceVersions map[string]string `yaml:"serviceVersions"`
+
+	// ServiceCharts chart per platform service type, the type itself when missing
+	ServiceCharts map[string]string `yaml:"serviceCharts"`
+
+	// GitProvider Git hosting service of claims without spec.gitProvider (gitea, github, gitlab, git)
+	GitProvider string `yaml:"gitProvider"`
+
+	// Branch GitOps branch of bootstrapped repositories and external chart repositories
+	Branch string `yaml:"branch"`
+
+	// ChartsRepo name of the bootstrapped charts repository
+	ChartsRepo string `yaml:"chartsRepo"`
+
+	// VoltranRepo name of the bootstrapped GitOps repository
+	VoltranRepo string `yaml:"voltranRepo"`
+
+	// ChartsRepositoryType source type of external chart repositories (git, oci)
+	ChartsRepositoryType string `yaml:"chartsRepositoryType"`
+
+	// ClusterType cluster type of bootstrapped GitOps layouts
+	ClusterType string `yaml:"clusterType"`
+
+	// Environments environments claims may target and the bootstrap creates
+	Environments []string `yaml:"environments"`
+
+	// EnvironmentValues Helm values per environment shared by every application deployed to it;
+	// the typed fields and values of an application override them
+	EnvironmentValues map[string]map[string]interface{} `yaml:"environmentValues"`
+}
+
+// New returns the built-in defaults
+func New() *Defaults {
+	return &Defaults{
+		Chart:        "microservice",
+		ChartVersion: "1.1.0",
+		ImageTag:     "latest",
+		StorageClass: "standard", // Default for Kind cluster
+		ServiceVersions: map[string]string{
+			"postgresql": "15",
+			"redis":      "7.0",
+		},
+		ServiceCharts: map[string]string{
+			"postgresql": "postgresql",
+			"redis":      "redis",
+			"rabbitmq":   "rabbitmq",
+			"mongodb":    "mongodb",
+			"kafka":      "kafka",
+		},
+		GitProvider:          "gitea",
+		Branch:               "main",
+		ChartsRepo:           "charts",
+		VoltranRepo:          "voltran",
+		ChartsRepositoryType: "git",
+		ClusterType:          "nonprod",
+		Environments:         []string{"dev", "qa", "sandbox", "staging", "prod"},
+		EnvironmentValues:    map[string]map[string]interface{}{},
+	}
+}
+
+// Load returns the built-in defaults overridden by the YAML file at path; an empty path
+// returns the built-in defaults. Maps are merged key by key, other fields replaced when set
+func Load(path string) (*Defaults, error) {
+	d := New()
+	if path == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read defaults %s: %w", path, err)
+	}
+	overrides := &Defaults{}
+	if err := yaml.Unmarshal(data, overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse defaults %s: %w", path, err)
+	}
+
+	overrideString(&d.Chart, overrides.Chart)
+	overrideString(&d.ChartVersion, overrides.ChartVersion)
+	overrideString(&d.ImageTag, overrides.ImageTag)
+	overrideString(&d.StorageClass, overrides.StorageClass)
+	overrideString(&d.GitProvider, overrides.GitProvider)
+	overrideString(&d.Branch, overrides.Branch)
+	overrideString(&d.ChartsRepo, overrides.ChartsRepo)
+	overrideString(&d.VoltranRepo, overrides.VoltranRepo)
+	overrideString(&d.ChartsRepositoryType, overrides.ChartsRepositoryType)
+	overrideString(&d.ClusterType, overrides.ClusterType)
+	for serviceType, version := range overrides.ServiceVersions {
+		d.ServiceVersions[serviceType] = version
+	}
+	for serviceType, chart := range overrides.ServiceCharts {
+		d.ServiceCharts[serviceType] = chart
+	}
+	if len(overrides.Environments) > 0 {
+		d.Environments = overrides.Environments
+	}
+	for env, values := range overrides.EnvironmentValues {
+		d.EnvironmentValues[env] = values
+	}
+	return d, nil
+}
+
+// OrBuiltin returns d, or the built-in defaults when d is nil
+func OrBuiltin(d *Defaults) *Defaults {
+	if d == nil {
+		return New()
+	}
+	return d
+}
+
+// ServiceVersion returns version, or the default version of the service type when empty
+func (d *Defaults) ServiceVersion(serviceType, version string) string {
+	if version != "" {
+		return version
+	}
+	return d.ServiceVersions[serviceType]
+}
+
+// ServiceChart returns chart, or the chart of the service type when empty
+func (d *Defaults) ServiceChart(serviceType, chart string) string {
+	if chart != "" {
+		return chart
+	}
+	if mapped, ok := d.ServiceCharts[serviceType]; ok {
+		return mapped
+	}
+	return serviceType
+}
+
+// ApplyApplicationClaim fills the unset Git provider, the unset chart, version and image tag
+// of every application and the unset version of every component
+func (d *Defaults) ApplyApplicationClaim(claim *platformv1.ApplicationClaim) {
+	setString(&claim.Spec.GitProvider, d.GitProvider)
+	for i := range claim.Spec.Applications {
+		app := &claim.Spec.Applications[i]
+		setString(&app.Chart.Name, d.Chart)
+		if d.versionsChart(app.Chart) {
+			setString(&app.Chart.Version, d.ChartVersion)
+		}
+		if app.Image.Repository != "" {
+			setString(&app.Image.Tag, d.ImageTag)
+		}
+	}
+	for i := range claim.Spec.Components {
+		comp := &claim.Spec.Components[i]
+		comp.Version = d.ServiceVersion(comp.Type, comp.Version)
+	}
+}
+
+// versionsChart reports whether ChartVersion applies to the chart: embedded charts and the default
+// chart published to an OCI registry. Git charts follow HEAD and Helm repository charts the latest
+// version unless pinned, and other OCI charts must be pinned explicitly.
+func (d *Defaults) versionsChart(chart platformv1.ChartSpec) bool {
+	switch {
+	case strings.HasPrefix(chart.Repository, "oci://"):
+		return chart.Source != "git" && chart.Source != "github" && chart.Name == d.Chart
+	case chart.Source == "" || chart.Source == "embedded":
+		return true
+	}
+	return false
+}
+
+// ApplyPlatformApplicationClaim fills the unset Git provider, storage class and the unset
+// chart and version of every platform service
+func (d *Defaults) ApplyPlatformApplicationClaim(claim *platformv1.PlatformApplicationClaim) {
+	setString(&claim.Spec.GitProvider, d.GitProvider)
+	setString(&claim.Spec.StorageClass, d.StorageClass)
+	for i := range claim.Spec.Services {
+		service := &claim.Spec.Services[i]
+		service.Chart.Name = d.ServiceChart(service.Type, service.Chart.Name)
+		service.Version = d.ServiceVersion(service.Type, service.Version)
+	}
+}
+
+// ApplyBootstrapClaim fills the unset Git provider, repository names, GitOps branch, cluster
+// type, environments and external chart repository settings
+func (d *Defaults) ApplyBootstrapClaim(claim *platformv1.BootstrapClaim) {
+	spec := &claim.Spec
+	setString(&spec.GitProvider, d.GitProvider)
+	setString(&spec.Repositories.Charts, d.ChartsRepo)
+	setString(&spec.Repositories.Voltran, d.VoltranRepo)
+	setString(&spec.GitOps.Branch, d.Branch)
+	setString(&spec.GitOps.ClusterType, d.ClusterType)
+	if len(spec.GitOps.Environments) == 0 {
+		spec.GitOps.Environments = append([]string(nil), d.Environments...)
+	}
+	if repo := spec.ChartsRepository; repo != nil {
+		setString(&repo.Type, d.ChartsRepositoryType)
+		if repo.Type == "git" {
+			setString(&repo.Branch, d.Branch)
+		}
+	}
+}
+
+// overrideString sets *field to value when value is not empty
+func overrideString(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// setString sets *field to value when it is empty
+func setString(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
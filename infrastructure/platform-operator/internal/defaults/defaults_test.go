@@ -0,0 +1,100 @@
+package defaults
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	content := "imageTag: stable\nstorageClass: gp3\nserviceVersions:\n  postgresql: \"16\"\nenvironments: [dev, prod]\nenvironmentValues:\n  prod:\n    podAnnotations:\n      tier: prod\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if d.ImageTag != "stable" || d.StorageClass != "gp3" {
+		t.Errorf("expected overridden image tag and storage class, got %q and %q", d.ImageTag, d.StorageClass)
+	}
+	if d.Chart != "microservice" || d.Branch != "main" {
+		t.Errorf("expected unset fields to keep the built-in defaults, got chart %q branch %q", d.Chart, d.Branch)
+	}
+	if d.ServiceVersions["postgresql"] != "16" || d.ServiceVersions["redis"] != "7.0" {
+		t.Errorf("expected service versions to be merged, got %v", d.ServiceVersions)
+	}
+	if !reflect.DeepEqual(d.Environments, []string{"dev", "prod"}) {
+		t.Errorf("expected environments to be replaced, got %v", d.Environments)
+	}
+
+	if annotations, ok := d.EnvironmentValues["prod"]["podAnnotations"].(map[string]interface{}); !ok || annotations["tier"] != "prod" {
+		t.Errorf("expected prod environment values, got %v", d.EnvironmentValues)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing defaults file")
+	}
+}
+
+func TestApplyClaims(t *testing.T) {
+	d := New()
+
+	app := &platformv1.ApplicationClaim{Spec: platformv1.ApplicationClaimSpec{
+		Applications: []platformv1.ApplicationSpec{
+			{Name: "api", Image: platformv1.ImageSpec{Repository: "ghcr.io/acme/api"}},
+			{Name: "web", Chart: platformv1.ChartSpec{Name: "frontend", Version: "2.1.0"}, Image: platformv1.ImageSpec{Repository: "ghcr.io/acme/web", Tag: "v3"}},
+			{Name: "git", Chart: platformv1.ChartSpec{Source: "git", Repository: "https://github.com/acme/charts.git"}},
+			{Name: "helm", Chart: platformv1.ChartSpec{Name: "nginx", Source: "helm-repo", Repository: "https://charts.bitnami.com/bitnami"}},
+			{Name: "oci", Chart: platformv1.ChartSpec{Repository: "oci://ghcr.io/acme/charts"}},
+			{Name: "oci-other", Chart: platformv1.ChartSpec{Name: "nginx", Repository: "oci://ghcr.io/acme/charts"}},
+		},
+		Components: []platformv1.ComponentSpec{{Name: "db", Type: "postgresql"}, {Name: "mq", Type: "rabbitmq"}},
+	}}
+	d.ApplyApplicationClaim(app)
+	if api := app.Spec.Applications[0]; api.Chart.Name != "microservice" || api.Chart.Version != "1.1.0" || api.Image.Tag != "latest" {
+		t.Errorf("unexpected defaulted application: %+v", api)
+	}
+	if web := app.Spec.Applications[1]; web.Chart.Name != "frontend" || web.Chart.Version != "2.1.0" || web.Image.Tag != "v3" {
+		t.Errorf("expected explicit values to be kept: %+v", web)
+	}
+	for i, want := range map[int]string{2: "", 3: "", 4: "1.1.0", 5: ""} {
+		if got := app.Spec.Applications[i]; got.Chart.Version != want {
+			t.Errorf("expected chart version %q for %s, got %q", want, got.Name, got.Chart.Version)
+		}
+	}
+	if app.Spec.Components[0].Version != "15" || app.Spec.Components[1].Version != "" {
+		t.Errorf("unexpected component versions: %+v", app.Spec.Components)
+	}
+
+	platform := &platformv1.PlatformApplicationClaim{Spec: platformv1.PlatformApplicationClaimSpec{
+		Services: []platformv1.PlatformServiceSpec{{Name: "cache", Type: "redis"}, {Name: "search", Type: "elasticsearch"}},
+	}}
+	d.ApplyPlatformApplicationClaim(platform)
+	if platform.Spec.StorageClass != "standard" {
+		t.Errorf("expected default storage class, got %q", platform.Spec.StorageClass)
+	}
+	if cache := platform.Spec.Services[0]; cache.Chart.Name != "redis" || cache.Version != "7.0" {
+		t.Errorf("unexpected defaulted redis service: %+v", cache)
+	}
+	if search := platform.Spec.Services[1]; search.Chart.Name != "elasticsearch" {
+		t.Errorf("expected the type as chart fallback, got %q", search.Chart.Name)
+	}
+
+	bootstrap := &platformv1.BootstrapClaim{Spec: platformv1.BootstrapClaimSpec{
+		ChartsRepository: &platformv1.ChartsRepositorySpec{URL: "https://github.com/acme/charts"},
+	}}
+	d.ApplyBootstrapClaim(bootstrap)
+	spec := bootstrap.Spec
+	if spec.GitProvider != "gitea" || spec.Repositories.Charts != "charts" || spec.Repositories.Voltran != "voltran" || spec.GitOps.Branch != "main" || spec.GitOps.ClusterType != "nonprod" {
+		t.Errorf("unexpected defaulted bootstrap spec: %+v", spec)
+	}
+	if len(spec.GitOps.Environments) != 5 || spec.ChartsRepository.Type != "git" || spec.ChartsRepository.Branch != "main" {
+		t.Errorf("unexpected defaulted GitOps and charts repository: %+v %+v", spec.GitOps, spec.ChartsRepository)
+	}
+}
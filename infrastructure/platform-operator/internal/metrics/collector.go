@@ -131,6 +131,16 @@ var (
 		[]string{"repository", "version"},
 	)
 
+	// Git metrics
+	gitOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "platform_operator_git_operation_duration_seconds",
+			Help:    "Duration of Git clones and fetches in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation", "result"},
+	)
+
 	// Health metrics
 	operatorHealth = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -166,6 +176,7 @@ func init() {
 		helmReleasesTotal,
 		helmOperationsTotal,
 		githubReleasesDownloaded,
+		gitOperationDuration,
 		operatorHealth,
 		operatorInfo,
 	)
@@ -289,6 +300,15 @@ func RecordGitHubDownload(repository, version string) {
 	githubReleasesDownloaded.WithLabelValues(repository, version).Inc()
 }
 
+// RecordGitOperation records the duration of a Git clone or fetch
+func RecordGitOperation(operation string, duration float64, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	gitOperationDuration.WithLabelValues(operation, result).Observe(duration)
+}
+
 // UpdateNamespacesManaged updates the count of managed namespaces
 func UpdateNamespacesManaged(count int) {
 	namespacesManaged.Set(float64(count))
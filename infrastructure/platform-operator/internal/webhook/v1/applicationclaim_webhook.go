@@ -0,0 +1,331 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+)
+
+// componentTypes component types backed by a platform service chart and operator
+var componentTypes = []string{"postgresql", "redis", "rabbitmq", "mongodb", "kafka", "elasticsearch"}
+
+// chartSources sources application charts can be deployed from
+var chartSources = []string{"embedded", "git", "github", "helm-repo", "oci"}
+
+// pullPolicies image pull policies accepted by Kubernetes
+var pullPolicies = []string{"Always", "IfNotPresent", "Never"}
+
+// probeTypes probe types rendered by the microservice chart
+var probeTypes = []string{"http", "tcp", "exec"}
+
+//+kubebuilder:webhook:path=/mutate-platform-infraforge-io-v1-applicationclaim,mutating=true,failurePolicy=fail,sideEffects=None,groups=platform.infraforge.io,resources=applicationclaims,verbs=create;update,versions=v1,name=mapplicationclaim.platform.infraforge.io,admissionReviewVersions=v1
+
+// ApplicationClaimDefaulter writes the operator defaults into unset ApplicationClaim fields
+type ApplicationClaimDefaulter struct {
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+}
+
+var _ webhook.CustomDefaulter = &ApplicationClaimDefaulter{}
+
+// SetupWebhookWithManager registers the ApplicationClaim defaulting webhook
+func (d *ApplicationClaimDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&platformv1.ApplicationClaim{}).
+		WithDefaulter(d).
+		Complete()
+}
+
+// Default fills the unset fields of an ApplicationClaim
+func (d *ApplicationClaimDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	claim, ok := obj.(*platformv1.ApplicationClaim)
+	if !ok {
+		return fmt.Errorf("expected an ApplicationClaim, got %T", obj)
+	}
+	defaults.OrBuiltin(d.Defaults).ApplyApplicationClaim(claim)
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-platform-infraforge-io-v1-applicationclaim,mutating=false,failurePolicy=fail,sideEffects=None,groups=platform.infraforge.io,resources=applicationclaims,verbs=create;update,versions=v1,name=vapplicationclaim.platform.infraforge.io,admissionReviewVersions=v1
+
+// ApplicationClaimValidator rejects ApplicationClaims that would generate broken GitOps files
+type ApplicationClaimValidator struct {
+	// Defaults operator defaults holding the allowed environments, built-in defaults when nil
+	Defaults *defaults.Defaults
+
+	// RejectMutableProdTags refuses empty and "latest" image tags in prod claims
+	RejectMutableProdTags bool
+}
+
+var _ webhook.CustomValidator = &ApplicationClaimValidator{}
+
+// SetupWebhookWithManager registers the ApplicationClaim validating webhook
+func (v *ApplicationClaimValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&platformv1.ApplicationClaim{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates a new ApplicationClaim
+func (v *ApplicationClaimValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	claim, ok := obj.(*platformv1.ApplicationClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected an ApplicationClaim, got %T", obj)
+	}
+	return nil, v.validate(claim)
+}
+
+// ValidateUpdate validates an updated ApplicationClaim
+func (v *ApplicationClaimValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return v.ValidateCreate(ctx, newObj)
+}
+
+// ValidateDelete allows every deletion; the finalizer cleans up the generated files
+func (v *ApplicationClaimValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an Invalid error listing every problem of the claim, nil if it is valid
+func (v *ApplicationClaimValidator) validate(claim *platformv1.ApplicationClaim) error {
+	d := defaults.OrBuiltin(v.Defaults)
+
+	specPath := field.NewPath("spec")
+	spec := claim.Spec
+
+	errs := validateGitOpsTarget(specPath, spec.GiteaURL, spec.Organization, spec.Environment, spec.ClusterType, d.Environments)
+	errs = append(errs, validateNamespace(specPath.Child("namespace"), spec.Namespace)...)
+	errs = append(errs, validateGitOpsMode(specPath.Child("gitOpsMode"), spec.GitOpsMode)...)
+	errs = append(errs, validateDriftPolicy(specPath.Child("driftPolicy"), spec.DriftPolicy)...)
+
+	// Applications and components share the ArgoCD Application name pattern, so names are
+	// unique across both lists
+	names := map[string]bool{}
+	for i, app := range spec.Applications {
+		appPath := specPath.Child("applications").Index(i)
+		errs = append(errs, validateUniqueName(appPath.Child("name"), app.Name, names)...)
+		errs = append(errs, v.validateApplication(appPath, claim, app)...)
+	}
+	errs = append(errs, validateDependencies(specPath.Child("applications"), spec.Applications)...)
+
+	for i, comp := range spec.Components {
+		compPath := specPath.Child("components").Index(i)
+		errs = append(errs, validateUniqueName(compPath.Child("name"), comp.Name, names)...)
+		errs = append(errs, validateComponent(compPath, comp)...)
+	}
+
+	return invalidClaim("ApplicationClaim", claim.Name, errs)
+}
+
+// validateApplication checks a single application of the claim
+func (v *ApplicationClaimValidator) validateApplication(path *field.Path, claim *platformv1.ApplicationClaim, app platformv1.ApplicationSpec) field.ErrorList {
+	errs := validateChart(path.Child("chart"), app.Chart)
+
+	imagePath := path.Child("image")
+	if app.Image.PullPolicy != "" && !contains(pullPolicies, app.Image.PullPolicy) {
+		errs = append(errs, field.NotSupported(imagePath.Child("pullPolicy"), app.Image.PullPolicy, pullPolicies))
+	}
+	if v.RejectMutableProdTags && app.Enabled && claim.Spec.ClusterType == "prod" &&
+		(app.Image.Tag == "" || app.Image.Tag == "latest") {
+		errs = append(errs, field.Invalid(imagePath.Child("tag"), app.Image.Tag, "prod claims require a pinned image tag"))
+	}
+
+	if app.Replicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("replicas"), app.Replicas, "must not be negative"))
+	}
+	errs = append(errs, validateResources(path.Child("resources"), app.Resources)...)
+	errs = append(errs, validateObject(path.Child("values"), app.Values)...)
+
+	// Port names referenced by the ingress and health checks must be declared
+	portNames := map[string]bool{}
+	for i, port := range app.Ports {
+		portPath := path.Child("ports").Index(i)
+		if port.Name == "" {
+			errs = append(errs, field.Required(portPath.Child("name"), "port name is required"))
+		} else if portNames[port.Name] {
+			errs = append(errs, field.Duplicate(portPath.Child("name"), port.Name))
+		}
+		portNames[port.Name] = true
+		errs = append(errs, validatePortNumber(portPath.Child("port"), port.Port, false)...)
+		errs = append(errs, validatePortNumber(portPath.Child("servicePort"), port.ServicePort, true)...)
+	}
+	validatePortRef := func(refPath *field.Path, portName string) {
+		// Without declared ports the chart only exposes the default http port
+		if portName != "" && !portNames[portName] && !(len(app.Ports) == 0 && portName == "http") {
+			errs = append(errs, field.NotFound(refPath, portName))
+		}
+	}
+
+	if app.Ingress != nil {
+		validatePortRef(path.Child("ingress", "port"), app.Ingress.Port)
+	}
+
+	hcPath := path.Child("healthCheck")
+	hc := app.HealthCheck
+	validatePortRef(hcPath.Child("portName"), hc.PortName)
+	errs = append(errs, validatePortNumber(hcPath.Child("port"), hc.Port, true)...)
+	for _, probe := range []struct {
+		name string
+		spec *platformv1.ProbeSpec
+	}{{"liveness", hc.Liveness}, {"readiness", hc.Readiness}, {"startup", hc.Startup}} {
+		if probe.spec == nil || (probe.spec.Enabled != nil && !*probe.spec.Enabled) {
+			continue
+		}
+		probePath := hcPath.Child(probe.name)
+		validatePortRef(probePath.Child("portName"), probe.spec.PortName)
+		errs = append(errs, validatePortNumber(probePath.Child("port"), probe.spec.Port, true)...)
+		switch probe.spec.Type {
+		case "", "http", "tcp":
+			if len(probe.spec.Command) > 0 {
+				errs = append(errs, field.Forbidden(probePath.Child("command"), "only exec probes run a command"))
+			}
+		case "exec":
+			if len(probe.spec.Command) == 0 {
+				errs = append(errs, field.Required(probePath.Child("command"), "exec probes require a command"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(probePath.Child("type"), probe.spec.Type, probeTypes))
+		}
+	}
+
+	envNames := map[string]bool{}
+	for i, env := range app.Env {
+		envPath := path.Child("env").Index(i)
+		if env.Name == "" {
+			errs = append(errs, field.Required(envPath.Child("name"), "environment variable name is required"))
+		} else if envNames[env.Name] {
+			errs = append(errs, field.Duplicate(envPath.Child("name"), env.Name))
+		}
+		envNames[env.Name] = true
+		if env.ValueFrom == nil {
+			continue
+		}
+		if env.Value != "" {
+			errs = append(errs, field.Forbidden(envPath.Child("value"), "may not be set together with valueFrom"))
+		}
+		// Unknown source kinds (fieldRef, resourceFieldRef, ...) are pruned by the API server
+		switch {
+		case env.ValueFrom.SecretKeyRef == nil && env.ValueFrom.ConfigMapKeyRef == nil:
+			errs = append(errs, field.Required(envPath.Child("valueFrom"), "one of secretKeyRef or configMapKeyRef is required"))
+		case env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.ConfigMapKeyRef != nil:
+			errs = append(errs, field.Forbidden(envPath.Child("valueFrom", "configMapKeyRef"), "may not be set together with secretKeyRef"))
+		}
+	}
+
+	if as := app.Autoscaling; as != nil && as.Enabled {
+		asPath := path.Child("autoscaling")
+		if as.MinReplicas < 1 {
+			errs = append(errs, field.Invalid(asPath.Child("minReplicas"), as.MinReplicas, "must be at least 1"))
+		}
+		if as.MaxReplicas < as.MinReplicas {
+			errs = append(errs, field.Invalid(asPath.Child("maxReplicas"), as.MaxReplicas, "must not be lower than minReplicas"))
+		}
+	}
+
+	return errs
+}
+
+// validateComponent checks a single component of the claim
+func validateComponent(path *field.Path, comp platformv1.ComponentSpec) field.ErrorList {
+	var errs field.ErrorList
+	if !contains(componentTypes, comp.Type) {
+		errs = append(errs, field.NotSupported(path.Child("type"), comp.Type, componentTypes))
+	}
+	errs = append(errs, validateQuantity(path.Child("storage"), comp.Storage)...)
+	if comp.Replicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("replicas"), comp.Replicas, "must not be negative"))
+	}
+	errs = append(errs, validateResources(path.Child("resources"), comp.Resources)...)
+	errs = append(errs, validateSize(path.Child("size"), comp.Size)...)
+	errs = append(errs, validateObject(path.Child("config"), comp.Config)...)
+	return errs
+}
+
+// validateDependencies rejects self-references and dependency cycles between the applications of
+// the claim; names that are not applications refer to components or platform services, which the
+// controller resolves
+func validateDependencies(path *field.Path, apps []platformv1.ApplicationSpec) field.ErrorList {
+	var errs field.ErrorList
+
+	index := map[string]int{}
+	for i, app := range apps {
+		index[app.Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(apps))
+	var visit func(i int, chain []string)
+	visit = func(i int, chain []string) {
+		state[i] = visiting
+		chain = append(chain, apps[i].Name)
+		for j, dep := range apps[i].DependsOn {
+			depPath := path.Index(i).Child("dependsOn").Index(j)
+			next, ok := index[dep]
+			switch {
+			case dep == apps[i].Name:
+				errs = append(errs, field.Invalid(depPath, dep, "an application cannot depend on itself"))
+			case !ok:
+				errs = append(errs, validateDNSLabel(depPath, dep)...)
+			case state[next] == visiting:
+				errs = append(errs, field.Invalid(depPath, dep,
+					fmt.Sprintf("dependency cycle %s -> %s", strings.Join(chain[indexOf(chain, dep):], " -> "), dep)))
+			case state[next] == unvisited:
+				visit(next, chain)
+			}
+		}
+		state[i] = done
+	}
+	for i := range apps {
+		if state[i] == unvisited {
+			visit(i, nil)
+		}
+	}
+	return errs
+}
+
+// validateChart checks that the chart source has the repository and version it needs
+func validateChart(path *field.Path, chart platformv1.ChartSpec) field.ErrorList {
+	var errs field.ErrorList
+	repoPath := path.Child("repository")
+	switch chart.Source {
+	case "":
+		// Without a source an oci:// repository selects an OCI chart
+		if chart.Repository != "" && !hasScheme(chart.Repository, "oci") {
+			errs = append(errs, field.Forbidden(repoPath, "embedded charts come from the organization's charts repository"))
+		}
+	case "embedded":
+		if chart.Repository != "" {
+			errs = append(errs, field.Forbidden(repoPath, "embedded charts come from the organization's charts repository"))
+		}
+	case "git", "github", "helm-repo":
+		if chart.Repository == "" {
+			errs = append(errs, field.Required(repoPath, "chart source "+chart.Source+" requires a repository"))
+		} else if chart.Source != "helm-repo" && hasScheme(chart.Repository, "oci") {
+			errs = append(errs, field.Invalid(repoPath, chart.Repository, "git repositories may not use an oci:// URL"))
+		}
+	case "oci":
+		if !hasScheme(chart.Repository, "oci") {
+			errs = append(errs, field.Invalid(repoPath, chart.Repository, "OCI charts require an oci:// repository"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("source"), chart.Source, chartSources))
+	}
+	// OCI registries do not resolve version ranges
+	if (chart.Source == "oci" || hasScheme(chart.Repository, "oci")) && chart.Version == "" {
+		errs = append(errs, field.Required(path.Child("version"), "OCI charts require a pinned version"))
+	}
+	return errs
+}
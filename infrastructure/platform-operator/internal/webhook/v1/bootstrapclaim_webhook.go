@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+)
+
+// chartsRepositoryTypes supported chart repository sources
+var chartsRepositoryTypes = []string{"git", "oci"}
+
+//+kubebuilder:webhook:path=/mutate-platform-infraforge-io-v1-bootstrapclaim,mutating=true,failurePolicy=fail,sideEffects=None,groups=platform.infraforge.io,resources=bootstrapclaims,verbs=create;update,versions=v1,name=mbootstrapclaim.platform.infraforge.io,admissionReviewVersions=v1
+
+// BootstrapClaimDefaulter writes the operator defaults into unset BootstrapClaim fields
+type BootstrapClaimDefaulter struct {
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+}
+
+var _ webhook.CustomDefaulter = &BootstrapClaimDefaulter{}
+
+// SetupWebhookWithManager registers the BootstrapClaim defaulting webhook
+func (d *BootstrapClaimDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&platformv1.BootstrapClaim{}).
+		WithDefaulter(d).
+		Complete()
+}
+
+// Default fills the unset fields of a BootstrapClaim
+func (d *BootstrapClaimDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	claim, ok := obj.(*platformv1.BootstrapClaim)
+	if !ok {
+		return fmt.Errorf("expected a BootstrapClaim, got %T", obj)
+	}
+	defaults.OrBuiltin(d.Defaults).ApplyBootstrapClaim(claim)
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-platform-infraforge-io-v1-bootstrapclaim,mutating=false,failurePolicy=fail,sideEffects=None,groups=platform.infraforge.io,resources=bootstrapclaims,verbs=create;update,versions=v1,name=vbootstrapclaim.platform.infraforge.io,admissionReviewVersions=v1
+
+// BootstrapClaimValidator rejects BootstrapClaims that would create an unusable voltran layout
+type BootstrapClaimValidator struct {
+	// Defaults operator defaults holding the allowed environments, built-in defaults when nil
+	Defaults *defaults.Defaults
+}
+
+var _ webhook.CustomValidator = &BootstrapClaimValidator{}
+
+// SetupWebhookWithManager registers the BootstrapClaim validating webhook
+func (v *BootstrapClaimValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&platformv1.BootstrapClaim{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates a new BootstrapClaim
+func (v *BootstrapClaimValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	claim, ok := obj.(*platformv1.BootstrapClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a BootstrapClaim, got %T", obj)
+	}
+	return nil, v.validate(claim)
+}
+
+// ValidateUpdate validates an updated BootstrapClaim
+func (v *BootstrapClaimValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return v.ValidateCreate(ctx, newObj)
+}
+
+// ValidateDelete allows every deletion
+func (v *BootstrapClaimValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an Invalid error listing every problem of the claim, nil if it is valid
+func (v *BootstrapClaimValidator) validate(claim *platformv1.BootstrapClaim) error {
+	d := defaults.OrBuiltin(v.Defaults)
+
+	specPath := field.NewPath("spec")
+	spec := claim.Spec
+
+	errs := validateGiteaURL(specPath.Child("giteaURL"), spec.GiteaURL)
+	if spec.Organization == "" {
+		errs = append(errs, field.Required(specPath.Child("organization"), "Gitea organization is required"))
+	}
+
+	gitOpsPath := specPath.Child("gitOps")
+	if spec.GitOps.ClusterType != "" {
+		errs = append(errs, validateClusterType(gitOpsPath.Child("clusterType"), spec.GitOps.ClusterType)...)
+	}
+	seen := map[string]bool{}
+	for i, env := range spec.GitOps.Environments {
+		envPath := gitOpsPath.Child("environments").Index(i)
+		errs = append(errs, validateEnvironment(envPath, env, d.Environments)...)
+		if seen[env] {
+			errs = append(errs, field.Duplicate(envPath, env))
+		}
+		seen[env] = true
+	}
+
+	if repo := spec.ChartsRepository; repo != nil {
+		repoPath := specPath.Child("chartsRepository")
+		repoType := repo.Type
+		if repoType == "" {
+			repoType = d.ChartsRepositoryType
+		}
+		if !contains(chartsRepositoryTypes, repoType) {
+			errs = append(errs, field.NotSupported(repoPath.Child("type"), repo.Type, chartsRepositoryTypes))
+		}
+		switch {
+		case repo.URL == "":
+			errs = append(errs, field.Required(repoPath.Child("url"), "charts repository URL is required"))
+		case repoType == "oci" && !hasScheme(repo.URL, "oci"):
+			errs = append(errs, field.Invalid(repoPath.Child("url"), repo.URL, "OCI repositories must use an oci:// URL"))
+		case repoType == "git" && hasScheme(repo.URL, "oci"):
+			errs = append(errs, field.Invalid(repoPath.Child("url"), repo.URL, "git repositories may not use an oci:// URL"))
+		}
+	}
+
+	return invalidClaim("BootstrapClaim", claim.Name, errs)
+}
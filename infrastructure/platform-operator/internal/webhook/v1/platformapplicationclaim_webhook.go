@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+	"github.com/infraforge/platform-operator/internal/defaults"
+)
+
+// platformServiceTypes platform service types; types without a default chart need spec.chart.name
+var platformServiceTypes = []string{"postgresql", "redis", "rabbitmq", "mongodb", "mysql", "kafka", "elasticsearch"}
+
+//+kubebuilder:webhook:path=/mutate-platform-infraforge-io-v1-platformapplicationclaim,mutating=true,failurePolicy=fail,sideEffects=None,groups=platform.infraforge.io,resources=platformapplicationclaims,verbs=create;update,versions=v1,name=mplatformapplicationclaim.platform.infraforge.io,admissionReviewVersions=v1
+
+// PlatformApplicationClaimDefaulter writes the operator defaults into unset PlatformApplicationClaim fields
+type PlatformApplicationClaimDefaulter struct {
+	// Defaults values of unset claim fields, built-in defaults when nil
+	Defaults *defaults.Defaults
+}
+
+var _ webhook.CustomDefaulter = &PlatformApplicationClaimDefaulter{}
+
+// SetupWebhookWithManager registers the PlatformApplicationClaim defaulting webhook
+func (d *PlatformApplicationClaimDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&platformv1.PlatformApplicationClaim{}).
+		WithDefaulter(d).
+		Complete()
+}
+
+// Default fills the unset fields of a PlatformApplicationClaim
+func (d *PlatformApplicationClaimDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	claim, ok := obj.(*platformv1.PlatformApplicationClaim)
+	if !ok {
+		return fmt.Errorf("expected a PlatformApplicationClaim, got %T", obj)
+	}
+	defaults.OrBuiltin(d.Defaults).ApplyPlatformApplicationClaim(claim)
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-platform-infraforge-io-v1-platformapplicationclaim,mutating=false,failurePolicy=fail,sideEffects=None,groups=platform.infraforge.io,resources=platformapplicationclaims,verbs=create;update,versions=v1,name=vplatformapplicationclaim.platform.infraforge.io,admissionReviewVersions=v1
+
+// PlatformApplicationClaimValidator rejects PlatformApplicationClaims that would generate broken GitOps files
+type PlatformApplicationClaimValidator struct {
+	// Defaults operator defaults holding the allowed environments, built-in defaults when nil
+	Defaults *defaults.Defaults
+}
+
+var _ webhook.CustomValidator = &PlatformApplicationClaimValidator{}
+
+// SetupWebhookWithManager registers the PlatformApplicationClaim validating webhook
+func (v *PlatformApplicationClaimValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&platformv1.PlatformApplicationClaim{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates a new PlatformApplicationClaim
+func (v *PlatformApplicationClaimValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	claim, ok := obj.(*platformv1.PlatformApplicationClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a PlatformApplicationClaim, got %T", obj)
+	}
+	return nil, v.validate(claim)
+}
+
+// ValidateUpdate validates an updated PlatformApplicationClaim
+func (v *PlatformApplicationClaimValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return v.ValidateCreate(ctx, newObj)
+}
+
+// ValidateDelete allows every deletion; the finalizer cleans up the generated files
+func (v *PlatformApplicationClaimValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an Invalid error listing every problem of the claim, nil if it is valid
+func (v *PlatformApplicationClaimValidator) validate(claim *platformv1.PlatformApplicationClaim) error {
+	d := defaults.OrBuiltin(v.Defaults)
+
+	specPath := field.NewPath("spec")
+	spec := claim.Spec
+
+	errs := validateGitOpsTarget(specPath, spec.GiteaURL, spec.Organization, spec.Environment, spec.ClusterType, d.Environments)
+	errs = append(errs, validateNamespace(specPath.Child("namespace"), spec.Namespace)...)
+	errs = append(errs, validateGitOpsMode(specPath.Child("gitOpsMode"), spec.GitOpsMode)...)
+	errs = append(errs, validateDriftPolicy(specPath.Child("driftPolicy"), spec.DriftPolicy)...)
+
+	serviceNames := map[string]bool{}
+	for i, service := range spec.Services {
+		servicePath := specPath.Child("services").Index(i)
+		errs = append(errs, validateUniqueName(servicePath.Child("name"), service.Name, serviceNames)...)
+		errs = append(errs, validatePlatformService(servicePath, service, d)...)
+	}
+
+	return invalidClaim("PlatformApplicationClaim", claim.Name, errs)
+}
+
+// validatePlatformService checks a single platform service of the claim
+func validatePlatformService(path *field.Path, service platformv1.PlatformServiceSpec, d *defaults.Defaults) field.ErrorList {
+	var errs field.ErrorList
+	switch {
+	case !contains(platformServiceTypes, service.Type):
+		errs = append(errs, field.NotSupported(path.Child("type"), service.Type, platformServiceTypes))
+	case service.Chart.Name == "" && d.ServiceCharts[service.Type] == "":
+		errs = append(errs, field.Required(path.Child("chart", "name"),
+			fmt.Sprintf("no platform chart exists for type %s", service.Type)))
+	}
+	errs = append(errs, validateSize(path.Child("size"), service.Size)...)
+	if service.Values.Raw != nil {
+		var values map[string]interface{}
+		if err := json.Unmarshal(service.Values.Raw, &values); err != nil {
+			errs = append(errs, field.Invalid(path.Child("values"), string(service.Values.Raw), "must be an object"))
+		}
+	}
+	if service.Backup != nil && service.Backup.Retention < 0 {
+		errs = append(errs, field.Invalid(path.Child("backup", "retention"), service.Backup.Retention, "must not be negative"))
+	}
+	return errs
+}
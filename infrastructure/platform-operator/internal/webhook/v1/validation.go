@@ -0,0 +1,206 @@
+// Package v1 contains the admission webhooks of the platform.infraforge.io/v1 API group
+package v1
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// clusterTypes cluster types the voltran layout is generated for
+var clusterTypes = []string{"nonprod", "prod"}
+
+// gitOpsModes ways generated files reach the voltran branch
+var gitOpsModes = []string{"direct", "pullRequest"}
+
+// driftPolicies reactions to voltran files changed outside the operator
+var driftPolicies = []string{"alert", "selfHeal"}
+
+// sizes presets accepted by the Size fields of components and platform services
+var sizes = []string{"small", "medium", "large"}
+
+// invalidClaim wraps field errors into the Invalid status error returned to the API server
+func invalidClaim(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: platformv1.GroupVersion.Group, Kind: kind}, name, errs)
+}
+
+// validateGitOpsTarget checks the Gitea server, organization, environment and cluster type a claim writes to
+func validateGitOpsTarget(specPath *field.Path, giteaURL, organization, environment, clusterType string, environments []string) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateGiteaURL(specPath.Child("giteaURL"), giteaURL)...)
+	if organization == "" {
+		errs = append(errs, field.Required(specPath.Child("organization"), "Gitea organization is required"))
+	}
+	errs = append(errs, validateEnvironment(specPath.Child("environment"), environment, environments)...)
+	errs = append(errs, validateClusterType(specPath.Child("clusterType"), clusterType)...)
+	return errs
+}
+
+// validateGiteaURL checks that the Gitea server URL is an absolute http(s) URL
+func validateGiteaURL(path *field.Path, giteaURL string) field.ErrorList {
+	if giteaURL == "" {
+		return field.ErrorList{field.Required(path, "Gitea server URL is required")}
+	}
+	u, err := url.Parse(giteaURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, giteaURL, "must be an absolute http or https URL")}
+	}
+	return nil
+}
+
+// validateEnvironment checks that the environment is one of the allowed environments
+func validateEnvironment(path *field.Path, environment string, environments []string) field.ErrorList {
+	if environment == "" {
+		return field.ErrorList{field.Required(path, "environment is required")}
+	}
+	if !contains(environments, environment) {
+		return field.ErrorList{field.NotSupported(path, environment, environments)}
+	}
+	return nil
+}
+
+// validateClusterType checks that the cluster type is nonprod or prod
+func validateClusterType(path *field.Path, clusterType string) field.ErrorList {
+	if clusterType == "" {
+		return field.ErrorList{field.Required(path, "clusterType is required")}
+	}
+	if !contains(clusterTypes, clusterType) {
+		return field.ErrorList{field.NotSupported(path, clusterType, clusterTypes)}
+	}
+	return nil
+}
+
+// validateNamespace checks an optional destination namespace
+func validateNamespace(path *field.Path, namespace string) field.ErrorList {
+	if namespace == "" {
+		return nil
+	}
+	return validateDNSLabel(path, namespace)
+}
+
+// validateGitOpsMode checks an optional GitOps mode
+func validateGitOpsMode(path *field.Path, mode string) field.ErrorList {
+	if mode != "" && !contains(gitOpsModes, mode) {
+		return field.ErrorList{field.NotSupported(path, mode, gitOpsModes)}
+	}
+	return nil
+}
+
+// validateDriftPolicy checks an optional drift policy
+func validateDriftPolicy(path *field.Path, policy string) field.ErrorList {
+	if policy != "" && !contains(driftPolicies, policy) {
+		return field.ErrorList{field.NotSupported(path, policy, driftPolicies)}
+	}
+	return nil
+}
+
+// validateDNSLabel checks that a name can be used in Kubernetes object and ArgoCD Application names
+func validateDNSLabel(path *field.Path, name string) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(path, "name is required")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	return errs
+}
+
+// validateUniqueName checks a list entry name and records it, reporting duplicates
+func validateUniqueName(path *field.Path, name string, seen map[string]bool) field.ErrorList {
+	errs := validateDNSLabel(path, name)
+	if name != "" && seen[name] {
+		errs = append(errs, field.Duplicate(path, name))
+	}
+	seen[name] = true
+	return errs
+}
+
+// validateResources checks that every CPU and memory value is a valid quantity
+func validateResources(path *field.Path, res platformv1.ResourceRequirements) field.ErrorList {
+	var errs field.ErrorList
+	for name, list := range map[string]platformv1.ResourceList{"requests": res.Requests, "limits": res.Limits} {
+		errs = append(errs, validateQuantity(path.Child(name, "cpu"), list.CPU)...)
+		errs = append(errs, validateQuantity(path.Child(name, "memory"), list.Memory)...)
+	}
+	return errs
+}
+
+// validateObject checks that optional free-form values decode to an object
+func validateObject(path *field.Path, raw runtime.RawExtension) field.ErrorList {
+	if raw.Raw == nil {
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw.Raw, &values); err != nil {
+		return field.ErrorList{field.Invalid(path, string(raw.Raw), "must be an object")}
+	}
+	return nil
+}
+
+// validateQuantity checks an optional resource quantity such as "100m" or "20Gi"
+func validateQuantity(path *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, value, "must be a valid resource quantity (e.g. 100m, 128Mi)")}
+	}
+	if q.Sign() < 0 {
+		return field.ErrorList{field.Invalid(path, value, "must not be negative")}
+	}
+	return nil
+}
+
+// validatePortNumber checks that a port is within 1-65535; optional ports may be zero
+func validatePortNumber(path *field.Path, port int32, optional bool) field.ErrorList {
+	if optional && port == 0 {
+		return nil
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsValidPortNum(int(port)) {
+		errs = append(errs, field.Invalid(path, port, msg))
+	}
+	return errs
+}
+
+// validateSize checks an optional size preset
+func validateSize(path *field.Path, size string) field.ErrorList {
+	if size != "" && !contains(sizes, size) {
+		return field.ErrorList{field.NotSupported(path, size, sizes)}
+	}
+	return nil
+}
+
+// contains reports whether value is one of values
+func contains(values []string, value string) bool {
+	return indexOf(values, value) >= 0
+}
+
+// indexOf returns the position of value in values, -1 if it is missing
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasScheme reports whether a repository URL starts with the given scheme
+func hasScheme(repoURL, scheme string) bool {
+	return strings.HasPrefix(repoURL, scheme+"://")
+}
@@ -0,0 +1,240 @@
+package v1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	platformv1 "github.com/infraforge/platform-operator/api/v1"
+)
+
+// samplesDir holds the sample claims applied to the development cluster
+var samplesDir = filepath.Join("..", "..", "..", "..", "..", "deployments", "dev")
+
+// loadSample decodes a sample claim from the deployments directory
+func loadSample(t *testing.T, file string, into interface{}) {
+	t.Helper()
+	f, err := os.Open(filepath.Join(samplesDir, file))
+	if err != nil {
+		t.Fatalf("failed to open sample %s: %v", file, err)
+	}
+	defer f.Close()
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(into); err != nil {
+		t.Fatalf("failed to decode sample %s: %v", file, err)
+	}
+}
+
+// expectInvalid asserts that err is an Invalid error reporting every given field path
+func expectInvalid(t *testing.T, name string, err error, fields ...string) {
+	t.Helper()
+	if !apierrors.IsInvalid(err) {
+		t.Errorf("%s: expected an Invalid error, got %v", name, err)
+		return
+	}
+	for _, f := range fields {
+		if !strings.Contains(err.Error(), f) {
+			t.Errorf("%s: expected error for %s, got %v", name, f, err)
+		}
+	}
+}
+
+func TestApplicationClaimValidator(t *testing.T) {
+	ctx := context.Background()
+	v := &ApplicationClaimValidator{RejectMutableProdTags: true}
+
+	sample := &platformv1.ApplicationClaim{}
+	loadSample(t, "apps-claim.yaml", sample)
+	if _, err := v.ValidateCreate(ctx, sample); err != nil {
+		t.Fatalf("expected sample ApplicationClaim to be valid, got %v", err)
+	}
+
+	tests := map[string]struct {
+		mutate func(claim *platformv1.ApplicationClaim)
+		fields []string
+	}{
+		"unknown environment": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.Environment = "preprod" },
+			fields: []string{"spec.environment"},
+		},
+		"unknown cluster type": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.ClusterType = "edge" },
+			fields: []string{"spec.clusterType"},
+		},
+		"unknown GitOps mode": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.GitOpsMode = "merge" },
+			fields: []string{"spec.gitOpsMode"},
+		},
+		"unknown drift policy": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.DriftPolicy = "ignore" },
+			fields: []string{"spec.driftPolicy"},
+		},
+		"duplicate app name": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.Applications[1].Name = c.Spec.Applications[0].Name },
+			fields: []string{"spec.applications[1].name"},
+		},
+		"invalid quantity": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.Applications[0].Resources.Limits.Memory = "128MB" },
+			fields: []string{"spec.applications[0].resources.limits.memory"},
+		},
+		"port out of range": {
+			mutate: func(c *platformv1.ApplicationClaim) { c.Spec.Applications[1].Ports[0].Port = 70000 },
+			fields: []string{"spec.applications[1].ports[0].port"},
+		},
+		"undeclared probe port": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Applications[1].HealthCheck.Liveness.PortName = "metrics"
+			},
+			fields: []string{"spec.applications[1].healthCheck.liveness.portName"},
+		},
+		"mutable prod tag": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Environment = "prod"
+				c.Spec.ClusterType = "prod"
+				c.Spec.Applications[0].Image.Tag = "latest"
+			},
+			fields: []string{"spec.applications[0].image.tag"},
+		},
+		"dependency cycle": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Applications[0].DependsOn = []string{"postgres", c.Spec.Applications[1].Name}
+				c.Spec.Applications[1].DependsOn = []string{c.Spec.Applications[0].Name}
+			},
+			fields: []string{"spec.applications[1].dependsOn[0]"},
+		},
+		"self dependency": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Applications[0].DependsOn = []string{c.Spec.Applications[0].Name, "Redis"}
+			},
+			fields: []string{"spec.applications[0].dependsOn[0]", "spec.applications[0].dependsOn[1]"},
+		},
+		"unpinned OCI chart": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Applications[0].Chart = platformv1.ChartSpec{Name: "microservice", Repository: "oci://ghcr.io/acme/charts"}
+				c.Spec.Applications[1].Chart = platformv1.ChartSpec{Name: "microservice", Source: "helm-repo"}
+			},
+			fields: []string{"spec.applications[0].chart.version", "spec.applications[1].chart.repository"},
+		},
+		"embedded chart with an OCI repository": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Applications[0].Chart = platformv1.ChartSpec{Name: "microservice", Source: "embedded", Repository: "oci://ghcr.io/acme/charts", Version: "1.1.0"}
+			},
+			fields: []string{"spec.applications[0].chart.repository"},
+		},
+		"values not an object": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Applications[0].Values = runtime.RawExtension{Raw: []byte(`"replicaCount: 2"`)}
+			},
+			fields: []string{"spec.applications[0].values"},
+		},
+		"component named like an app": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Components = []platformv1.ComponentSpec{{Name: c.Spec.Applications[0].Name, Type: "redis"}}
+			},
+			fields: []string{"spec.components[0].name"},
+		},
+		"unknown component type": {
+			mutate: func(c *platformv1.ApplicationClaim) {
+				c.Spec.Components = []platformv1.ComponentSpec{{Name: "cache", Type: "memcached", Storage: "lots"}}
+			},
+			fields: []string{"spec.components[0].type", "spec.components[0].storage"},
+		},
+	}
+	for name, tt := range tests {
+		claim := sample.DeepCopy()
+		tt.mutate(claim)
+		_, err := v.ValidateUpdate(ctx, sample, claim)
+		expectInvalid(t, name, err, tt.fields...)
+	}
+}
+
+func TestPlatformApplicationClaimValidator(t *testing.T) {
+	ctx := context.Background()
+	v := &PlatformApplicationClaimValidator{}
+
+	sample := &platformv1.PlatformApplicationClaim{}
+	loadSample(t, "platform-infrastructure-claim.yaml", sample)
+	if _, err := v.ValidateCreate(ctx, sample); err != nil {
+		t.Fatalf("expected sample PlatformApplicationClaim to be valid, got %v", err)
+	}
+
+	claim := sample.DeepCopy()
+	claim.Spec.ClusterType = "staging"
+	claim.Spec.Services[1].Name = claim.Spec.Services[0].Name
+	claim.Spec.Services[2].Type = "mysql"
+	claim.Spec.Services[2].Chart.Name = ""
+	claim.Spec.Services[3].Size = "huge"
+	_, err := v.ValidateCreate(ctx, claim)
+	expectInvalid(t, "invalid services", err,
+		"spec.clusterType", "spec.services[1].name", "spec.services[2].chart.name", "spec.services[3].size")
+}
+
+func TestBootstrapClaimValidator(t *testing.T) {
+	ctx := context.Background()
+	v := &BootstrapClaimValidator{}
+
+	sample := &platformv1.BootstrapClaim{}
+	loadSample(t, "bootstrap-claim.yaml", sample)
+	if _, err := v.ValidateCreate(ctx, sample); err != nil {
+		t.Fatalf("expected sample BootstrapClaim to be valid, got %v", err)
+	}
+
+	claim := sample.DeepCopy()
+	claim.Spec.GiteaURL = "gitea.local:3000"
+	claim.Spec.GitOps.Environments = append(claim.Spec.GitOps.Environments, "dev", "perf")
+	claim.Spec.ChartsRepository = &platformv1.ChartsRepositorySpec{Type: "oci", URL: "https://ghcr.io/acme/charts"}
+	_, err := v.ValidateCreate(ctx, claim)
+	expectInvalid(t, "invalid bootstrap", err,
+		"spec.giteaURL", "spec.gitOps.environments[5]", "spec.gitOps.environments[6]", "spec.chartsRepository.url")
+
+	// Wrong object kinds are reported as errors, not admitted
+	var other runtime.Object = &platformv1.ApplicationClaim{}
+	if _, err := v.ValidateCreate(ctx, other); err == nil {
+		t.Errorf("expected an error for a non-BootstrapClaim object")
+	}
+}
+
+func TestDefaultersWriteDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	claim := &platformv1.ApplicationClaim{}
+	loadSample(t, "apps-claim.yaml", claim)
+	claim.Spec.Applications[0].Chart = platformv1.ChartSpec{}
+	claim.Spec.Applications[0].Image.Tag = ""
+	if err := (&ApplicationClaimDefaulter{}).Default(ctx, claim); err != nil {
+		t.Fatalf("Default failed: %v", err)
+	}
+	if app := claim.Spec.Applications[0]; app.Chart.Name != "microservice" || app.Chart.Version != "1.1.0" || app.Image.Tag != "latest" {
+		t.Errorf("unexpected defaulted application: %+v", app)
+	}
+
+	platform := &platformv1.PlatformApplicationClaim{}
+	loadSample(t, "platform-infrastructure-claim.yaml", platform)
+	platform.Spec.StorageClass = ""
+	if err := (&PlatformApplicationClaimDefaulter{}).Default(ctx, platform); err != nil {
+		t.Fatalf("Default failed: %v", err)
+	}
+	if platform.Spec.StorageClass != "standard" {
+		t.Errorf("expected default storage class, got %q", platform.Spec.StorageClass)
+	}
+
+	bootstrap := &platformv1.BootstrapClaim{}
+	loadSample(t, "bootstrap-claim.yaml", bootstrap)
+	bootstrap.Spec.GitOps.Branch = ""
+	if err := (&BootstrapClaimDefaulter{}).Default(ctx, bootstrap); err != nil {
+		t.Fatalf("Default failed: %v", err)
+	}
+	if bootstrap.Spec.GitOps.Branch != "main" {
+		t.Errorf("expected default branch, got %q", bootstrap.Spec.GitOps.Branch)
+	}
+
+	// Defaulted sample claims still pass validation
+	if _, err := (&ApplicationClaimValidator{}).ValidateCreate(ctx, claim); err != nil {
+		t.Errorf("expected defaulted ApplicationClaim to be valid, got %v", err)
+	}
+}
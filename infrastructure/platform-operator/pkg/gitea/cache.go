@@ -0,0 +1,261 @@
+package gitea
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// DefaultRepoCache mirrors the repositories of every Client without its own cache; nil clones
+// every repository from scratch
+var DefaultRepoCache *RepoCache
+
+// GitOperationObserver is called with the duration of every clone and fetch, e.g. to export
+// metrics; operation is "clone" or "fetch"
+var GitOperationObserver func(operation string, duration time.Duration, err error)
+
+// observe reports a Git operation that started at start to GitOperationObserver
+func observe(operation string, start time.Time, err error) {
+	if GitOperationObserver != nil {
+		GitOperationObserver(operation, time.Since(start), err)
+	}
+}
+
+// CacheOptions configure a RepoCache
+type CacheOptions struct {
+	// MaxSize total size in bytes of the mirrors kept on disk, unlimited when zero
+	MaxSize int64
+
+	// MaxAge how long an unused mirror is kept, forever when zero
+	MaxAge time.Duration
+}
+
+// RepoCache keeps a bare mirror of every repository on disk and fetches it incrementally
+// instead of cloning the repository for every read or write. Each caller gets its own
+// worktree cloned from the mirror, so writers never share a checkout. Mirrors unused for
+// MaxAge, and the least recently used ones while the cache exceeds MaxSize, are removed.
+type RepoCache struct {
+	dir  string
+	opts CacheOptions
+
+	mu      sync.Mutex
+	mirrors map[string]*mirror
+}
+
+// mirror bare repository of one remote URL
+type mirror struct {
+	// mu is held exclusively by fetches, which rewrite refs and packs, and shared by the worktree
+	// clones reading the mirror
+	mu       sync.RWMutex
+	path     string
+	size     int64
+	lastUsed time.Time
+	// users callers between acquire and release; mirrors in use are never evicted
+	users int
+}
+
+// NewRepoCache creates a RepoCache in dir; mirrors left there by a previous run are reused
+func NewRepoCache(dir string, opts CacheOptions) (*RepoCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	rc := &RepoCache{dir: dir, opts: opts, mirrors: map[string]*mirror{}}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		rc.mirrors[entry.Name()] = &mirror{path: path, size: dirSize(path), lastUsed: info.ModTime()}
+	}
+	rc.evict()
+	return rc, nil
+}
+
+// checkout fetches the mirror of repoURL and clones branch from it into a new temporary
+// directory the caller removes; origin of the clone is repoURL, so pushes go to the server
+func (rc *RepoCache) checkout(ctx context.Context, repoURL string, auth transport.AuthMethod, branch string) (*git.Repository, string, error) {
+	m := rc.acquire(repoURL)
+	defer rc.release(m)
+
+	m.mu.Lock()
+	err := m.fetch(ctx, repoURL, auth)
+	size := dirSize(m.path)
+	m.mu.Unlock()
+	rc.mu.Lock()
+	m.size = size
+	rc.mu.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+
+	tempDir, err := os.MkdirTemp("", "gitea-worktree-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	m.mu.RLock()
+	repo, err := git.PlainCloneContext(ctx, tempDir, false, &git.CloneOptions{
+		URL:           m.path,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+	})
+	m.mu.RUnlock()
+	if err == nil {
+		err = pointOriginAt(repo, repoURL)
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", fmt.Errorf("failed to clone repository: %w", err)
+	}
+	return repo, tempDir, nil
+}
+
+// fetch brings the branches of the mirror up to date with the server, creating the mirror on
+// first use. Fetching with the caller's credentials on every checkout keeps the server in
+// charge of who may read the repository.
+func (m *mirror) fetch(ctx context.Context, repoURL string, auth transport.AuthMethod) error {
+	operation := "fetch"
+	repo, err := git.PlainOpen(m.path)
+	if err != nil {
+		// Missing or unusable mirror - start over
+		operation = "clone"
+		if err := os.RemoveAll(m.path); err != nil {
+			return fmt.Errorf("failed to remove mirror: %w", err)
+		}
+		if repo, err = git.PlainInit(m.path, true); err != nil {
+			return fmt.Errorf("failed to create mirror: %w", err)
+		}
+		if _, err := repo.CreateRemote(&config.RemoteConfig{
+			Name:  "origin",
+			URLs:  []string{repoURL},
+			Fetch: []config.RefSpec{"+refs/heads/*:refs/heads/*"},
+		}); err != nil {
+			return fmt.Errorf("failed to create mirror remote: %w", err)
+		}
+	}
+
+	start := time.Now()
+	err = repo.FetchContext(ctx, &git.FetchOptions{RemoteName: "origin", Auth: auth, Tags: git.NoTags, Force: true})
+	if err == git.NoErrAlreadyUpToDate {
+		err = nil
+	}
+	observe(operation, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to %s repository: %w", operation, err)
+	}
+
+	return nil
+}
+
+// pointOriginAt makes origin of a worktree cloned from a mirror the server repository
+func pointOriginAt(repo *git.Repository, repoURL string) error {
+	if err := repo.DeleteRemote("origin"); err != nil {
+		return err
+	}
+	_, err := repo.CreateRemote(&config.RemoteConfig{
+		Name:  "origin",
+		URLs:  []string{repoURL},
+		Fetch: []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+	})
+	return err
+}
+
+// acquire returns the mirror of repoURL and keeps it from being evicted until release
+func (rc *RepoCache) acquire(repoURL string) *mirror {
+	sum := sha256.Sum256([]byte(repoURL))
+	key := hex.EncodeToString(sum[:8])
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	m, ok := rc.mirrors[key]
+	if !ok {
+		m = &mirror{path: filepath.Join(rc.dir, key)}
+		rc.mirrors[key] = m
+	}
+	m.users++
+	m.lastUsed = time.Now()
+	return m
+}
+
+// release hands a mirror back and evicts mirrors beyond the cache limits
+func (rc *RepoCache) release(m *mirror) {
+	rc.mu.Lock()
+	m.users--
+	m.lastUsed = time.Now()
+	rc.mu.Unlock()
+	rc.evict()
+}
+
+// evict removes unused mirrors older than MaxAge, then the least recently used ones while the
+// cache is larger than MaxSize
+func (rc *RepoCache) evict() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	keys := make([]string, 0, len(rc.mirrors))
+	var total int64
+	for key, m := range rc.mirrors {
+		keys = append(keys, key)
+		total += m.size
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return rc.mirrors[keys[i]].lastUsed.Before(rc.mirrors[keys[j]].lastUsed)
+	})
+
+	for _, key := range keys {
+		m := rc.mirrors[key]
+		expired := rc.opts.MaxAge > 0 && time.Since(m.lastUsed) > rc.opts.MaxAge
+		oversized := rc.opts.MaxSize > 0 && total > rc.opts.MaxSize
+		if m.users > 0 || (!expired && !oversized) {
+			continue
+		}
+		if err := os.RemoveAll(m.path); err != nil {
+			continue
+		}
+		total -= m.size
+		delete(rc.mirrors, key)
+	}
+}
+
+// Size returns the total size in bytes of the mirrors on disk
+func (rc *RepoCache) Size() int64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	var total int64
+	for _, m := range rc.mirrors {
+		total += m.size
+	}
+	return total
+}
+
+// dirSize returns the size in bytes of the files under path
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,189 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRepoCacheFetchesIncrementally(t *testing.T) {
+	var operations []string
+	GitOperationObserver = func(operation string, _ time.Duration, err error) {
+		if err != nil {
+			t.Errorf("%s failed: %v", operation, err)
+		}
+		operations = append(operations, operation)
+	}
+	defer func() { GitOperationObserver = nil }()
+
+	repoURL := newTestRepo(t, map[string]string{"charts/a.yaml": "a: 1\n"})
+	cache, err := NewRepoCache(t.TempDir(), CacheOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("", "", "").WithRepoCache(cache).WithWriteCoordinator(NewWriteCoordinator(WriteOptions{}))
+
+	files, err := c.CloneAndExtractFiles(context.Background(), repoURL, "main", "charts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files["a.yaml"] != "a: 1\n" {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	// Writes go through a worktree of the mirror and are pushed to the server
+	if _, err := c.PushFiles(context.Background(), repoURL, "main", map[string]string{"charts/b.yaml": "b: 1\n"},
+		"Add b", "test", "test@local"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next read fetches the new commit into the mirror
+	files, err = c.CloneAndExtractFiles(context.Background(), repoURL, "main", "charts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files["b.yaml"] != "b: 1\n" {
+		t.Errorf("expected the mirror to be fetched, got %v", files)
+	}
+
+	if len(operations) != 3 || operations[0] != "clone" || operations[1] != "fetch" || operations[2] != "fetch" {
+		t.Errorf("expected one clone and then fetches, got %v", operations)
+	}
+	if readTestRepo(t, repoURL)["charts/b.yaml"] != "b: 1\n" {
+		t.Error("expected the write to be pushed to the server")
+	}
+	if cache.Size() == 0 {
+		t.Error("expected the mirror size to be tracked")
+	}
+}
+
+func TestRepoCacheEviction(t *testing.T) {
+	first := newTestRepo(t, nil)
+	second := newTestRepo(t, nil)
+	dir := t.TempDir()
+
+	checkout := func(cache *RepoCache, repoURL string) {
+		t.Helper()
+		_, worktree, err := cache.checkout(context.Background(), repoURL, nil, "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.RemoveAll(worktree)
+	}
+	mirrors := func() int {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	// Both mirrors fit the cache
+	cache, err := NewRepoCache(dir, CacheOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkout(cache, first)
+	checkout(cache, second)
+	if mirrors() != 2 {
+		t.Fatalf("expected 2 mirrors, got %d", mirrors())
+	}
+
+	// A restart keeps the mirrors; a size limit below both removes the least recently used one
+	cache, err = NewRepoCache(dir, CacheOptions{MaxSize: cache.Size() - 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mirrors() != 1 {
+		t.Fatalf("expected 1 mirror within the size limit, got %d", mirrors())
+	}
+	checkout(cache, second)
+	if mirrors() != 1 {
+		t.Errorf("expected the most recently used mirror to be kept, got %d mirrors", mirrors())
+	}
+
+	// Unused mirrors expire
+	cache, err = NewRepoCache(dir, CacheOptions{MaxAge: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	cache.evict()
+	if mirrors() != 0 {
+		t.Errorf("expected expired mirrors to be removed, got %d", mirrors())
+	}
+}
+
+func TestRepoCacheCheckoutDuringFetch(t *testing.T) {
+	repoURL := newTestRepo(t, map[string]string{"charts/a.yaml": "a: 1\n"})
+	cache, err := NewRepoCache(t.TempDir(), CacheOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commits keep arriving, so every checkout fetches into the mirror the others clone from
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		server := NewClient("", "", "")
+		for i := 0; i < 10; i++ {
+			_, err := server.PushFiles(context.Background(), repoURL, "main",
+				map[string]string{fmt.Sprintf("charts/%d.yaml", i): "n: 1\n"}, "Add chart", "test", "test@local")
+			errs <- err
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo, worktree, err := cache.checkout(context.Background(), repoURL, nil, "main")
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer os.RemoveAll(worktree)
+			head, err := repo.Head()
+			if err == nil {
+				_, err = repo.CommitObject(head.Hash())
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// A fetch waits for the clones reading the mirror
+	m := cache.acquire(repoURL)
+	defer cache.release(m)
+	fetched := make(chan struct{}, 1)
+	GitOperationObserver = func(string, time.Duration, error) { fetched <- struct{}{} }
+	defer func() { GitOperationObserver = nil }()
+
+	m.mu.RLock()
+	done := make(chan error)
+	go func() {
+		_, worktree, err := cache.checkout(context.Background(), repoURL, nil, "main")
+		os.RemoveAll(worktree)
+		done <- err
+	}()
+	select {
+	case <-fetched:
+		t.Error("expected the fetch to wait for the clone")
+	case <-time.After(100 * time.Millisecond):
+	}
+	m.mu.RUnlock()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
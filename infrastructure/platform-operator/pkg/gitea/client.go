@@ -6,10 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,7 +20,10 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
 	"gopkg.in/yaml.v3"
 )
 
@@ -27,6 +33,19 @@ type Client struct {
 	token      string
 	httpClient *http.Client
 	username   string
+	encoder    FileEncoder
+	writes     *WriteCoordinator
+	cache      *RepoCache
+
+	// sshURL and sshAuth replace HTTP with the token for Git operations when set
+	sshURL  string
+	sshAuth *gitssh.PublicKeys
+}
+
+// FileEncoder transforms file contents before they are committed, e.g. to encrypt secrets.
+// previous is the content committed on the base branch, empty for new files.
+type FileEncoder interface {
+	Encode(path, content, previous string) (string, error)
 }
 
 // Repository represents a Gitea repository
@@ -49,6 +68,32 @@ type CreateRepoOptions struct {
 	DefaultBranch string `json:"default_branch"`
 }
 
+// PullRequest represents a Gitea pull request
+type PullRequest struct {
+	Number         int64             `json:"number"`
+	Title          string            `json:"title"`
+	HTMLURL        string            `json:"html_url"`
+	State          string            `json:"state"`
+	Merged         bool              `json:"merged"`
+	MergeCommitSHA string            `json:"merge_commit_sha"`
+	Head           PullRequestBranch `json:"head"`
+	Base           PullRequestBranch `json:"base"`
+}
+
+// PullRequestBranch head or base branch of a pull request
+type PullRequestBranch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// CreatePullRequestOptions options for opening a pull request
+type CreatePullRequestOptions struct {
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
 // CommitFileOptions options for committing a file
 type CommitFileOptions struct {
 	Message     string
@@ -70,6 +115,90 @@ func NewClient(baseURL, username, token string) *Client {
 	}
 }
 
+// WithFileEncoder encodes every file written by PushFiles, SyncFiles and SyncFilesToBranch
+// with enc; a nil encoder writes files as is
+func (c *Client) WithFileEncoder(enc FileEncoder) *Client {
+	c.encoder = enc
+	return c
+}
+
+// WithWriteCoordinator serializes the writes of the client with wc instead of DefaultWriteCoordinator
+func (c *Client) WithWriteCoordinator(wc *WriteCoordinator) *Client {
+	c.writes = wc
+	return c
+}
+
+// WithRepoCache clones through the mirrors of rc instead of DefaultRepoCache
+func (c *Client) WithRepoCache(rc *RepoCache) *Client {
+	c.cache = rc
+	return c
+}
+
+// repoCache returns the cache the client clones through, nil when caching is disabled
+func (c *Client) repoCache() *RepoCache {
+	if c.cache != nil {
+		return c.cache
+	}
+	return DefaultRepoCache
+}
+
+// WithSSHKey runs Git operations over SSH with privateKey instead of over HTTP with the token;
+// API calls keep using the token. sshURL is the SSH base URL of the server (e.g.
+// ssh://git@gitea-ssh.gitea.svc:22) and knownHosts the accepted host keys in known_hosts format,
+// the known_hosts files of the operator when empty.
+func (c *Client) WithSSHKey(sshURL string, privateKey, knownHosts []byte) (*Client, error) {
+	u, err := url.Parse(sshURL)
+	if err != nil || u.Scheme != "ssh" || u.Host == "" {
+		return nil, fmt.Errorf("invalid SSH URL %q, expected ssh://user@host[:port]", sshURL)
+	}
+	user := "git"
+	if u.User != nil && u.User.Username() != "" {
+		user = u.User.Username()
+	}
+
+	auth, err := gitssh.NewPublicKeys(user, privateKey, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH private key: %w", err)
+	}
+	if len(knownHosts) > 0 {
+		if auth.HostKeyCallback, err = knownHostsCallback(knownHosts); err != nil {
+			return nil, err
+		}
+	}
+
+	c.sshURL = strings.TrimSuffix(sshURL, "/")
+	c.sshAuth = auth
+	return c, nil
+}
+
+// knownHostsCallback accepts the host keys listed in known_hosts content, whatever their host patterns
+func knownHostsCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	var keys [][]byte
+	for rest := knownHosts; len(bytes.TrimSpace(rest)) > 0; {
+		_, _, key, _, next, err := ssh.ParseKnownHosts(rest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid known_hosts: %w", err)
+		}
+		keys = append(keys, key.Marshal())
+		rest = next
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("known_hosts lists no host key")
+	}
+
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		for _, known := range keys {
+			if bytes.Equal(known, key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key of %s is not in known_hosts", hostname)
+	}, nil
+}
+
 // CreateOrganization creates a Gitea organization
 func (c *Client) CreateOrganization(ctx context.Context, orgName, description string) error {
 	body := map[string]interface{}{
@@ -174,37 +303,300 @@ func (c *Client) GetRepository(ctx context.Context, orgName, repoName string) (*
 	return &repo, nil
 }
 
-// PushFiles pushes multiple files to a repository
-func (c *Client) PushFiles(ctx context.Context, repoURL, branch string, files map[string]string, commitMsg, authorName, authorEmail string) error {
-	// Clone repository to temp directory with unique name (using nanosecond for uniqueness)
-	tempDir := fmt.Sprintf("/tmp/gitea-repo-%d", time.Now().UnixNano())
+// CreatePullRequest opens a pull request; if one is already open for the same head and base
+// branches that pull request is returned instead
+func (c *Client) CreatePullRequest(ctx context.Context, orgName, repoName string, opts CreatePullRequestOptions) (*PullRequest, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls", c.baseURL, orgName, repoName)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		// Gitea refuses a second open pull request for the same branches
+		if resp.StatusCode == http.StatusConflict {
+			return c.findOpenPullRequest(ctx, orgName, repoName, opts.Head, opts.Base)
+		}
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var pr PullRequest
+	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &pr, nil
+}
+
+// GetPullRequest gets a pull request by number
+func (c *Client) GetPullRequest(ctx context.Context, orgName, repoName string, number int64) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls/%d", c.baseURL, orgName, repoName, number)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "token "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var pr PullRequest
+	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &pr, nil
+}
+
+// ClosePullRequest closes a pull request without merging it
+func (c *Client) ClosePullRequest(ctx context.Context, orgName, repoName string, number int64) error {
+	data, err := json.Marshal(map[string]string{"state": "closed"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls/%d", c.baseURL, orgName, repoName, number)
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to close pull request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// findOpenPullRequest returns the open pull request from head into base
+func (c *Client) findOpenPullRequest(ctx context.Context, orgName, repoName, head, base string) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls?state=open&limit=50", c.baseURL, orgName, repoName)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "token "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var prs []PullRequest
+	if err := json.NewDecoder(resp.Body).Decode(&prs); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for i := range prs {
+		if prs[i].Head.Ref == head && prs[i].Base.Ref == base {
+			return &prs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no open pull request from %s into %s in %s/%s", head, base, orgName, repoName)
+}
+
+// PushFiles pushes multiple files to a repository and returns the resulting commit SHA
+func (c *Client) PushFiles(ctx context.Context, repoURL, branch string, files map[string]string, commitMsg, authorName, authorEmail string) (string, error) {
+	return c.SyncFiles(ctx, repoURL, branch, nil, files, commitMsg, authorName, authorEmail)
+}
+
+// SyncFiles makes the repository match the desired set of files in a single commit.
+// Every file under one of the owned prefixes (a file path or a directory) that is not
+// part of files is deleted; files are written and added as with PushFiles.
+// If the resulting tree does not differ from the branch head no commit is created and
+// the SHA of the current head is returned, otherwise the SHA of the pushed commit.
+func (c *Client) SyncFiles(ctx context.Context, repoURL, branch string, owned []string, files map[string]string, commitMsg, authorName, authorEmail string) (string, error) {
+	sha, _, err := c.SyncFilesToBranch(ctx, repoURL, branch, branch, owned, files, commitMsg, authorName, authorEmail)
+	return sha, err
+}
+
+// SyncFilesToBranch applies the same single-commit sync as SyncFiles on top of baseBranch and
+// pushes the result to targetBranch, replacing whatever targetBranch pointed to before.
+// It reports whether a commit was created; without changes against baseBranch nothing is
+// pushed and the SHA of the baseBranch head is returned.
+// Writes to the same branch are serialized by the client's WriteCoordinator, which re-applies
+// the files on top of the new head when the push is rejected.
+func (c *Client) SyncFilesToBranch(ctx context.Context, repoURL, baseBranch, targetBranch string, owned []string, files map[string]string, commitMsg, authorName, authorEmail string) (string, bool, error) {
+	coordinator := c.writes
+	if coordinator == nil {
+		coordinator = DefaultWriteCoordinator
+	}
+	return coordinator.submit(&writeRequest{
+		ctx:          ctx,
+		client:       c,
+		repoURL:      repoURL,
+		baseBranch:   baseBranch,
+		targetBranch: targetBranch,
+		owned:        owned,
+		files:        files,
+		commitMsg:    commitMsg,
+		authorName:   authorName,
+		authorEmail:  authorEmail,
+	})
+}
+
+// commitAndPush applies the files of every request on top of the head of the base branch in one
+// commit and pushes it to the target branch, with the credentials of the first request
+func (c *Client) commitAndPush(ctx context.Context, batch []*writeRequest) (string, bool, error) {
+	first := batch[0]
+	repo, tempDir, err := c.cloneBranch(ctx, first.repoURL, first.baseBranch)
+	if err != nil {
+		return "", false, err
+	}
 	defer os.RemoveAll(tempDir) // Cleanup temp directory after push
 
-	repo, err := git.PlainClone(tempDir, false, &git.CloneOptions{
-		URL: repoURL,
-		Auth: &githttp.BasicAuth{
-			Username: c.username,
-			Password: c.token,
+	base, err := repo.Head()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	for _, req := range batch {
+		if err := req.client.applyFiles(w, tempDir, req.owned, req.files); err != nil {
+			return "", false, err
+		}
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	if status.IsClean() {
+		// Nothing changed - avoid an empty commit
+		return base.Hash().String(), false, nil
+	}
+
+	// Commit
+	commit, err := w.Commit(batchCommitMessage(batch), &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  first.authorName,
+			Email: first.authorEmail,
+			When:  time.Now(),
 		},
-		ReferenceName: plumbing.ReferenceName("refs/heads/" + branch),
-		SingleBranch:  true,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+		return "", false, fmt.Errorf("failed to commit: %w", err)
 	}
 
-	w, err := repo.Worktree()
+	// Push - a separate target branch is owned by the caller and overwritten
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: "origin",
+		Auth:       c.gitAuth(),
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", first.baseBranch, first.targetBranch)),
+		},
+		Force: first.targetBranch != first.baseBranch,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+		if first.targetBranch == first.baseBranch && c.branchMoved(ctx, repo, first.baseBranch, base.Hash()) {
+			return "", false, fmt.Errorf("failed to push: %w: %w", errBranchMoved, err)
+		}
+		return "", false, fmt.Errorf("failed to push: %w", err)
+	}
+
+	return commit.String(), true, nil
+}
+
+// branchMoved reports whether branch of the server is no longer at base, the commit a rejected
+// push was built on. Servers report rejections as free text, so the branch is looked up instead.
+func (c *Client) branchMoved(ctx context.Context, repo *git.Repository, branch string, base plumbing.Hash) bool {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return false
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: c.gitAuth()})
+	if err != nil {
+		return false
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.NewBranchReferenceName(branch) {
+			return ref.Hash() != base
+		}
+	}
+	// The branch was deleted
+	return true
+}
+
+// applyFiles prunes the files under the owned prefixes that are not part of files, then writes
+// and stages files in the worktree rooted at dir
+func (c *Client) applyFiles(w *git.Worktree, dir string, owned []string, files map[string]string) error {
+	// Prune files under owned prefixes that are no longer desired
+	for _, prefix := range owned {
+		stale, err := listFiles(dir, strings.Trim(prefix, "/"))
+		if err != nil {
+			return fmt.Errorf("failed to list files under %s: %w", prefix, err)
+		}
+		for _, path := range stale {
+			if _, keep := files[path]; keep {
+				continue
+			}
+			if _, err := w.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove file %s: %w", path, err)
+			}
+		}
 	}
 
 	// Write all files
 	for path, content := range files {
-		fullPath := fmt.Sprintf("%s/%s", tempDir, path)
+		fullPath := fmt.Sprintf("%s/%s", dir, path)
 		if err := ensureDir(fullPath); err != nil {
 			return fmt.Errorf("failed to ensure directory: %w", err)
 		}
 
+		if c.encoder != nil {
+			previous, err := os.ReadFile(fullPath)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+			if content, err = c.encoder.Encode(path, content, string(previous)); err != nil {
+				return fmt.Errorf("failed to encode file %s: %w", path, err)
+			}
+		}
+
 		if err := writeFile(fullPath, content); err != nil {
 			return fmt.Errorf("failed to write file %s: %w", path, err)
 		}
@@ -213,35 +605,112 @@ func (c *Client) PushFiles(ctx context.Context, repoURL, branch string, files ma
 			return fmt.Errorf("failed to add file %s: %w", path, err)
 		}
 	}
+	return nil
+}
 
-	// Commit
-	_, err = w.Commit(commitMsg, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  authorName,
-			Email: authorEmail,
-			When:  time.Now(),
-		},
-	})
+// DiffFiles compares the files with the head of branch and returns the paths that differ, sorted,
+// together with the head commit SHA. A path differs when it is missing, when its content is not
+// what SyncFiles would write or when it lies under one of the owned prefixes without being part
+// of files.
+func (c *Client) DiffFiles(ctx context.Context, repoURL, branch string, owned []string, files map[string]string) ([]string, string, error) {
+	repo, tempDir, err := c.cloneBranch(ctx, repoURL, branch)
 	if err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+		return nil, "", err
 	}
+	defer os.RemoveAll(tempDir)
 
-	// Push
-	err = repo.Push(&git.PushOptions{
-		RemoteName: "origin",
-		Auth: &githttp.BasicAuth{
-			Username: c.username,
-			Password: c.token,
-		},
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch)),
-		},
+	head, err := repo.Head()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	differing := map[string]bool{}
+	for path, content := range files {
+		current, err := os.ReadFile(filepath.Join(tempDir, path))
+		missing := os.IsNotExist(err)
+		if err != nil && !missing {
+			return nil, "", fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		if c.encoder != nil {
+			if content, err = c.encoder.Encode(path, content, string(current)); err != nil {
+				return nil, "", fmt.Errorf("failed to encode file %s: %w", path, err)
+			}
+		}
+		if missing || string(current) != content {
+			differing[path] = true
+		}
+	}
+	for _, prefix := range owned {
+		existing, err := listFiles(tempDir, strings.Trim(prefix, "/"))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list files under %s: %w", prefix, err)
+		}
+		for _, path := range existing {
+			if _, desired := files[path]; !desired {
+				differing[path] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(differing))
+	for path := range differing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, head.Hash().String(), nil
+}
+
+// cloneBranch clones a single branch into a new temporary directory the caller removes
+func (c *Client) cloneBranch(ctx context.Context, repoURL, branch string) (*git.Repository, string, error) {
+	if cache := c.repoCache(); cache != nil {
+		return cache.checkout(ctx, c.gitURL(repoURL), c.gitAuth(), branch)
+	}
+
+	// Clone repository to temp directory with unique name (using nanosecond for uniqueness)
+	tempDir := fmt.Sprintf("/tmp/gitea-repo-%d", time.Now().UnixNano())
+
+	start := time.Now()
+	repo, err := git.PlainCloneContext(ctx, tempDir, false, &git.CloneOptions{
+		URL:           c.gitURL(repoURL),
+		Auth:          c.gitAuth(),
+		ReferenceName: plumbing.ReferenceName("refs/heads/" + branch),
+		SingleBranch:  true,
 	})
+	observe("clone", start, err)
 	if err != nil {
-		return fmt.Errorf("failed to push: %w", err)
+		os.RemoveAll(tempDir)
+		return nil, "", fmt.Errorf("failed to clone repository: %w", err)
 	}
+	return repo, tempDir, nil
+}
 
-	return nil
+// gitAuth returns the credentials used for Git operations against the server
+func (c *Client) gitAuth() transport.AuthMethod {
+	if c.sshAuth != nil {
+		return c.sshAuth
+	}
+	return &githttp.BasicAuth{
+		Username: c.username,
+		Password: c.token,
+	}
+}
+
+// credentialsID identifies the credentials of the client; writes are only batched into one push
+// when their credentials are the same
+func (c *Client) credentialsID() string {
+	if c.sshAuth != nil {
+		return "ssh " + c.sshURL + " " + ssh.FingerprintSHA256(c.sshAuth.Signer.PublicKey())
+	}
+	return "http " + c.username + " " + c.token
+}
+
+// gitURL returns the URL Git operations use for a clone URL of the server: its SSH counterpart
+// when an SSH key is configured
+func (c *Client) gitURL(repoURL string) string {
+	if c.sshAuth == nil || !strings.HasPrefix(repoURL, c.baseURL+"/") {
+		return repoURL
+	}
+	return c.sshURL + strings.TrimPrefix(repoURL, c.baseURL)
 }
 
 // GetBaseURL returns the base URL of the Gitea server
@@ -249,6 +718,11 @@ func (c *Client) GetBaseURL() string {
 	return c.baseURL
 }
 
+// CommitURL returns the web URL of a commit in a repository
+func (c *Client) CommitURL(orgName, repoName, sha string) string {
+	return fmt.Sprintf("%s/%s/%s/commit/%s", c.baseURL, orgName, repoName, sha)
+}
+
 // ConstructCloneURL constructs the internal clone URL for a repository
 // This ensures we use the cluster-internal URL instead of the external ROOT_URL from Gitea API
 func (c *Client) ConstructCloneURL(orgName, repoName string) string {
@@ -327,15 +801,20 @@ func (c *Client) PullOCIChartAndExtract(ctx context.Context, chartURL, version s
 	return files, nil
 }
 
-// CloneAndExtractFiles clones a Git repository and extracts all files from a specific path
-// Returns a map of file paths to file contents
-func (c *Client) CloneAndExtractFiles(ctx context.Context, repoURL, branch, subPath string) (map[string]string, error) {
+// cloneFiles checks out branch of a repository into a new temporary directory the caller
+// removes. A named branch is checked out from the repository cache when there is one;
+// otherwise, or for the default branch, the repository is cloned shallowly.
+func (c *Client) cloneFiles(ctx context.Context, repoURL, branch string) (string, error) {
+	if cache := c.repoCache(); cache != nil && branch != "" {
+		_, dir, err := cache.checkout(ctx, repoURL, nil, branch)
+		return dir, err
+	}
+
 	// Create temporary directory for cloning
 	tmpDir, err := os.MkdirTemp("", "charts-clone-*")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
 	}
-	defer os.RemoveAll(tmpDir)
 
 	// Clone options
 	cloneOpts := &git.CloneOptions{
@@ -351,10 +830,24 @@ func (c *Client) CloneAndExtractFiles(ctx context.Context, repoURL, branch, subP
 	}
 
 	// Clone the repository
+	start := time.Now()
 	_, err = git.PlainCloneContext(ctx, tmpDir, false, cloneOpts)
+	observe("clone", start, err)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to clone repository: %w", err)
+	}
+	return tmpDir, nil
+}
+
+// CloneAndExtractFiles clones a Git repository and extracts all files from a specific path
+// Returns a map of file paths to file contents
+func (c *Client) CloneAndExtractFiles(ctx context.Context, repoURL, branch, subPath string) (map[string]string, error) {
+	tmpDir, err := c.cloneFiles(ctx, repoURL, branch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to clone repository: %w", err)
+		return nil, err
 	}
+	defer os.RemoveAll(tmpDir)
 
 	// Determine the source path
 	sourcePath := tmpDir
@@ -521,3 +1014,31 @@ func ensureDir(filePath string) error {
 func writeFile(path, content string) error {
 	return os.WriteFile(path, []byte(content), 0644)
 }
+
+// listFiles returns the repository-relative paths of all files at or below prefix
+func listFiles(root, prefix string) ([]string, error) {
+	start := filepath.Join(root, prefix)
+	if _, err := os.Stat(start); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var paths []string
+	err := filepath.Walk(start, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(relPath))
+		return nil
+	})
+	return paths, err
+}
@@ -0,0 +1,411 @@
+package gitea
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestRepo creates a bare repository seeded with the given files on branch main
+// and returns its path, usable as a clone URL
+func newTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatalf("failed to init bare repository: %v", err)
+	}
+
+	work := t.TempDir()
+	repo, err := git.PlainInit(work, false)
+	if err != nil {
+		t.Fatalf("failed to init work repository: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))); err != nil {
+		t.Fatalf("failed to set HEAD: %v", err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if files == nil {
+		files = map[string]string{"README.md": "# test\n"}
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(work, path)
+		if err := ensureDir(fullPath); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFile(fullPath, content); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@local", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}}); err != nil {
+		t.Fatalf("failed to create remote: %v", err)
+	}
+	if err := repo.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatalf("failed to push seed commit: %v", err)
+	}
+
+	return bare
+}
+
+// readTestRepo clones the repository and returns all files on branch main
+func readTestRepo(t *testing.T, repoURL string) map[string]string {
+	t.Helper()
+
+	c := NewClient("", "", "")
+	files, err := c.CloneAndExtractFiles(context.Background(), repoURL, "main", "")
+	if err != nil {
+		t.Fatalf("failed to read repository: %v", err)
+	}
+	return files
+}
+
+// branchHead returns the commit hash branch main points to
+func branchHead(t *testing.T, repoURL string) plumbing.Hash {
+	t.Helper()
+
+	repo, err := git.PlainOpen(repoURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref.Hash()
+}
+
+// commitCount returns the number of commits on branch main
+func commitCount(t *testing.T, repoURL string) int {
+	t.Helper()
+
+	repo, err := git.PlainOpen(repoURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, err := repo.Log(&git.LogOptions{From: branchHead(t, repoURL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	_ = iter.ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	})
+	return count
+}
+
+func TestSyncFilesPrunesOwnedPrefixes(t *testing.T) {
+	repoURL := newTestRepo(t, map[string]string{
+		"README.md":                             "# voltran\n",
+		"envs/dev/applications/.gitkeep":        "",
+		"envs/dev/applications/old/values.yaml": "replicaCount: 1\n",
+		"envs/dev/applications/api/values.yaml": "replicaCount: 1\n",
+		"envs/dev/applications-extra/keep.yaml": "keep: true\n",
+		"envs/dev/platform/redis/values.yaml":   "type: redis\n",
+	})
+
+	c := NewClient("", "", "")
+	sha, err := c.SyncFiles(context.Background(), repoURL, "main",
+		[]string{"envs/dev/applications"},
+		map[string]string{
+			"envs/dev/applications/.gitkeep":        "",
+			"envs/dev/applications/api/values.yaml": "replicaCount: 2\n",
+			"envs/dev/applications/web/values.yaml": "replicaCount: 1\n",
+		},
+		"sync", "test", "test@local")
+	if err != nil {
+		t.Fatalf("SyncFiles failed: %v", err)
+	}
+
+	files := readTestRepo(t, repoURL)
+	if _, ok := files["envs/dev/applications/old/values.yaml"]; ok {
+		t.Errorf("expected stale application to be pruned")
+	}
+	if got := files["envs/dev/applications/api/values.yaml"]; got != "replicaCount: 2\n" {
+		t.Errorf("expected api values to be updated, got %q", got)
+	}
+	if _, ok := files["envs/dev/applications/web/values.yaml"]; !ok {
+		t.Errorf("expected new application to be added")
+	}
+	for _, path := range []string{"README.md", "envs/dev/applications/.gitkeep", "envs/dev/applications-extra/keep.yaml", "envs/dev/platform/redis/values.yaml"} {
+		if _, ok := files[path]; !ok {
+			t.Errorf("expected %s outside the owned set to be kept", path)
+		}
+	}
+	if got := commitCount(t, repoURL); got != 2 {
+		t.Errorf("expected a single sync commit, got %d commits", got)
+	}
+	if head := branchHead(t, repoURL).String(); sha != head {
+		t.Errorf("expected SyncFiles to return the pushed commit %s, got %s", head, sha)
+	}
+}
+
+func TestSyncFilesWithoutChangesDoesNotCommit(t *testing.T) {
+	repoURL := newTestRepo(t, map[string]string{"a/values.yaml": "x: 1\n"})
+
+	head := branchHead(t, repoURL).String()
+
+	c := NewClient("", "", "")
+	sha, err := c.SyncFiles(context.Background(), repoURL, "main",
+		[]string{"a", "missing"}, map[string]string{"a/values.yaml": "x: 1\n"},
+		"noop", "test", "test@local")
+	if err != nil {
+		t.Fatalf("SyncFiles failed: %v", err)
+	}
+
+	if got := commitCount(t, repoURL); got != 1 {
+		t.Errorf("expected no new commit, got %d commits", got)
+	}
+	if sha != head {
+		t.Errorf("expected SyncFiles to return the unchanged head %s, got %s", head, sha)
+	}
+}
+
+// upperEncoder records the previous content it is given and upper-cases files
+type upperEncoder struct {
+	previous map[string]string
+}
+
+func (e *upperEncoder) Encode(path, content, previous string) (string, error) {
+	e.previous[path] = previous
+	return strings.ToUpper(content), nil
+}
+
+func TestSyncFilesEncodesFiles(t *testing.T) {
+	repoURL := newTestRepo(t, map[string]string{"a/values.yaml": "X: 1\n"})
+
+	enc := &upperEncoder{previous: map[string]string{}}
+	c := NewClient("", "", "").WithFileEncoder(enc)
+	if _, err := c.SyncFiles(context.Background(), repoURL, "main", nil,
+		map[string]string{"a/values.yaml": "x: 2\n", "b/values.yaml": "y: 1\n"},
+		"encode", "test", "test@local"); err != nil {
+		t.Fatalf("SyncFiles failed: %v", err)
+	}
+
+	files := readTestRepo(t, repoURL)
+	if files["a/values.yaml"] != "X: 2\n" || files["b/values.yaml"] != "Y: 1\n" {
+		t.Errorf("expected encoded files, got %v", files)
+	}
+	if enc.previous["a/values.yaml"] != "X: 1\n" || enc.previous["b/values.yaml"] != "" {
+		t.Errorf("expected the committed content as previous, got %v", enc.previous)
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	repoURL := newTestRepo(t, map[string]string{
+		"apps/api/values.yaml":   "replicaCount: 5\n",
+		"apps/web/values.yaml":   "replicaCount: 1\n",
+		"apps/old/values.yaml":   "replicaCount: 1\n",
+		"other/team/values.yaml": "replicaCount: 1\n",
+	})
+
+	c := NewClient("", "", "")
+	paths, sha, err := c.DiffFiles(context.Background(), repoURL, "main", []string{"apps"}, map[string]string{
+		"apps/api/values.yaml": "replicaCount: 2\n",
+		"apps/web/values.yaml": "replicaCount: 1\n",
+		"apps/new/values.yaml": "replicaCount: 1\n",
+	})
+	if err != nil {
+		t.Fatalf("DiffFiles failed: %v", err)
+	}
+	expected := []string{"apps/api/values.yaml", "apps/new/values.yaml", "apps/old/values.yaml"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected differing paths %v, got %v", expected, paths)
+	}
+	if head := branchHead(t, repoURL).String(); sha != head {
+		t.Errorf("expected the head commit %s, got %s", head, sha)
+	}
+	if got := commitCount(t, repoURL); got != 1 {
+		t.Errorf("expected DiffFiles not to commit, got %d commits", got)
+	}
+}
+
+func TestListFilesMissingPrefix(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := listFiles(root, "b")
+	if err != nil || len(paths) != 0 {
+		t.Errorf("expected no files for missing prefix, got %v, %v", paths, err)
+	}
+}
+
+func TestSyncFilesToBranchLeavesBaseBranch(t *testing.T) {
+	repoURL := newTestRepo(t, map[string]string{"envs/prod/api/values.yaml": "replicaCount: 1\n"})
+	head := branchHead(t, repoURL)
+
+	c := NewClient("", "", "")
+	sha, committed, err := c.SyncFilesToBranch(context.Background(), repoURL, "main", "claim/default/api/2",
+		[]string{"envs/prod/api"}, map[string]string{"envs/prod/api/values.yaml": "replicaCount: 3\n"},
+		"update", "test", "test@local")
+	if err != nil {
+		t.Fatalf("SyncFilesToBranch failed: %v", err)
+	}
+	if !committed {
+		t.Fatalf("expected a commit for changed files")
+	}
+
+	if branchHead(t, repoURL) != head {
+		t.Errorf("expected the base branch to be left untouched")
+	}
+	repo, err := git.PlainOpen(repoURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("claim/default/api/2"), true)
+	if err != nil {
+		t.Fatalf("expected the target branch to be pushed: %v", err)
+	}
+	if ref.Hash().String() != sha {
+		t.Errorf("expected target branch at %s, got %s", sha, ref.Hash())
+	}
+
+	// Files matching the base branch need no commit
+	sha, committed, err = c.SyncFilesToBranch(context.Background(), repoURL, "main", "claim/default/api/3",
+		[]string{"envs/prod/api"}, map[string]string{"envs/prod/api/values.yaml": "replicaCount: 1\n"},
+		"noop", "test", "test@local")
+	if err != nil || committed || sha != head.String() {
+		t.Errorf("expected no commit and the base head %s, got %s, %v, %v", head, sha, committed, err)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName("claim/default/api/3"), true); err == nil {
+		t.Errorf("expected no branch to be pushed without changes")
+	}
+}
+
+func TestPullRequests(t *testing.T) {
+	var closed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/v1/repos/acme/voltran/pulls":
+			var opts CreatePullRequestOptions
+			_ = json.NewDecoder(r.Body).Decode(&opts)
+			if opts.Head == "claim/default/api/1" {
+				// Already open for this head
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(PullRequest{Number: 7, State: "open", HTMLURL: "http://gitea/acme/voltran/pulls/7",
+				Head: PullRequestBranch{Ref: opts.Head}, Base: PullRequestBranch{Ref: opts.Base}})
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/acme/voltran/pulls":
+			_ = json.NewEncoder(w).Encode([]PullRequest{
+				{Number: 3, State: "open", Head: PullRequestBranch{Ref: "other"}, Base: PullRequestBranch{Ref: "main"}},
+				{Number: 5, State: "open", Head: PullRequestBranch{Ref: "claim/default/api/1"}, Base: PullRequestBranch{Ref: "main"}},
+			})
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/acme/voltran/pulls/7":
+			_ = json.NewEncoder(w).Encode(PullRequest{Number: 7, State: "closed", Merged: true, MergeCommitSHA: "abc123"})
+		case r.Method == "PATCH" && r.URL.Path == "/api/v1/repos/acme/voltran/pulls/5":
+			closed = true
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewClient(server.URL, "operator", "secret")
+
+	pr, err := c.CreatePullRequest(ctx, "acme", "voltran", CreatePullRequestOptions{Head: "claim/default/api/2", Base: "main", Title: "update"})
+	if err != nil || pr.Number != 7 || pr.HTMLURL == "" {
+		t.Fatalf("unexpected created pull request %+v, %v", pr, err)
+	}
+
+	pr, err = c.CreatePullRequest(ctx, "acme", "voltran", CreatePullRequestOptions{Head: "claim/default/api/1", Base: "main", Title: "update"})
+	if err != nil || pr.Number != 5 {
+		t.Fatalf("expected the already open pull request 5, got %+v, %v", pr, err)
+	}
+
+	pr, err = c.GetPullRequest(ctx, "acme", "voltran", 7)
+	if err != nil || !pr.Merged || pr.MergeCommitSHA != "abc123" {
+		t.Fatalf("unexpected pull request %+v, %v", pr, err)
+	}
+
+	if err := c.ClosePullRequest(ctx, "acme", "voltran", 5); err != nil || !closed {
+		t.Fatalf("expected pull request 5 to be closed, got %v", err)
+	}
+	if _, err := c.GetPullRequest(ctx, "acme", "voltran", 9); err == nil {
+		t.Errorf("expected an error for a missing pull request")
+	}
+}
+
+func TestWithSSHKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(block)
+
+	c := NewClient("http://gitea.local:3000/", "bot", "token")
+	if _, err := c.WithSSHKey("http://gitea.local:22", privateKey, nil); err == nil {
+		t.Errorf("expected a non-ssh URL to be rejected")
+	}
+	if _, err := c.WithSSHKey("ssh://git@gitea-ssh.local:22", []byte("not a key"), nil); err == nil {
+		t.Errorf("expected an invalid private key to be rejected")
+	}
+	if _, err := c.WithSSHKey("ssh://git@gitea-ssh.local:22", privateKey, []byte("# nothing\n")); err == nil {
+		t.Errorf("expected known_hosts without keys to be rejected")
+	}
+
+	hostKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := []byte("gitea-ssh.local " + string(ssh.MarshalAuthorizedKey(hostKey)))
+	if _, err := c.WithSSHKey("ssh://git@gitea-ssh.local:22/", privateKey, knownHosts); err != nil {
+		t.Fatalf("WithSSHKey failed: %v", err)
+	}
+
+	if got := c.gitURL(c.ConstructCloneURL("acme", "voltran")); got != "ssh://git@gitea-ssh.local:22/acme/voltran.git" {
+		t.Errorf("unexpected Git URL %q", got)
+	}
+	if got := c.gitURL("https://github.com/acme/charts.git"); got != "https://github.com/acme/charts.git" {
+		t.Errorf("expected URLs of other servers to be kept, got %q", got)
+	}
+	if err := c.sshAuth.HostKeyCallback("gitea-ssh.local:22", nil, hostKey); err != nil {
+		t.Errorf("expected the known host key to be accepted: %v", err)
+	}
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewPublicKey(other.Public())
+	if err := c.sshAuth.HostKeyCallback("gitea-ssh.local:22", nil, otherKey); err == nil {
+		t.Errorf("expected an unknown host key to be rejected")
+	}
+}
@@ -0,0 +1,201 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/storage"
+)
+
+const (
+	// DefaultWriteTimeout bounds a batch, which runs detached from the contexts of its writers
+	DefaultWriteTimeout = 2 * time.Minute
+
+	// DefaultPushAttempts pushes tried per write before a rejected push is returned
+	DefaultPushAttempts = 5
+
+	// DefaultRetryDelay delay before the second push attempt; it grows with every attempt
+	DefaultRetryDelay = 200 * time.Millisecond
+)
+
+// errBranchMoved marks a push rejected because the branch moved since it was cloned
+var errBranchMoved = errors.New("branch moved since the clone")
+
+// DefaultWriteCoordinator serializes the writes of every Client without its own coordinator
+var DefaultWriteCoordinator = NewWriteCoordinator(WriteOptions{})
+
+// WriteOptions configure a WriteCoordinator
+type WriteOptions struct {
+	// MaxAttempts pushes tried per write, DefaultPushAttempts when zero
+	MaxAttempts int
+
+	// RetryDelay delay before the second attempt, DefaultRetryDelay when zero
+	RetryDelay time.Duration
+
+	// Timeout bound of a batch including its retries, DefaultWriteTimeout when zero
+	Timeout time.Duration
+
+	// Batch merges the writes queued for the same branch into one commit
+	Batch bool
+}
+
+// WriteCoordinator serializes writes per repository and branch. A push rejected because the
+// branch moved (a writer of another operator replica or a person) is retried with the files
+// re-applied on top of the new head; the files are the desired state, so re-applying them
+// is the rebase.
+type WriteCoordinator struct {
+	opts WriteOptions
+
+	mu     sync.Mutex
+	queues map[string][]*writeRequest
+}
+
+// writeRequest one SyncFilesToBranch call waiting for its turn
+type writeRequest struct {
+	ctx          context.Context
+	client       *Client
+	repoURL      string
+	baseBranch   string
+	targetBranch string
+	owned        []string
+	files        map[string]string
+	commitMsg    string
+	authorName   string
+	authorEmail  string
+	done         chan writeResult
+}
+
+// writeResult outcome of a writeRequest
+type writeResult struct {
+	sha     string
+	changed bool
+	err     error
+}
+
+// NewWriteCoordinator creates a WriteCoordinator
+func NewWriteCoordinator(opts WriteOptions) *WriteCoordinator {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultPushAttempts
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultRetryDelay
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultWriteTimeout
+	}
+	return &WriteCoordinator{opts: opts, queues: map[string][]*writeRequest{}}
+}
+
+// submit queues a write and waits for its result. The first writer of an idle branch starts a
+// worker that drains the branch queue; the worker stops once the queue is empty.
+func (wc *WriteCoordinator) submit(req *writeRequest) (string, bool, error) {
+	req.done = make(chan writeResult, 1)
+	key := req.repoURL + "#" + req.targetBranch
+
+	wc.mu.Lock()
+	idle := len(wc.queues[key]) == 0
+	wc.queues[key] = append(wc.queues[key], req)
+	wc.mu.Unlock()
+	if idle {
+		go wc.drain(key)
+	}
+
+	// A writer that gives up stays queued; its files are still written with the batch
+	select {
+	case result := <-req.done:
+		return result.sha, result.changed, result.err
+	case <-req.ctx.Done():
+		return "", false, req.ctx.Err()
+	}
+}
+
+// drain writes the queued requests of a branch one push at a time; the request being written
+// stays at the head of the queue so that later writers do not start a second worker
+func (wc *WriteCoordinator) drain(key string) {
+	for {
+		wc.mu.Lock()
+		batch := wc.nextBatch(wc.queues[key])
+		wc.mu.Unlock()
+
+		sha, changed, err := wc.write(batch)
+		for _, req := range batch {
+			if ctxErr := req.ctx.Err(); ctxErr != nil {
+				req.done <- writeResult{err: ctxErr}
+				continue
+			}
+			req.done <- writeResult{sha: sha, changed: changed, err: err}
+		}
+
+		wc.mu.Lock()
+		remaining := wc.queues[key][len(batch):]
+		if len(remaining) == 0 {
+			delete(wc.queues, key)
+			wc.mu.Unlock()
+			return
+		}
+		wc.queues[key] = remaining
+		wc.mu.Unlock()
+	}
+}
+
+// nextBatch returns the requests written by the next push: the first one and, when batching,
+// the queued requests after it that share its base branch and credentials
+func (wc *WriteCoordinator) nextBatch(queue []*writeRequest) []*writeRequest {
+	n := 1
+	if wc.opts.Batch {
+		first := queue[0]
+		for n < len(queue) && queue[n].baseBranch == first.baseBranch &&
+			queue[n].client.credentialsID() == first.client.credentialsID() {
+			n++
+		}
+	}
+	return queue[:n:n]
+}
+
+// write pushes a batch, re-applying its files on the new head while the push is rejected. The
+// batch runs detached from the contexts of its writers, so that one writer giving up does not
+// fail the writes batched with it.
+func (wc *WriteCoordinator) write(batch []*writeRequest) (string, bool, error) {
+	first := batch[0]
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(first.ctx), wc.opts.Timeout)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		sha, changed, err := first.client.commitAndPush(ctx, batch)
+		if err == nil || !isPushConflict(err) || attempt >= wc.opts.MaxAttempts {
+			if err != nil && isPushConflict(err) {
+				err = fmt.Errorf("branch %s kept moving after %d attempts: %w", first.targetBranch, attempt, err)
+			}
+			return sha, changed, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case <-time.After(time.Duration(attempt) * wc.opts.RetryDelay):
+		}
+	}
+}
+
+// isPushConflict reports whether a push was rejected because the branch moved since the clone
+func isPushConflict(err error) bool {
+	return errors.Is(err, errBranchMoved) || errors.Is(err, storage.ErrReferenceHasChanged)
+}
+
+// batchCommitMessage returns the message of a request, or a summary listing the messages of
+// batched requests
+func batchCommitMessage(batch []*writeRequest) string {
+	if len(batch) == 1 {
+		return batch[0].commitMsg
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Apply %d queued changes by operator\n", len(batch))
+	for _, req := range batch {
+		subject, _, _ := strings.Cut(req.commitMsg, "\n")
+		fmt.Fprintf(&b, "\n- %s", subject)
+	}
+	return b.String()
+}
@@ -0,0 +1,242 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestWriteCoordinatorRacingWriters(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batch=%v", batch), func(t *testing.T) {
+			repoURL := newTestRepo(t, nil)
+			wc := NewWriteCoordinator(WriteOptions{Batch: batch})
+
+			const writers = 20
+			var wg sync.WaitGroup
+			errs := make(chan error, writers)
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					dir := fmt.Sprintf("apps/app-%d", i)
+					c := NewClient("", "", "").WithWriteCoordinator(wc)
+					_, err := c.SyncFiles(context.Background(), repoURL, "main", []string{dir},
+						map[string]string{dir + "/values.yaml": fmt.Sprintf("index: %d\n", i)},
+						fmt.Sprintf("Update app-%d", i), "test", "test@local")
+					errs <- err
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Errorf("write failed: %v", err)
+				}
+			}
+
+			files := readTestRepo(t, repoURL)
+			for i := 0; i < writers; i++ {
+				if files[fmt.Sprintf("apps/app-%d/values.yaml", i)] != fmt.Sprintf("index: %d\n", i) {
+					t.Errorf("missing the files of writer %d: %v", i, files)
+				}
+			}
+			if count := commitCount(t, repoURL); count > writers+1 || (!batch && count != writers+1) {
+				t.Errorf("unexpected number of commits %d", count)
+			}
+		})
+	}
+}
+
+// blockingEncoder blocks the first Encode call until release is closed
+type blockingEncoder struct {
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (e *blockingEncoder) Encode(path, content, previous string) (string, error) {
+	e.once.Do(func() {
+		close(e.entered)
+		<-e.release
+	})
+	return content, nil
+}
+
+func TestWriteCoordinatorBatchesQueuedWrites(t *testing.T) {
+	repoURL := newTestRepo(t, nil)
+	wc := NewWriteCoordinator(WriteOptions{Batch: true})
+	enc := &blockingEncoder{entered: make(chan struct{}), release: make(chan struct{})}
+
+	write := func(c *Client, name string, wg *sync.WaitGroup) {
+		defer wg.Done()
+		if _, err := c.SyncFiles(context.Background(), repoURL, "main", nil,
+			map[string]string{name + ".yaml": "x: 1\n"}, "Update "+name, "test", "test@local"); err != nil {
+			t.Errorf("write of %s failed: %v", name, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go write(NewClient("", "", "").WithWriteCoordinator(wc).WithFileEncoder(enc), "first", &wg)
+	<-enc.entered
+
+	// Queue two writers with the same credentials and one with other credentials behind the first
+	key := repoURL + "#main"
+	for i, w := range []struct{ name, token string }{{"second", ""}, {"third", ""}, {"other", "token"}} {
+		wg.Add(1)
+		go write(NewClient("", "", w.token).WithWriteCoordinator(wc), w.name, &wg)
+		waitQueued(t, wc, key, i+2)
+	}
+	close(enc.release)
+	wg.Wait()
+
+	// initial, first, second and third batched, other
+	if count := commitCount(t, repoURL); count != 4 {
+		t.Errorf("expected 4 commits, got %d", count)
+	}
+	repo, err := git.PlainOpen(repoURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, err := repo.Log(&git.LogOptions{From: branchHead(t, repoURL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for commit, err := iter.Next(); err == nil; commit, err = iter.Next() {
+		messages = append(messages, commit.Message)
+	}
+	if len(messages) < 2 || messages[1] != "Apply 2 queued changes by operator\n\n- Update second\n- Update third" {
+		t.Errorf("expected the second and third writes in one commit, got %q", messages)
+	}
+}
+
+func TestWriteCoordinatorBatchOutlivesCanceledWriter(t *testing.T) {
+	repoURL := newTestRepo(t, nil)
+	wc := NewWriteCoordinator(WriteOptions{Batch: true})
+	enc := &blockingEncoder{entered: make(chan struct{}), release: make(chan struct{})}
+
+	write := func(ctx context.Context, c *Client, name string) <-chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := c.SyncFiles(ctx, repoURL, "main", nil, map[string]string{name + ".yaml": "x: 1\n"},
+				"Update "+name, "test", "test@local")
+			errs <- err
+		}()
+		return errs
+	}
+
+	first := write(context.Background(), NewClient("", "", "").WithWriteCoordinator(wc).WithFileEncoder(enc), "first")
+	<-enc.entered
+
+	// The second writer leads the next batch and gives up before it runs
+	key := repoURL + "#main"
+	ctx, cancel := context.WithCancel(context.Background())
+	second := write(ctx, NewClient("", "", "").WithWriteCoordinator(wc), "second")
+	waitQueued(t, wc, key, 2)
+	third := write(context.Background(), NewClient("", "", "").WithWriteCoordinator(wc), "third")
+	waitQueued(t, wc, key, 3)
+	cancel()
+	if err := <-second; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled writer to return, got %v", err)
+	}
+	close(enc.release)
+
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-third; err != nil {
+		t.Errorf("expected the write batched with a canceled writer to succeed, got %v", err)
+	}
+	if files := readTestRepo(t, repoURL); files["third.yaml"] == "" {
+		t.Errorf("expected the batch to be pushed, got %v", files)
+	}
+}
+
+func TestIsPushConflict(t *testing.T) {
+	if !isPushConflict(fmt.Errorf("failed to push: %w: %w", errBranchMoved, errors.New("command error on refs/heads/main: fetch first"))) {
+		t.Error("expected a moved branch to be a conflict")
+	}
+	if isPushConflict(fmt.Errorf("failed to push: %w", errors.New("authentication required: non-fast-forward"))) {
+		t.Error("expected a rejection of a branch that did not move not to be a conflict")
+	}
+}
+
+// waitQueued waits until n writes are queued for key
+func waitQueued(t *testing.T, wc *WriteCoordinator, key string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		wc.mu.Lock()
+		queued := len(wc.queues[key])
+		wc.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued writes, got %d", n, queued)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// interferingEncoder pushes a commit of another writer to the branch on its first conflict calls,
+// after the coordinator cloned it and before it pushes
+type interferingEncoder struct {
+	t        *testing.T
+	repoURL  string
+	conflict int
+	calls    int
+}
+
+func (e *interferingEncoder) Encode(path, content, previous string) (string, error) {
+	e.calls++
+	if e.calls <= e.conflict {
+		other := NewClient("", "", "").WithWriteCoordinator(NewWriteCoordinator(WriteOptions{}))
+		if _, err := other.PushFiles(context.Background(), e.repoURL, "main",
+			map[string]string{fmt.Sprintf("other-%d.yaml", e.calls): "y: 1\n"}, "other writer", "other", "other@local"); err != nil {
+			e.t.Errorf("interfering push failed: %v", err)
+		}
+	}
+	return content, nil
+}
+
+func TestWriteCoordinatorReappliesRejectedPush(t *testing.T) {
+	repoURL := newTestRepo(t, nil)
+	enc := &interferingEncoder{t: t, repoURL: repoURL, conflict: 2}
+	wc := NewWriteCoordinator(WriteOptions{RetryDelay: time.Millisecond})
+	c := NewClient("", "", "").WithWriteCoordinator(wc).WithFileEncoder(enc)
+
+	sha, err := c.SyncFiles(context.Background(), repoURL, "main", nil, map[string]string{"mine.yaml": "x: 1\n"},
+		"mine", "test", "test@local")
+	if err != nil {
+		t.Fatalf("expected the write to be re-applied, got %v", err)
+	}
+	if enc.calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", enc.calls)
+	}
+	if head := branchHead(t, repoURL); head != plumbing.NewHash(sha) {
+		t.Errorf("expected the returned commit %s to be the head, got %s", sha, head)
+	}
+	files := readTestRepo(t, repoURL)
+	if files["mine.yaml"] != "x: 1\n" || files["other-1.yaml"] == "" || files["other-2.yaml"] == "" {
+		t.Errorf("expected both writers' files, got %v", files)
+	}
+
+	// Giving up after the last attempt
+	repoURL = newTestRepo(t, nil)
+	enc = &interferingEncoder{t: t, repoURL: repoURL, conflict: 5}
+	c = NewClient("", "", "").WithWriteCoordinator(NewWriteCoordinator(WriteOptions{MaxAttempts: 2, RetryDelay: time.Millisecond})).WithFileEncoder(enc)
+	_, err = c.SyncFiles(context.Background(), repoURL, "main", nil, map[string]string{"mine.yaml": "x: 2\n"},
+		"mine", "test", "test@local")
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("expected the write to give up after 2 attempts, got %v", err)
+	}
+}
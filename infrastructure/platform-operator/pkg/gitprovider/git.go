@@ -0,0 +1,54 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Git GitProvider for a plain Git remote without a hosting API: organizations and repositories
+// must exist already and changes cannot go through pull requests
+type Git struct {
+	gitFiles
+	baseURL string
+}
+
+// CreateOrganization does nothing; organizations are the directories of the remote
+func (g *Git) CreateOrganization(ctx context.Context, orgName, description string) error {
+	return nil
+}
+
+// CreateRepository returns the repository without creating it; pushes fail if it does not exist
+func (g *Git) CreateRepository(ctx context.Context, orgName string, opts CreateRepoOptions) (*Repository, error) {
+	return &Repository{
+		Name:        opts.Name,
+		FullName:    orgName + "/" + opts.Name,
+		Description: opts.Description,
+		CloneURL:    g.ConstructCloneURL(orgName, opts.Name),
+	}, nil
+}
+
+// ConstructCloneURL returns the URL of a repository on the remote
+func (g *Git) ConstructCloneURL(orgName, repoName string) string {
+	return fmt.Sprintf("%s/%s/%s.git", strings.TrimSuffix(g.baseURL, "/"), orgName, repoName)
+}
+
+// CommitURL returns an empty string; a plain remote has no web interface
+func (g *Git) CommitURL(orgName, repoName, sha string) string {
+	return ""
+}
+
+// CreatePullRequest returns ErrPullRequestsUnsupported
+func (g *Git) CreatePullRequest(ctx context.Context, orgName, repoName string, opts CreatePullRequestOptions) (*PullRequest, error) {
+	return nil, ErrPullRequestsUnsupported
+}
+
+// GetPullRequest returns ErrPullRequestsUnsupported
+func (g *Git) GetPullRequest(ctx context.Context, orgName, repoName string, number int64) (*PullRequest, error) {
+	return nil, ErrPullRequestsUnsupported
+}
+
+// ClosePullRequest returns ErrPullRequestsUnsupported
+func (g *Git) ClosePullRequest(ctx context.Context, orgName, repoName string, number int64) error {
+	return ErrPullRequestsUnsupported
+}
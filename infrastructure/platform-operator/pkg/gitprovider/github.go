@@ -0,0 +1,123 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/infraforge/platform-operator/pkg/gitea"
+)
+
+// GitHub GitProvider for github.com and GitHub Enterprise Server
+type GitHub struct {
+	gitFiles
+	baseURL  string
+	username string
+	api      apiClient
+}
+
+// newGitHub creates a GitHub provider; github.com is served by api.github.com, GitHub
+// Enterprise Server by /api/v3 on the server itself
+func newGitHub(cfg Config, git *gitea.Client) *GitHub {
+	baseURL := strings.TrimSuffix(cfg.URL, "/")
+	apiURL := baseURL + "/api/v3"
+	if u, err := url.Parse(baseURL); err == nil && u.Host == "github.com" {
+		apiURL = "https://api.github.com"
+	}
+	return &GitHub{
+		gitFiles: gitFiles{git: git},
+		baseURL:  baseURL,
+		username: cfg.Username,
+		api:      newAPIClient(apiURL, "Authorization", "Bearer "+cfg.Token),
+	}
+}
+
+// CreateOrganization creates a GitHub organization through the site admin API of GitHub
+// Enterprise Server; organizations on github.com must exist already
+func (g *GitHub) CreateOrganization(ctx context.Context, orgName, description string) error {
+	status, err := g.api.do(ctx, http.MethodGet, "/orgs/"+orgName, nil, nil, http.StatusOK)
+	if err == nil {
+		return nil
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	body := map[string]string{"login": orgName, "admin": g.username, "profile_name": description}
+	if _, err := g.api.do(ctx, http.MethodPost, "/admin/organizations", body, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// CreateRepository creates a repository in an organization
+func (g *GitHub) CreateRepository(ctx context.Context, orgName string, opts CreateRepoOptions) (*Repository, error) {
+	body := map[string]interface{}{
+		"name":        opts.Name,
+		"description": opts.Description,
+		"private":     opts.Private,
+		"auto_init":   opts.AutoInit,
+	}
+	var repo Repository
+	status, err := g.api.do(ctx, http.MethodPost, "/orgs/"+orgName+"/repos", body, &repo, http.StatusCreated)
+	if status == http.StatusUnprocessableEntity {
+		// Name already exists on this account
+		repo = Repository{}
+		_, err = g.api.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s", orgName, opts.Name), nil, &repo, http.StatusOK)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository: %w", err)
+	}
+	return &repo, nil
+}
+
+// ConstructCloneURL returns the HTTPS clone URL of a repository
+func (g *GitHub) ConstructCloneURL(orgName, repoName string) string {
+	return fmt.Sprintf("%s/%s/%s.git", g.baseURL, orgName, repoName)
+}
+
+// CommitURL returns the web URL of a commit
+func (g *GitHub) CommitURL(orgName, repoName, sha string) string {
+	return fmt.Sprintf("%s/%s/%s/commit/%s", g.baseURL, orgName, repoName, sha)
+}
+
+// CreatePullRequest opens a pull request, returning the open one from the same head if it exists
+func (g *GitHub) CreatePullRequest(ctx context.Context, orgName, repoName string, opts CreatePullRequestOptions) (*PullRequest, error) {
+	var pr PullRequest
+	status, err := g.api.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", orgName, repoName), opts, &pr, http.StatusCreated)
+	if status == http.StatusUnprocessableEntity {
+		// A pull request already exists for the head branch
+		var open []PullRequest
+		query := url.Values{"state": {"open"}, "head": {orgName + ":" + opts.Head}, "base": {opts.Base}}
+		if _, err := g.api.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls?%s", orgName, repoName, query.Encode()), nil, &open, http.StatusOK); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		if len(open) > 0 {
+			return &open[0], nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return &pr, nil
+}
+
+// GetPullRequest returns a pull request
+func (g *GitHub) GetPullRequest(ctx context.Context, orgName, repoName string, number int64) (*PullRequest, error) {
+	var pr PullRequest
+	if _, err := g.api.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls/%d", orgName, repoName, number), nil, &pr, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	return &pr, nil
+}
+
+// ClosePullRequest closes a pull request without merging it
+func (g *GitHub) ClosePullRequest(ctx context.Context, orgName, repoName string, number int64) error {
+	body := map[string]string{"state": "closed"}
+	if _, err := g.api.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/%s/pulls/%d", orgName, repoName, number), body, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to close pull request: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/infraforge/platform-operator/pkg/gitea"
+)
+
+// GitLab GitProvider for gitlab.com and self-managed GitLab; organizations are groups and pull
+// requests merge requests
+type GitLab struct {
+	gitFiles
+	baseURL string
+	api     apiClient
+}
+
+// gitlabProject GitLab project as returned by the API
+type gitlabProject struct {
+	ID                int64  `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	WebURL            string `json:"web_url"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+}
+
+// gitlabMergeRequest GitLab merge request as returned by the API
+type gitlabMergeRequest struct {
+	IID            int64  `json:"iid"`
+	Title          string `json:"title"`
+	WebURL         string `json:"web_url"`
+	State          string `json:"state"`
+	SHA            string `json:"sha"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	SquashSHA      string `json:"squash_commit_sha"`
+	SourceBranch   string `json:"source_branch"`
+	TargetBranch   string `json:"target_branch"`
+}
+
+// newGitLab creates a GitLab provider using the v4 API of the server
+func newGitLab(cfg Config, git *gitea.Client) *GitLab {
+	baseURL := strings.TrimSuffix(cfg.URL, "/")
+	return &GitLab{
+		gitFiles: gitFiles{git: git},
+		baseURL:  baseURL,
+		api:      newAPIClient(baseURL+"/api/v4", "PRIVATE-TOKEN", cfg.Token),
+	}
+}
+
+// CreateOrganization creates a top-level group
+func (g *GitLab) CreateOrganization(ctx context.Context, orgName, description string) error {
+	if _, err := g.group(ctx, orgName); err == nil {
+		return nil
+	}
+
+	body := map[string]string{"name": orgName, "path": orgName, "description": description, "visibility": "public"}
+	if _, err := g.api.do(ctx, http.MethodPost, "/groups", body, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+	return nil
+}
+
+// CreateRepository creates a project in the group of the organization
+func (g *GitLab) CreateRepository(ctx context.Context, orgName string, opts CreateRepoOptions) (*Repository, error) {
+	groupID, err := g.group(ctx, orgName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	visibility := "public"
+	if opts.Private {
+		visibility = "private"
+	}
+	body := map[string]interface{}{
+		"name":                   opts.Name,
+		"path":                   opts.Name,
+		"namespace_id":           groupID,
+		"description":            opts.Description,
+		"visibility":             visibility,
+		"initialize_with_readme": opts.AutoInit,
+	}
+	if opts.DefaultBranch != "" {
+		body["default_branch"] = opts.DefaultBranch
+	}
+
+	var project gitlabProject
+	status, err := g.api.do(ctx, http.MethodPost, "/projects", body, &project, http.StatusCreated)
+	if status == http.StatusBadRequest {
+		// Path has already been taken
+		project = gitlabProject{}
+		_, err = g.api.do(ctx, http.MethodGet, "/projects/"+projectID(orgName, opts.Name), nil, &project, http.StatusOK)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository: %w", err)
+	}
+	return &Repository{
+		ID:          project.ID,
+		Name:        project.Name,
+		FullName:    project.PathWithNamespace,
+		Description: project.Description,
+		HTMLURL:     project.WebURL,
+		CloneURL:    project.HTTPURLToRepo,
+		SSHURL:      project.SSHURLToRepo,
+	}, nil
+}
+
+// group returns the ID of a group
+func (g *GitLab) group(ctx context.Context, path string) (int64, error) {
+	var group struct {
+		ID int64 `json:"id"`
+	}
+	if _, err := g.api.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(path), nil, &group, http.StatusOK); err != nil {
+		return 0, err
+	}
+	return group.ID, nil
+}
+
+// ConstructCloneURL returns the HTTPS clone URL of a repository
+func (g *GitLab) ConstructCloneURL(orgName, repoName string) string {
+	return fmt.Sprintf("%s/%s/%s.git", g.baseURL, orgName, repoName)
+}
+
+// CommitURL returns the web URL of a commit
+func (g *GitLab) CommitURL(orgName, repoName, sha string) string {
+	return fmt.Sprintf("%s/%s/%s/-/commit/%s", g.baseURL, orgName, repoName, sha)
+}
+
+// CreatePullRequest opens a merge request, returning the open one from the same source branch if it exists
+func (g *GitLab) CreatePullRequest(ctx context.Context, orgName, repoName string, opts CreatePullRequestOptions) (*PullRequest, error) {
+	body := map[string]string{
+		"source_branch": opts.Head,
+		"target_branch": opts.Base,
+		"title":         opts.Title,
+		"description":   opts.Body,
+	}
+	path := "/projects/" + projectID(orgName, repoName) + "/merge_requests"
+	var mr gitlabMergeRequest
+	status, err := g.api.do(ctx, http.MethodPost, path, body, &mr, http.StatusCreated)
+	if status == http.StatusConflict {
+		// A merge request already exists for the source branch
+		var open []gitlabMergeRequest
+		query := url.Values{"state": {"opened"}, "source_branch": {opts.Head}, "target_branch": {opts.Base}}
+		if _, err := g.api.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &open, http.StatusOK); err != nil {
+			return nil, fmt.Errorf("failed to list merge requests: %w", err)
+		}
+		if len(open) > 0 {
+			return open[0].pullRequest(), nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return mr.pullRequest(), nil
+}
+
+// GetPullRequest returns a merge request
+func (g *GitLab) GetPullRequest(ctx context.Context, orgName, repoName string, number int64) (*PullRequest, error) {
+	var mr gitlabMergeRequest
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", projectID(orgName, repoName), number)
+	if _, err := g.api.do(ctx, http.MethodGet, path, nil, &mr, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to get merge request: %w", err)
+	}
+	return mr.pullRequest(), nil
+}
+
+// ClosePullRequest closes a merge request without merging it
+func (g *GitLab) ClosePullRequest(ctx context.Context, orgName, repoName string, number int64) error {
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", projectID(orgName, repoName), number)
+	if _, err := g.api.do(ctx, http.MethodPut, path, map[string]string{"state_event": "close"}, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to close merge request: %w", err)
+	}
+	return nil
+}
+
+// pullRequest maps a merge request to a PullRequest; opened is open, merged is closed and merged
+func (mr gitlabMergeRequest) pullRequest() *PullRequest {
+	pr := &PullRequest{
+		Number:  mr.IID,
+		Title:   mr.Title,
+		HTMLURL: mr.WebURL,
+		State:   "closed",
+		Head:    PullRequestBranch{Ref: mr.SourceBranch, SHA: mr.SHA},
+		Base:    PullRequestBranch{Ref: mr.TargetBranch},
+	}
+	switch mr.State {
+	case "opened", "locked":
+		pr.State = "open"
+	case "merged":
+		pr.Merged = true
+		pr.MergeCommitSHA = mr.MergeCommitSHA
+		if pr.MergeCommitSHA == "" {
+			// Squashed and fast-forwarded merge requests have no merge commit
+			pr.MergeCommitSHA = mr.SquashSHA
+		}
+		if pr.MergeCommitSHA == "" {
+			pr.MergeCommitSHA = mr.SHA
+		}
+	}
+	return pr
+}
+
+// projectID returns the URL-encoded path of a project, which the API accepts in place of its ID
+func projectID(orgName, repoName string) string {
+	return url.PathEscape(orgName + "/" + repoName)
+}
@@ -0,0 +1,220 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/infraforge/platform-operator/pkg/gitea"
+)
+
+// Local GitProvider keeping bare repositories in a local directory and pull requests in memory,
+// for tests. Pull requests are merged with MergePullRequest.
+type Local struct {
+	Git
+	dir string
+
+	mu           sync.Mutex
+	pullRequests map[string][]PullRequest
+}
+
+var _ GitProvider = &Local{}
+
+// NewLocal creates a Local provider keeping repository org/repo in dir/org/repo.git
+func NewLocal(dir string) *Local {
+	return &Local{
+		Git:          Git{gitFiles: gitFiles{git: gitea.NewClient(dir, "", "")}, baseURL: dir},
+		dir:          dir,
+		pullRequests: map[string][]PullRequest{},
+	}
+}
+
+// WithFileEncoder encodes every written file with enc
+func (l *Local) WithFileEncoder(enc FileEncoder) *Local {
+	l.git.WithFileEncoder(enc)
+	return l
+}
+
+// CreateRepository creates a bare repository, with an initial commit on the default branch when
+// opts.AutoInit is set
+func (l *Local) CreateRepository(ctx context.Context, orgName string, opts CreateRepoOptions) (*Repository, error) {
+	repo, _ := l.Git.CreateRepository(ctx, orgName, opts)
+	path := l.repoPath(orgName, opts.Name)
+	if _, err := os.Stat(path); err == nil {
+		return repo, nil
+	}
+
+	if _, err := git.PlainInit(path, true); err != nil {
+		return nil, fmt.Errorf("failed to create repository: %w", err)
+	}
+	if !opts.AutoInit {
+		return repo, nil
+	}
+
+	branch := opts.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+	if err := initBranch(ctx, path, branch, opts.Name); err != nil {
+		return nil, fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	return repo, nil
+}
+
+// initBranch pushes a commit with a README to branch of the bare repository at path
+func initBranch(ctx context.Context, path, branch, name string) error {
+	work, err := os.MkdirTemp("", "gitprovider-init-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	repo, err := git.PlainInit(work, false)
+	if err != nil {
+		return err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(work, "README.md"), []byte("# "+name+"\n"), 0644); err != nil {
+		return err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Add("README.md"); err != nil {
+		return err
+	}
+	if _, err := w.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "gitprovider", Email: "gitprovider@local", When: time.Now()},
+	}); err != nil {
+		return err
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{path}}); err != nil {
+		return err
+	}
+	return repo.PushContext(ctx, &git.PushOptions{RemoteName: "origin"})
+}
+
+// CommitURL returns a file URL of the commit
+func (l *Local) CommitURL(orgName, repoName, sha string) string {
+	return fmt.Sprintf("file://%s#%s", l.repoPath(orgName, repoName), sha)
+}
+
+// CreatePullRequest opens a pull request, returning the open one from the same head into the same base if it exists
+func (l *Local) CreatePullRequest(ctx context.Context, orgName, repoName string, opts CreatePullRequestOptions) (*PullRequest, error) {
+	head, err := l.branchHead(orgName, repoName, opts.Head)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := orgName + "/" + repoName
+	for i := range l.pullRequests[key] {
+		pr := &l.pullRequests[key][i]
+		if pr.State == "open" && pr.Head.Ref == opts.Head && pr.Base.Ref == opts.Base {
+			pr.Head.SHA = head
+			copied := *pr
+			return &copied, nil
+		}
+	}
+
+	pr := PullRequest{
+		Number:  int64(len(l.pullRequests[key]) + 1),
+		Title:   opts.Title,
+		HTMLURL: fmt.Sprintf("file://%s#pull/%d", l.repoPath(orgName, repoName), len(l.pullRequests[key])+1),
+		State:   "open",
+		Head:    PullRequestBranch{Ref: opts.Head, SHA: head},
+		Base:    PullRequestBranch{Ref: opts.Base},
+	}
+	l.pullRequests[key] = append(l.pullRequests[key], pr)
+	return &pr, nil
+}
+
+// GetPullRequest returns a pull request
+func (l *Local) GetPullRequest(ctx context.Context, orgName, repoName string, number int64) (*PullRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pr, err := l.pullRequest(orgName, repoName, number)
+	if err != nil {
+		return nil, err
+	}
+	copied := *pr
+	return &copied, nil
+}
+
+// ClosePullRequest closes a pull request without merging it
+func (l *Local) ClosePullRequest(ctx context.Context, orgName, repoName string, number int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pr, err := l.pullRequest(orgName, repoName, number)
+	if err != nil {
+		return err
+	}
+	pr.State = "closed"
+	return nil
+}
+
+// MergePullRequest merges an open pull request by pointing its base branch at the head commit
+func (l *Local) MergePullRequest(orgName, repoName string, number int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pr, err := l.pullRequest(orgName, repoName, number)
+	if err != nil {
+		return err
+	}
+	if pr.State != "open" {
+		return fmt.Errorf("pull request %d is %s", number, pr.State)
+	}
+
+	repo, err := git.PlainOpen(l.repoPath(orgName, repoName))
+	if err != nil {
+		return err
+	}
+	head, err := repo.Reference(plumbing.NewBranchReferenceName(pr.Head.Ref), true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", pr.Head.Ref, err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(pr.Base.Ref), head.Hash())); err != nil {
+		return fmt.Errorf("failed to update %s: %w", pr.Base.Ref, err)
+	}
+	pr.State, pr.Merged, pr.MergeCommitSHA = "closed", true, head.Hash().String()
+	return nil
+}
+
+// pullRequest returns the stored pull request; l.mu must be held
+func (l *Local) pullRequest(orgName, repoName string, number int64) (*PullRequest, error) {
+	prs := l.pullRequests[orgName+"/"+repoName]
+	if number < 1 || number > int64(len(prs)) {
+		return nil, fmt.Errorf("pull request %d of %s/%s not found", number, orgName, repoName)
+	}
+	return &prs[number-1], nil
+}
+
+// branchHead returns the commit SHA of a branch
+func (l *Local) branchHead(orgName, repoName, branch string) (string, error) {
+	repo, err := git.PlainOpen(l.repoPath(orgName, repoName))
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository %s/%s: %w", orgName, repoName, err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", branch, err)
+	}
+	return ref.Hash().String(), nil
+}
+
+// repoPath returns the directory of a bare repository
+func (l *Local) repoPath(orgName, repoName string) string {
+	return filepath.Join(l.dir, orgName, repoName+".git")
+}
@@ -0,0 +1,207 @@
+// Package gitprovider puts the Git hosting services the operator writes GitOps repositories to
+// behind one interface. Gitea, GitHub, GitLab and plain Git remotes differ in how organizations,
+// repositories and pull requests are created; files are always synced with Git itself.
+package gitprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/infraforge/platform-operator/pkg/gitea"
+)
+
+// Provider names accepted by New and spec.gitProvider of the claims
+const (
+	ProviderGitea  = "gitea"
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGit    = "git"
+)
+
+// ErrPullRequestsUnsupported is returned by providers that cannot open pull requests
+var ErrPullRequestsUnsupported = errors.New("pull requests are not supported by this Git provider")
+
+// Types shared by every provider; they are the Gitea API types, whose fields all providers can fill
+type (
+	Repository               = gitea.Repository
+	CreateRepoOptions        = gitea.CreateRepoOptions
+	PullRequest              = gitea.PullRequest
+	PullRequestBranch        = gitea.PullRequestBranch
+	CreatePullRequestOptions = gitea.CreatePullRequestOptions
+	FileEncoder              = gitea.FileEncoder
+)
+
+// GitProvider Git hosting service of the GitOps repositories
+type GitProvider interface {
+	// CreateOrganization creates the organization (GitHub organization, GitLab group) if it does not exist
+	CreateOrganization(ctx context.Context, orgName, description string) error
+
+	// CreateRepository creates a repository in an organization, returning the existing one if it exists
+	CreateRepository(ctx context.Context, orgName string, opts CreateRepoOptions) (*Repository, error)
+
+	// ConstructCloneURL returns the URL the operator clones a repository from
+	ConstructCloneURL(orgName, repoName string) string
+
+	// CommitURL returns the web URL of a commit, empty when the provider has no web interface
+	CommitURL(orgName, repoName, sha string) string
+
+	// PushFiles, SyncFiles, SyncFilesToBranch, DiffFiles and CloneAndExtractFiles behave as the
+	// gitea.Client methods of the same name
+	PushFiles(ctx context.Context, repoURL, branch string, files map[string]string, commitMsg, authorName, authorEmail string) (string, error)
+	SyncFiles(ctx context.Context, repoURL, branch string, owned []string, files map[string]string, commitMsg, authorName, authorEmail string) (string, error)
+	SyncFilesToBranch(ctx context.Context, repoURL, baseBranch, targetBranch string, owned []string, files map[string]string, commitMsg, authorName, authorEmail string) (string, bool, error)
+	DiffFiles(ctx context.Context, repoURL, branch string, owned []string, files map[string]string) ([]string, string, error)
+	CloneAndExtractFiles(ctx context.Context, repoURL, branch, subPath string) (map[string]string, error)
+
+	// CreatePullRequest opens a pull request, returning the open one from the same head into the same base if it exists
+	CreatePullRequest(ctx context.Context, orgName, repoName string, opts CreatePullRequestOptions) (*PullRequest, error)
+
+	// GetPullRequest returns a pull request; State is open or closed and Merged is set once merged
+	GetPullRequest(ctx context.Context, orgName, repoName string, number int64) (*PullRequest, error)
+
+	// ClosePullRequest closes a pull request without merging it
+	ClosePullRequest(ctx context.Context, orgName, repoName string, number int64) error
+}
+
+var _ GitProvider = &gitea.Client{}
+
+// Config selects and configures a GitProvider
+type Config struct {
+	// Provider gitea, github, gitlab or git; gitea when empty
+	Provider string
+
+	// URL web URL of the server, e.g. https://github.example.com; repositories are cloned from URL/org/repo.git
+	URL string
+
+	// Username and Token authenticate API calls and Git over HTTP
+	Username string
+	Token    string
+
+	// SSHURL, SSHPrivateKey and KnownHosts replace HTTP with SSH for Git operations when SSHPrivateKey is set
+	SSHURL        string
+	SSHPrivateKey []byte
+	KnownHosts    []byte
+
+	// Encoder encodes every written file, nil to write files as is
+	Encoder FileEncoder
+}
+
+// New returns the GitProvider selected by cfg.Provider
+func New(cfg Config) (GitProvider, error) {
+	git := gitea.NewClient(cfg.URL, cfg.Username, cfg.Token)
+	if cfg.Encoder != nil {
+		git.WithFileEncoder(cfg.Encoder)
+	}
+	if len(cfg.SSHPrivateKey) > 0 {
+		if _, err := git.WithSSHKey(cfg.SSHURL, cfg.SSHPrivateKey, cfg.KnownHosts); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.Provider {
+	case "", ProviderGitea:
+		return git, nil
+	case ProviderGitHub:
+		return newGitHub(cfg, git), nil
+	case ProviderGitLab:
+		return newGitLab(cfg, git), nil
+	case ProviderGit:
+		return &Git{gitFiles: gitFiles{git: git}, baseURL: cfg.URL}, nil
+	default:
+		return nil, fmt.Errorf("unknown Git provider %q", cfg.Provider)
+	}
+}
+
+// gitFiles syncs files with Git over HTTP or SSH for the providers whose API has no part in it
+type gitFiles struct {
+	git *gitea.Client
+}
+
+// PushFiles writes files to a branch in one commit
+func (f gitFiles) PushFiles(ctx context.Context, repoURL, branch string, files map[string]string, commitMsg, authorName, authorEmail string) (string, error) {
+	return f.git.PushFiles(ctx, repoURL, branch, files, commitMsg, authorName, authorEmail)
+}
+
+// SyncFiles makes the owned paths of a branch match files in one commit
+func (f gitFiles) SyncFiles(ctx context.Context, repoURL, branch string, owned []string, files map[string]string, commitMsg, authorName, authorEmail string) (string, error) {
+	return f.git.SyncFiles(ctx, repoURL, branch, owned, files, commitMsg, authorName, authorEmail)
+}
+
+// SyncFilesToBranch syncs files on top of baseBranch and pushes the result to targetBranch
+func (f gitFiles) SyncFilesToBranch(ctx context.Context, repoURL, baseBranch, targetBranch string, owned []string, files map[string]string, commitMsg, authorName, authorEmail string) (string, bool, error) {
+	return f.git.SyncFilesToBranch(ctx, repoURL, baseBranch, targetBranch, owned, files, commitMsg, authorName, authorEmail)
+}
+
+// DiffFiles returns the paths of a branch that differ from files
+func (f gitFiles) DiffFiles(ctx context.Context, repoURL, branch string, owned []string, files map[string]string) ([]string, string, error) {
+	return f.git.DiffFiles(ctx, repoURL, branch, owned, files)
+}
+
+// CloneAndExtractFiles returns the files under subPath of a branch
+func (f gitFiles) CloneAndExtractFiles(ctx context.Context, repoURL, branch, subPath string) (map[string]string, error) {
+	return f.git.CloneAndExtractFiles(ctx, repoURL, branch, subPath)
+}
+
+// apiClient JSON REST API of a provider
+type apiClient struct {
+	baseURL    string
+	header     string
+	value      string
+	httpClient *http.Client
+}
+
+// newAPIClient creates an apiClient sending header: value with every request
+func newAPIClient(baseURL, header, value string) apiClient {
+	return apiClient{baseURL: baseURL, header: header, value: value, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// do sends body as JSON and decodes the response into out when its status is one of ok; other
+// statuses return the status code with an error
+func (a apiClient) do(ctx context.Context, method, path string, body, out interface{}, ok ...int) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if a.value != "" {
+		req.Header.Set(a.header, a.value)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	for _, status := range ok {
+		if resp.StatusCode != status {
+			continue
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+			}
+		}
+		return resp.StatusCode, nil
+	}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+}
@@ -0,0 +1,204 @@
+package gitprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for provider, want := range map[string]string{
+		"":             "*gitea.Client",
+		ProviderGitea:  "*gitea.Client",
+		ProviderGitHub: "*gitprovider.GitHub",
+		ProviderGitLab: "*gitprovider.GitLab",
+		ProviderGit:    "*gitprovider.Git",
+	} {
+		p, err := New(Config{Provider: provider, URL: "https://git.example.com"})
+		if err != nil {
+			t.Fatalf("%q: %v", provider, err)
+		}
+		if got := fmt.Sprintf("%T", p); got != want {
+			t.Errorf("%q: expected %s, got %T", provider, want, p)
+		}
+	}
+	if _, err := New(Config{Provider: "bitbucket"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+// writeJSON writes v with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestGitHub(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.RequestURI() {
+		case "GET /api/v3/orgs/acme":
+			writeJSON(w, http.StatusOK, map[string]string{"login": "acme"})
+		case "POST /api/v3/orgs/acme/repos":
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "name already exists on this account"})
+		case "GET /api/v3/repos/acme/voltran":
+			writeJSON(w, http.StatusOK, Repository{Name: "voltran", FullName: "acme/voltran"})
+		case "POST /api/v3/repos/acme/voltran/pulls":
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "A pull request already exists"})
+		case "GET /api/v3/repos/acme/voltran/pulls?base=main&head=acme%3Aclaim%2Fshop&state=open":
+			writeJSON(w, http.StatusOK, []PullRequest{{Number: 4, State: "open"}})
+		case "GET /api/v3/repos/acme/voltran/pulls/4":
+			writeJSON(w, http.StatusOK, PullRequest{Number: 4, State: "closed", Merged: true, MergeCommitSHA: "f00d"})
+		case "PATCH /api/v3/repos/acme/voltran/pulls/4":
+			writeJSON(w, http.StatusOK, PullRequest{Number: 4, State: "closed"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(Config{Provider: ProviderGitHub, URL: server.URL, Username: "operator", Token: "t0ken"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := p.CreateOrganization(ctx, "acme", "Platform organization"); err != nil {
+		t.Errorf("expected the existing organization to be kept, got %v", err)
+	}
+	if repo, err := p.CreateRepository(ctx, "acme", CreateRepoOptions{Name: "voltran"}); err != nil || repo.FullName != "acme/voltran" {
+		t.Errorf("expected the existing repository, got %+v, %v", repo, err)
+	}
+	pr, err := p.CreatePullRequest(ctx, "acme", "voltran", CreatePullRequestOptions{Head: "claim/shop", Base: "main"})
+	if err != nil || pr.Number != 4 {
+		t.Fatalf("expected the open pull request, got %+v, %v", pr, err)
+	}
+	if pr, err = p.GetPullRequest(ctx, "acme", "voltran", 4); err != nil || !pr.Merged || pr.MergeCommitSHA != "f00d" {
+		t.Errorf("expected a merged pull request, got %+v, %v", pr, err)
+	}
+	if err := p.ClosePullRequest(ctx, "acme", "voltran", 4); err != nil {
+		t.Error(err)
+	}
+	if url := p.ConstructCloneURL("acme", "voltran"); url != server.URL+"/acme/voltran.git" {
+		t.Errorf("unexpected clone URL %s", url)
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	groupCreated := false
+	mergeRequest := gitlabMergeRequest{IID: 3, State: "opened", SHA: "head", SourceBranch: "claim/shop", TargetBranch: "main"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/groups/acme":
+			if !groupCreated {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]int64{"id": 7})
+		case "POST /api/v4/groups":
+			groupCreated = true
+			writeJSON(w, http.StatusCreated, map[string]int64{"id": 7})
+		case "POST /api/v4/projects":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["namespace_id"] != float64(7) || body["visibility"] != "private" {
+				t.Errorf("unexpected project %v", body)
+			}
+			writeJSON(w, http.StatusCreated, gitlabProject{ID: 9, Name: "voltran", PathWithNamespace: "acme/voltran", HTTPURLToRepo: "https://gitlab/acme/voltran.git"})
+		case "POST /api/v4/projects/acme%2Fvoltran/merge_requests":
+			writeJSON(w, http.StatusCreated, mergeRequest)
+		case "GET /api/v4/projects/acme%2Fvoltran/merge_requests/3":
+			writeJSON(w, http.StatusOK, mergeRequest)
+		case "PUT /api/v4/projects/acme%2Fvoltran/merge_requests/3":
+			writeJSON(w, http.StatusOK, mergeRequest)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(Config{Provider: ProviderGitLab, URL: server.URL, Token: "t0ken"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := p.CreateOrganization(ctx, "acme", "Platform organization"); err != nil || !groupCreated {
+		t.Fatalf("expected the group to be created, got %v", err)
+	}
+	repo, err := p.CreateRepository(ctx, "acme", CreateRepoOptions{Name: "voltran", Private: true})
+	if err != nil || repo.FullName != "acme/voltran" || repo.CloneURL != "https://gitlab/acme/voltran.git" {
+		t.Errorf("unexpected repository %+v, %v", repo, err)
+	}
+
+	pr, err := p.CreatePullRequest(ctx, "acme", "voltran", CreatePullRequestOptions{Head: "claim/shop", Base: "main"})
+	if err != nil || pr.Number != 3 || pr.State != "open" || pr.Head.Ref != "claim/shop" {
+		t.Fatalf("expected an open merge request, got %+v, %v", pr, err)
+	}
+	mergeRequest.State, mergeRequest.MergeCommitSHA = "merged", "f00d"
+	if pr, err = p.GetPullRequest(ctx, "acme", "voltran", 3); err != nil || pr.State != "closed" || !pr.Merged || pr.MergeCommitSHA != "f00d" {
+		t.Errorf("expected a merged merge request, got %+v, %v", pr, err)
+	}
+	if err := p.ClosePullRequest(ctx, "acme", "voltran", 3); err != nil {
+		t.Error(err)
+	}
+	if url := p.CommitURL("acme", "voltran", "f00d"); url != server.URL+"/acme/voltran/-/commit/f00d" {
+		t.Errorf("unexpected commit URL %s", url)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	p := NewLocal(t.TempDir())
+	ctx := context.Background()
+
+	if _, err := p.CreateRepository(ctx, "acme", CreateRepoOptions{Name: "voltran", AutoInit: true, DefaultBranch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	repoURL := p.ConstructCloneURL("acme", "voltran")
+	if _, _, err := p.SyncFilesToBranch(ctx, repoURL, "main", "claim/shop", nil, map[string]string{"apps/shop.yaml": "x: 1\n"},
+		"Add shop", "test", "test@local"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := CreatePullRequestOptions{Head: "claim/shop", Base: "main", Title: "Add shop"}
+	pr, err := p.CreatePullRequest(ctx, "acme", "voltran", opts)
+	if err != nil || pr.Number != 1 || pr.State != "open" {
+		t.Fatalf("expected an open pull request, got %+v, %v", pr, err)
+	}
+	if again, err := p.CreatePullRequest(ctx, "acme", "voltran", opts); err != nil || again.Number != 1 {
+		t.Errorf("expected the open pull request to be reused, got %+v, %v", again, err)
+	}
+
+	if err := p.MergePullRequest("acme", "voltran", 1); err != nil {
+		t.Fatal(err)
+	}
+	if pr, err = p.GetPullRequest(ctx, "acme", "voltran", 1); err != nil || !pr.Merged || pr.MergeCommitSHA != pr.Head.SHA {
+		t.Errorf("expected the pull request to be merged at its head, got %+v, %v", pr, err)
+	}
+	files, err := p.CloneAndExtractFiles(ctx, repoURL, "main", "")
+	if err != nil || files["apps/shop.yaml"] != "x: 1\n" || files["README.md"] == "" {
+		t.Errorf("expected the merged files on main, got %v, %v", files, err)
+	}
+
+	// Plain remotes have neither pull requests nor commit pages
+	plain, _ := New(Config{Provider: ProviderGit, URL: "ssh://git@git.example.com"})
+	if _, err := plain.CreatePullRequest(ctx, "acme", "voltran", opts); !errors.Is(err, ErrPullRequestsUnsupported) {
+		t.Errorf("expected ErrPullRequestsUnsupported, got %v", err)
+	}
+	if url := plain.CommitURL("acme", "voltran", "f00d"); url != "" {
+		t.Errorf("expected no commit URL, got %s", url)
+	}
+}
@@ -0,0 +1,776 @@
+// Package sops encrypts and decrypts YAML files in the SOPS format with age keys
+// Only values whose key path matches the encrypted regex are encrypted so files stay reviewable;
+// the sops CLI and helm-secrets decrypt them with the matching age identity
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Version sops version recorded in the metadata of encrypted files
+	Version = "3.8.1"
+
+	// DefaultEncryptedRegex keys whose values are encrypted: credentials, Secret data and the
+	// value of every environment variable, which the operator cannot tell apart from secrets
+	DefaultEncryptedRegex = `^(data|stringData|password|.*[Pp]assword|.*[Tt]oken|.*[Ss]ecret|apiKey|privateKey|accessKey|secretKey|value)$`
+
+	// DefaultPathRegex files passed through the encryptor: Helm values and Secret manifests
+	DefaultPathRegex = `(^|/)(values\.ya?ml|[^/]*secret[^/]*\.ya?ml)$`
+
+	// metadataKey top-level key holding the sops metadata
+	metadataKey = "sops"
+)
+
+// encryptedValue matches a value encrypted by sops
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// Encryptor encrypts generated files for a set of age recipients
+type Encryptor struct {
+	identities     []age.Identity
+	recipients     []*age.X25519Recipient
+	encryptedRegex *regexp.Regexp
+	pathRegex      *regexp.Regexp
+}
+
+// metadata sops metadata stored under the top-level sops key
+type metadata struct {
+	Age               []ageKey `yaml:"age"`
+	LastModified      string   `yaml:"lastmodified"`
+	MAC               string   `yaml:"mac"`
+	MACOnlyEncrypted  bool     `yaml:"mac_only_encrypted,omitempty"`
+	UnencryptedSuffix string   `yaml:"unencrypted_suffix,omitempty"`
+	EncryptedSuffix   string   `yaml:"encrypted_suffix,omitempty"`
+	UnencryptedRegex  string   `yaml:"unencrypted_regex,omitempty"`
+	EncryptedRegex    string   `yaml:"encrypted_regex,omitempty"`
+	Version           string   `yaml:"version"`
+}
+
+// selection rules of the metadata deciding which values of a file are encrypted
+type selection struct {
+	unencryptedSuffix string
+	encryptedSuffix   string
+	unencryptedRegex  *regexp.Regexp
+	encryptedRegex    *regexp.Regexp
+}
+
+// ageKey data key encrypted to an age recipient
+type ageKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// LoadIdentities reads the age identities of a key file as written by age-keygen
+func LoadIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age key file: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age key file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// NewEncryptor returns an Encryptor for the recipients of the identities plus the additional
+// recipients. The identities let it decrypt committed files so unchanged files keep their
+// ciphertext; an empty encryptedRegex uses DefaultEncryptedRegex.
+func NewEncryptor(identities []age.Identity, recipients []string, encryptedRegex string) (*Encryptor, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("at least one age identity is required")
+	}
+	if encryptedRegex == "" {
+		encryptedRegex = DefaultEncryptedRegex
+	}
+	re, err := regexp.Compile(encryptedRegex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted regex: %w", err)
+	}
+
+	e := &Encryptor{
+		identities:     identities,
+		encryptedRegex: re,
+		pathRegex:      regexp.MustCompile(DefaultPathRegex),
+	}
+	seen := map[string]bool{}
+	add := func(r *age.X25519Recipient) {
+		if !seen[r.String()] {
+			seen[r.String()] = true
+			e.recipients = append(e.recipients, r)
+		}
+	}
+	for _, identity := range identities {
+		x25519, ok := identity.(*age.X25519Identity)
+		if !ok {
+			return nil, fmt.Errorf("unsupported age identity %T", identity)
+		}
+		add(x25519.Recipient())
+	}
+	for _, s := range recipients {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		r, err := age.ParseX25519Recipient(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %s: %w", s, err)
+		}
+		add(r)
+	}
+	return e, nil
+}
+
+// Recipients returns the age recipients every file is encrypted to
+func (e *Encryptor) Recipients() []string {
+	recipients := make([]string, len(e.recipients))
+	for i, r := range e.recipients {
+		recipients[i] = r.String()
+	}
+	return recipients
+}
+
+// EncryptedRegex returns the regex selecting the keys whose values are encrypted
+func (e *Encryptor) EncryptedRegex() string {
+	return e.encryptedRegex.String()
+}
+
+// CreationRules returns a .sops.yaml encrypting files edited with the sops CLI the same way
+func (e *Encryptor) CreationRules() string {
+	rules := map[string]interface{}{
+		"creation_rules": []map[string]interface{}{
+			{
+				"path_regex":      DefaultPathRegex,
+				"encrypted_regex": e.EncryptedRegex(),
+				"age":             strings.Join(e.Recipients(), ","),
+			},
+		},
+	}
+	data, _ := yaml.Marshal(rules)
+	return string(data)
+}
+
+// Encode encrypts a generated file on its way into the repository; previous is the committed
+// content. Files outside DefaultPathRegex and files without sensitive values are kept in plain
+// text. Encryption is not deterministic, so a committed file decrypting to the same content for
+// the same recipients is kept as is instead of producing a new commit.
+func (e *Encryptor) Encode(path, content, previous string) (string, error) {
+	if e == nil || !e.pathRegex.MatchString(path) {
+		return content, nil
+	}
+	if previous != "" && e.unchanged([]byte(content), []byte(previous)) {
+		return previous, nil
+	}
+	encrypted, _, err := e.Encrypt([]byte(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %w", path, err)
+	}
+	return string(encrypted), nil
+}
+
+// unchanged reports whether previous is encrypted for the current recipients and regex and
+// decrypts to content
+func (e *Encryptor) unchanged(content, previous []byte) bool {
+	docs, err := parseDocuments(previous)
+	if err != nil {
+		return false
+	}
+	meta, err := readMetadata(docs)
+	if err != nil || meta.EncryptedRegex != e.EncryptedRegex() {
+		return false
+	}
+	var committed []string
+	for _, key := range meta.Age {
+		committed = append(committed, key.Recipient)
+	}
+	current := e.Recipients()
+	sort.Strings(committed)
+	sort.Strings(current)
+	if !reflect.DeepEqual(committed, current) {
+		return false
+	}
+
+	plain, err := e.Decrypt(previous)
+	if err != nil {
+		return false
+	}
+	return SameYAML(plain, content)
+}
+
+// Encrypt encrypts the values of a YAML file whose key path matches the encrypted regex and
+// reports whether anything was encrypted; files without such values are returned unchanged.
+// Like sops, every document of a multi-document file carries the same metadata.
+func (e *Encryptor) Encrypt(data []byte) ([]byte, bool, error) {
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return nil, false, err
+	}
+	var roots []*yaml.Node
+	for _, doc := range docs {
+		root := documentMapping(doc)
+		if root == nil {
+			// sops only encrypts mappings
+			return data, false, nil
+		}
+		if mappingValue(root, metadataKey) != nil {
+			return nil, false, fmt.Errorf("file is already encrypted")
+		}
+		roots = append(roots, root)
+	}
+	if !e.hasEncryptedValues(roots) {
+		return data, false, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, false, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	mac := sha512.New()
+	for _, doc := range docs {
+		// sops encrypts comments as well; dropping them keeps the MAC independent of comment handling
+		stripComments(doc)
+		err := walk(documentMapping(doc), nil, func(node *yaml.Node, path []string) error {
+			plaintext, valueType := scalarBytes(node)
+			mac.Write(plaintext)
+			if !anyKey(path, e.encryptedRegex.MatchString) {
+				return nil
+			}
+			value, err := encryptValue(plaintext, valueType, dataKey, additionalData(path))
+			if err != nil {
+				return err
+			}
+			node.Value = value
+			node.Tag = "!!str"
+			node.Style = 0
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	meta := metadata{
+		LastModified:   time.Now().UTC().Format(time.RFC3339),
+		EncryptedRegex: e.EncryptedRegex(),
+		Version:        Version,
+	}
+	meta.MAC, err = encryptValue([]byte(fmt.Sprintf("%X", mac.Sum(nil))), "str", dataKey, meta.LastModified)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, recipient := range e.recipients {
+		enc, err := encryptDataKey(dataKey, recipient)
+		if err != nil {
+			return nil, false, err
+		}
+		meta.Age = append(meta.Age, ageKey{Recipient: recipient.String(), Enc: enc})
+	}
+
+	for _, root := range roots {
+		var metaNode yaml.Node
+		if err := metaNode.Encode(meta); err != nil {
+			return nil, false, fmt.Errorf("failed to encode sops metadata: %w", err)
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: metadataKey}, &metaNode)
+	}
+
+	out, err := marshal(docs)
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// Decrypt decrypts a file encrypted by Encrypt or the sops CLI with the Encryptor's identities
+func (e *Encryptor) Decrypt(data []byte) ([]byte, error) {
+	return Decrypt(data, e.identities...)
+}
+
+// Decrypt decrypts a SOPS-encrypted YAML file with one of the age identities and verifies its MAC
+func Decrypt(data []byte, identities ...age.Identity) ([]byte, error) {
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readMetadata(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataKey []byte
+	for _, key := range meta.Age {
+		if dataKey, err = decryptDataKey(key.Enc, identities); err == nil {
+			break
+		}
+	}
+	if dataKey == nil {
+		return nil, fmt.Errorf("no age identity matches the file's recipients: %w", err)
+	}
+
+	sel, err := meta.selection()
+	if err != nil {
+		return nil, err
+	}
+
+	mac := sha512.New()
+	for _, doc := range docs {
+		root := documentMapping(doc)
+		// Remove the metadata before walking the values
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == metadataKey {
+				root.Content = append(root.Content[:i], root.Content[i+2:]...)
+				break
+			}
+		}
+
+		err := walk(root, nil, func(node *yaml.Node, path []string) error {
+			if !sel.encrypted(path) {
+				if !meta.MACOnlyEncrypted {
+					plaintext, _ := scalarBytes(node)
+					mac.Write(plaintext)
+				}
+				return nil
+			}
+			plaintext, valueType, err := decryptValue(node.Value, dataKey, additionalData(path))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+			}
+			mac.Write(plaintext)
+			setScalar(node, plaintext, valueType)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		walkComments(doc, nil, func(node *yaml.Node, path []string) {
+			if sel.encrypted(path) {
+				aad := additionalData(path)
+				node.HeadComment = decryptComment(node.HeadComment, dataKey, aad)
+				node.LineComment = decryptComment(node.LineComment, dataKey, aad)
+				node.FootComment = decryptComment(node.FootComment, dataKey, aad)
+			}
+		})
+	}
+
+	expected, _, err := decryptValue(meta.MAC, dataKey, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt MAC: %w", err)
+	}
+	if string(expected) != fmt.Sprintf("%X", mac.Sum(nil)) {
+		return nil, fmt.Errorf("MAC mismatch, the file was modified without sops")
+	}
+	return marshal(docs)
+}
+
+// IsEncrypted reports whether a YAML file carries sops metadata
+func IsEncrypted(data []byte) bool {
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return false
+	}
+	_, err = readMetadata(docs)
+	return err == nil
+}
+
+// SameYAML reports whether two YAML files hold the same documents regardless of formatting
+func SameYAML(a, b []byte) bool {
+	va, errA := decodeAll(a)
+	vb, errB := decodeAll(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// hasEncryptedValues reports whether any scalar below the roots has a key path the regex selects
+func (e *Encryptor) hasEncryptedValues(roots []*yaml.Node) bool {
+	found := false
+	for _, root := range roots {
+		_ = walk(root, nil, func(_ *yaml.Node, path []string) error {
+			found = found || anyKey(path, e.encryptedRegex.MatchString)
+			return nil
+		})
+	}
+	return found
+}
+
+// selection compiles the rules selecting the encrypted values
+func (m *metadata) selection() (*selection, error) {
+	sel := &selection{unencryptedSuffix: m.UnencryptedSuffix, encryptedSuffix: m.EncryptedSuffix}
+	var err error
+	if m.UnencryptedRegex != "" {
+		if sel.unencryptedRegex, err = regexp.Compile(m.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid unencrypted_regex in sops metadata: %w", err)
+		}
+	}
+	if m.EncryptedRegex != "" {
+		if sel.encryptedRegex, err = regexp.Compile(m.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid encrypted_regex in sops metadata: %w", err)
+		}
+	}
+	return sel, nil
+}
+
+// encrypted reports whether the value at path is encrypted, applying the rules in the order of
+// sops: a later rule overrides the earlier ones
+func (sel *selection) encrypted(path []string) bool {
+	encrypted := true
+	if sel.unencryptedSuffix != "" && anyKey(path, func(key string) bool { return strings.HasSuffix(key, sel.unencryptedSuffix) }) {
+		encrypted = false
+	}
+	if sel.encryptedSuffix != "" {
+		encrypted = anyKey(path, func(key string) bool { return strings.HasSuffix(key, sel.encryptedSuffix) })
+	}
+	if sel.unencryptedRegex != nil && anyKey(path, sel.unencryptedRegex.MatchString) {
+		encrypted = false
+	}
+	if sel.encryptedRegex != nil {
+		encrypted = anyKey(path, sel.encryptedRegex.MatchString)
+	}
+	return encrypted
+}
+
+// anyKey reports whether a key of path satisfies match
+func anyKey(path []string, match func(string) bool) bool {
+	for _, key := range path {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// readMetadata decodes the sops metadata of the first document
+func readMetadata(docs []*yaml.Node) (*metadata, error) {
+	var root *yaml.Node
+	if len(docs) > 0 {
+		root = documentMapping(docs[0])
+	}
+	if root == nil {
+		return nil, fmt.Errorf("file is not a YAML mapping")
+	}
+	node := mappingValue(root, metadataKey)
+	if node == nil {
+		return nil, fmt.Errorf("file is not encrypted with sops")
+	}
+	meta := &metadata{}
+	if err := node.Decode(meta); err != nil {
+		return nil, fmt.Errorf("invalid sops metadata: %w", err)
+	}
+	if len(meta.Age) == 0 {
+		return nil, fmt.Errorf("file has no age recipients")
+	}
+	for _, doc := range docs[1:] {
+		if root := documentMapping(doc); root == nil || mappingValue(root, metadataKey) == nil {
+			return nil, fmt.Errorf("document without sops metadata")
+		}
+	}
+	return meta, nil
+}
+
+// walk calls fn for every scalar below node with the mapping keys leading to it; sequence
+// items share the path of their sequence as in sops
+func walk(node *yaml.Node, path []string, fn func(*yaml.Node, []string) error) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if len(path) == 0 && key == metadataKey {
+				continue
+			}
+			if err := walk(node.Content[i+1], append(path[:len(path):len(path)], key), fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := walk(item, path, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return nil
+		}
+		return fn(node, path)
+	}
+	return nil
+}
+
+// walkComments calls fn for every node that may carry comments with the path sops encrypts
+// them with: the keys of the mapping or sequence holding them
+func walkComments(node *yaml.Node, path []string, fn func(*yaml.Node, []string)) {
+	fn(node, path)
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkComments(child, path, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fn(key, path)
+			if value.Kind == yaml.MappingNode || value.Kind == yaml.SequenceNode {
+				walkComments(value, append(path[:len(path):len(path)], key.Value), fn)
+			} else {
+				fn(value, path)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind == yaml.MappingNode || item.Kind == yaml.SequenceNode {
+				walkComments(item, path, fn)
+			} else {
+				fn(item, path)
+			}
+		}
+	}
+}
+
+// decryptComment decrypts the encrypted lines of a YAML comment; like sops, lines that do not
+// decrypt are kept as they are
+func decryptComment(comment string, key []byte, aad string) string {
+	if comment == "" {
+		return comment
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		value, ok := strings.CutPrefix(line, "#")
+		if !ok {
+			continue
+		}
+		if plaintext, valueType, err := decryptValue(value, key, aad); err == nil && valueType == "comment" {
+			lines[i] = "#" + string(plaintext)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scalarBytes returns the bytes sops hashes and encrypts for a scalar and its sops type
+func scalarBytes(node *yaml.Node) ([]byte, string) {
+	switch node.ShortTag() {
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err == nil {
+			if b {
+				return []byte("True"), "bool"
+			}
+			return []byte("False"), "bool"
+		}
+	case "!!int":
+		var i int
+		if err := node.Decode(&i); err == nil {
+			return []byte(strconv.Itoa(i)), "int"
+		}
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err == nil {
+			return []byte(strconv.FormatFloat(f, 'f', -1, 64)), "float"
+		}
+	}
+	return []byte(node.Value), "str"
+}
+
+// setScalar stores a decrypted value with the YAML tag of its sops type
+func setScalar(node *yaml.Node, plaintext []byte, valueType string) {
+	node.Style = 0
+	node.Value = string(plaintext)
+	switch valueType {
+	case "int":
+		node.Tag = "!!int"
+	case "float":
+		node.Tag = "!!float"
+	case "bool":
+		node.Tag = "!!bool"
+		node.Value = strings.ToLower(node.Value)
+	default:
+		node.Tag = "!!str"
+	}
+}
+
+// additionalData authenticates a value with its key path like sops does
+func additionalData(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+// encryptValue encrypts a value with AES256-GCM into the sops ENC[...] format
+func encryptValue(plaintext []byte, valueType string, key []byte, aad string) (string, error) {
+	// sops keeps empty strings empty
+	if valueType == "str" && len(plaintext) == 0 {
+		return "", nil
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("failed to generate IV: %w", err)
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(aad))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType), nil
+}
+
+// decryptValue decrypts a sops ENC[...] value and returns its plaintext and sops type
+func decryptValue(value string, key []byte, aad string) ([]byte, string, error) {
+	if value == "" {
+		return nil, "str", nil
+	}
+	match := encryptedValue.FindStringSubmatch(value)
+	if match == nil {
+		return nil, "", fmt.Errorf("value is not encrypted")
+	}
+	var parts [3][]byte
+	for i := range parts {
+		decoded, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid base64: %w", err)
+		}
+		parts[i] = decoded
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return nil, "", err
+	}
+	return plaintext, match[4], nil
+}
+
+// newGCM returns AES-GCM with the 32 byte nonces sops uses
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, 32)
+}
+
+// encryptDataKey encrypts the data key to an age recipient as an armored age file
+func encryptDataKey(dataKey []byte, recipient age.Recipient) (string, error) {
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipient)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := aw.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// decryptDataKey decrypts an armored data key with one of the identities
+func decryptDataKey(enc string, identities []age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(enc)), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// documentMapping returns the top-level mapping of a parsed document, nil if there is none
+func documentMapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// mappingValue returns the value of key in a mapping node, nil if it is missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// stripComments removes every comment below node
+func stripComments(node *yaml.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, child := range node.Content {
+		stripComments(child)
+	}
+}
+
+// parseDocuments parses every document of a YAML file
+func parseDocuments(data []byte) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []*yaml.Node
+	for {
+		doc := &yaml.Node{}
+		if err := dec.Decode(doc); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// decodeAll decodes every document of a YAML file
+func decodeAll(data []byte) ([]interface{}, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var values []interface{}
+	for {
+		var value interface{}
+		if err := dec.Decode(&value); err == io.EOF {
+			return values, nil
+		} else if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+}
+
+// marshal writes the documents with the four-space indentation of sops
+func marshal(docs []*yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(4)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to write YAML: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}